@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type CellType int
@@ -28,6 +30,7 @@ type Cell struct {
 	date1904 bool
 	Hidden   bool
 	cellType CellType
+	richText RichText
 }
 
 // CellInterface defines the public API of the Cell.
@@ -44,13 +47,42 @@ func (c *Cell) Type() CellType {
 	return c.cellType
 }
 
+// isDate1904 reports whether the dates in the Cell count from 1904,
+// rather than 1900, as they do when the File it belongs to says so.
+func (c *Cell) isDate1904() bool {
+	if c.Row != nil && c.Row.Sheet != nil && c.Row.Sheet.File != nil {
+		return c.Row.Sheet.File.Date1904
+	}
+	return c.date1904
+}
+
 // Set string
 func (c *Cell) SetString(s string) {
 	c.Value = s
 	c.formula = ""
+	c.richText = nil
+	c.cellType = CellTypeString
+}
+
+// SetRichText sets the value of the Cell to text made up of runs
+// that are each formatted in their own way.
+func (c *Cell) SetRichText(richText RichText) {
+	c.Value = richText.String()
+	c.formula = ""
+	c.richText = richText
 	c.cellType = CellTypeString
 }
 
+// RichText returns the formatted runs that make up the value of the
+// Cell, or nil if its value is plain text.  Changing the Value of the
+// Cell some other way discards them.
+func (c *Cell) RichText() RichText {
+	if c.richText == nil || c.richText.String() != c.Value {
+		return nil
+	}
+	return c.richText
+}
+
 // String returns the value of a Cell as a string.
 func (c *Cell) String() string {
 	return c.FormattedValue()
@@ -81,6 +113,15 @@ func (c *Cell) SetFloatWithFormat(n float64, format string) {
 	c.cellType = CellTypeNumeric
 }
 
+// setGeneralFloat sets the value of the Cell to n, in the "general"
+// number format, which shows it with as many digits as it needs.
+func (c *Cell) setGeneralFloat(n float64) {
+	c.Value = strconv.FormatFloat(n, 'g', -1, 64)
+	c.numFmt = "general"
+	c.formula = ""
+	c.cellType = CellTypeNumeric
+}
+
 // Returns the value of cell as a number
 func (c *Cell) Float() (float64, error) {
 	f, err := strconv.ParseFloat(c.Value, 64)
@@ -98,6 +139,29 @@ func (c *Cell) SetInt64(n int64) {
 	c.cellType = CellTypeNumeric
 }
 
+// SetUint64 sets the value of the Cell to an unsigned 64-bit integer.
+func (c *Cell) SetUint64(n uint64) {
+	c.Value = strconv.FormatUint(n, 10)
+	c.numFmt = "0"
+	c.formula = ""
+	c.cellType = CellTypeNumeric
+}
+
+// SetDateTime sets the value of the Cell to a date and time, shown in
+// the format "yyyy-mm-dd hh:mm:ss".
+func (c *Cell) SetDateTime(t time.Time) {
+	c.SetDateTimeWithFormat(t, "yyyy-mm-dd hh:mm:ss")
+}
+
+// SetDateTimeWithFormat sets the value of the Cell to a date and time,
+// shown in the given number format, such as "d-mmm-yy".
+func (c *Cell) SetDateTimeWithFormat(t time.Time, format string) {
+	c.Value = strconv.FormatFloat(TimeToExcelTime(t, c.isDate1904()), 'f', -1, 64)
+	c.numFmt = format
+	c.formula = ""
+	c.cellType = CellTypeNumeric
+}
+
 // Returns the value of cell as 64-bit integer
 func (c *Cell) Int64() (int64, error) {
 	f, err := strconv.ParseInt(c.Value, 10, 64)
@@ -172,7 +236,7 @@ func (c *Cell) formatToTime(format string) string {
 	if err != nil {
 		return err.Error()
 	}
-	return TimeFromExcelTime(f, c.date1904).Format(format)
+	return TimeFromExcelTime(f, c.isDate1904()).Format(format)
 }
 
 func (c *Cell) formatToFloat(format string) string {
@@ -262,7 +326,7 @@ func (c *Cell) FormattedValue() string {
 		if err != nil {
 			return err.Error()
 		}
-		t := TimeFromExcelTime(f, c.date1904)
+		t := TimeFromExcelTime(f, c.isDate1904())
 		if t.Hour() > 0 {
 			return t.Format("15:04:05")
 		}
@@ -272,9 +336,11 @@ func (c *Cell) FormattedValue() string {
 		if err != nil {
 			return err.Error()
 		}
-		t := TimeFromExcelTime(f, c.date1904)
+		t := TimeFromExcelTime(f, c.isDate1904())
 		return fmt.Sprintf("%0d%0d.%d", t.Minute(), t.Second(), t.Nanosecond()/1000)
 
+	case "yyyy-mm-dd":
+		return c.formatToTime("2006-01-02")
 	case "yyyy\\-mm\\-dd":
 		return c.formatToTime("2006\\-01\\-02")
 	case "dd/mm/yy":
@@ -306,3 +372,97 @@ func (c *Cell) FormattedValue() string {
 	}
 	return c.Value
 }
+
+// ErrorValue is the value of a cell whose formula gave an error, such
+// as "#DIV/0!".
+type ErrorValue string
+
+// Error returns the error, as Excel shows it.
+func (e ErrorValue) Error() string {
+	return string(e)
+}
+
+// TypedValue returns the value of the Cell as a Go value of the type
+// that suits it: a string, a bool, an ErrorValue, a time.Time for a
+// number formatted as a date (see IsDate), an int64 for a number that
+// is whole, and a float64 for any other number.  A number, or formula
+// result, that is missing is returned as nil.
+//
+// The Cell's Value field is the raw text of its value, so this can't
+// be called Value.
+func (c *Cell) TypedValue() interface{} {
+	switch c.cellType {
+	case CellTypeBool:
+		return c.Value == "1"
+	case CellTypeError:
+		return ErrorValue(c.Value)
+	case CellTypeNumeric, CellTypeFormula:
+		if c.Value == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			// Formulas can give text.
+			return c.Value
+		}
+		if c.IsDate() {
+			return TimeFromExcelTime(f, c.isDate1904())
+		}
+		if n, err := strconv.ParseInt(c.Value, 10, 64); err == nil {
+			return n
+		}
+		return f
+	}
+	return c.Value
+}
+
+// IsDate reports whether the number format of the Cell shows dates or
+// times, as the built in formats 14 to 22 and 45 to 47 do.
+func (c *Cell) IsDate() bool {
+	return isDateFormat(c.numFmt)
+}
+
+// isDateFormat reports whether a number format shows dates or times,
+// which is to say that it has any of the codes d, m, y, h or s in
+// it, other than in quoted or escaped text, or in a colour or
+// condition in square brackets.
+func isDateFormat(format string) bool {
+	// Only the first section, for positive numbers, counts.
+	inBrackets := false
+	for i := 0; i < len(format); i++ {
+		ch := format[i]
+		switch {
+		case inBrackets:
+			if ch == ']' {
+				inBrackets = false
+			}
+		case ch == '[':
+			// [h], [mm] and [ss] are elapsed times;
+			// anything else, such as [Red], isn't.
+			end := strings.IndexByte(format[i:], ']')
+			if end < 0 {
+				return false
+			}
+			if end > 1 && strings.Trim(strings.ToLower(format[i+1:i+end]), "hms") == "" {
+				return true
+			}
+			inBrackets = true
+		case ch == '"':
+			end := strings.IndexByte(format[i+1:], '"')
+			if end < 0 {
+				return false
+			}
+			i += end + 1
+		case ch == '\\', ch == '_', ch == '*':
+			i++
+		case ch == ';':
+			return false
+		default:
+			switch ch {
+			case 'd', 'D', 'm', 'M', 'y', 'Y', 'h', 'H', 's', 'S':
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,6 +1,8 @@
 package xlsx
 
 import (
+	"time"
+
 	. "gopkg.in/check.v1"
 )
 
@@ -269,6 +271,11 @@ func (s *CellSuite) TestSetterGetters(c *C) {
 	c.Assert(intValue, Equals, 1024)
 	c.Assert(cell.Type(), Equals, CellTypeNumeric)
 
+	cell.SetUint64(18446744073709551615)
+	c.Assert(cell.Value, Equals, "18446744073709551615")
+	c.Assert(cell.GetNumberFormat(), Equals, "0")
+	c.Assert(cell.Type(), Equals, CellTypeNumeric)
+
 	cell.SetFloat(1.024)
 	float, _ := cell.Float()
 	intValue, _ = cell.Int() // convert
@@ -280,3 +287,58 @@ func (s *CellSuite) TestSetterGetters(c *C) {
 	c.Assert(cell.Formula(), Equals, "10+20")
 	c.Assert(cell.Type(), Equals, CellTypeFormula)
 }
+
+func (s *CellSuite) TestSetDateTime(c *C) {
+	cell := Cell{}
+	cell.SetDateTime(time.Date(2013, 1, 1, 18, 0, 0, 0, time.UTC))
+	c.Assert(cell.Value, Equals, "41275.75")
+	c.Assert(cell.String(), Equals, "2013-01-01 18:00:00")
+	c.Assert(cell.Type(), Equals, CellTypeNumeric)
+
+	cell.SetDateTimeWithFormat(time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC), "d-mmm-yy")
+	c.Assert(cell.String(), Equals, "1-Jan-13")
+}
+
+func (s *CellSuite) TestTypedValue(c *C) {
+	cell := Cell{}
+	c.Assert(cell.TypedValue(), Equals, "")
+	cell.SetString("hello")
+	c.Assert(cell.TypedValue(), Equals, "hello")
+	cell.SetInt(1024)
+	c.Assert(cell.TypedValue(), Equals, int64(1024))
+	cell.SetFloat(1.5)
+	c.Assert(cell.TypedValue(), Equals, 1.5)
+	cell.SetBool(true)
+	c.Assert(cell.TypedValue(), Equals, true)
+	cell.SetDateTime(time.Date(2013, 1, 1, 18, 0, 0, 0, time.UTC))
+	c.Assert(cell.TypedValue(), Equals, time.Date(2013, 1, 1, 18, 0, 0, 0, time.UTC))
+
+	cell = Cell{Value: "#DIV/0!", cellType: CellTypeError}
+	c.Assert(cell.TypedValue(), Equals, ErrorValue("#DIV/0!"))
+	c.Assert(cell.TypedValue().(error).Error(), Equals, "#DIV/0!")
+	cell = Cell{Value: "total", formula: `"to"&"tal"`, cellType: CellTypeFormula}
+	c.Assert(cell.TypedValue(), Equals, "total")
+	cell = Cell{cellType: CellTypeFormula}
+	c.Assert(cell.TypedValue(), IsNil)
+}
+
+func (s *CellSuite) TestIsDate(c *C) {
+	for id := 14; id <= 22; id++ {
+		c.Assert(isDateFormat(getBuiltinNumberFormat(id)), Equals, true, Commentf("%d", id))
+	}
+	for id := 45; id <= 47; id++ {
+		c.Assert(isDateFormat(getBuiltinNumberFormat(id)), Equals, true, Commentf("%d", id))
+	}
+	for _, format := range []string{"yyyy-mm-dd", "[$-409]mmmm d, yyyy", "[h]:mm", "[Red]dd/mm/yy", `"Day "d`} {
+		c.Assert(isDateFormat(format), Equals, true, Commentf(format))
+	}
+	for _, format := range []string{"", "general", "0.00", "#,##0 ;[Red](#,##0)", `0" days"`, `0\d`, "0.00E+00", "@", "0;[h]"} {
+		c.Assert(isDateFormat(format), Equals, false, Commentf(format))
+	}
+
+	cell := Cell{}
+	cell.SetFloatWithFormat(41275, "d-mmm-yy")
+	c.Assert(cell.IsDate(), Equals, true)
+	cell.SetInt(41275)
+	c.Assert(cell.IsDate(), Equals, false)
+}
@@ -0,0 +1,529 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// What follows is a minimal implementation of the compound file
+// binary format (MS-CFB), which is the container that encrypted
+// workbooks are stored in.  It reads version 3 and 4 files, and
+// writes version 3 ones, with 512 byte sectors.
+
+const (
+	cfbSignature      = "\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1"
+	cfbHeaderSize     = 512
+	cfbSectorSize     = 512
+	cfbMiniSectorSize = 64
+	cfbMiniCutoff     = 4096
+	cfbDirEntrySize   = 128
+	cfbHeaderDIFAT    = 109
+
+	cfbDIFSect    = 0xfffffffc
+	cfbFATSect    = 0xfffffffd
+	cfbEndOfChain = 0xfffffffe
+	cfbFreeSect   = 0xffffffff
+	cfbNoStream   = 0xffffffff
+
+	cfbTypeStorage = 1
+	cfbTypeStream  = 2
+	cfbTypeRoot    = 5
+)
+
+// cfbEntry is an entry in the directory of a compound file, which is
+// either a storage, holding further entries, or a stream of data.
+type cfbEntry struct {
+	name     string
+	kind     byte
+	left     uint32
+	right    uint32
+	child    uint32
+	start    uint32
+	size     uint64
+	data     []byte      // The content of a stream being written.
+	children []*cfbEntry // The entries of a storage being written.
+}
+
+// cfbReader reads the streams of a compound file.
+type cfbReader struct {
+	r           io.ReaderAt
+	sectorShift uint
+	fat         []uint32
+	miniFAT     []uint32
+	entries     []*cfbEntry
+	miniStream  []byte
+}
+
+// newCFBReader reads the header, allocation tables and directory of a
+// compound file.
+func newCFBReader(r io.ReaderAt) (*cfbReader, error) {
+	header := make([]byte, cfbHeaderSize)
+	_, err := r.ReadAt(header, 0)
+	if err != nil {
+		return nil, fmt.Errorf("can't read compound file header: %s", err)
+	}
+	if string(header[:8]) != cfbSignature {
+		return nil, fmt.Errorf("not a compound file")
+	}
+	le := binary.LittleEndian
+	cfb := &cfbReader{r: r, sectorShift: uint(le.Uint16(header[30:]))}
+	if cfb.sectorShift != 9 && cfb.sectorShift != 12 {
+		return nil, fmt.Errorf("unsupported compound file sector size %d", 1<<cfb.sectorShift)
+	}
+	perSector := cfb.sectorSize() / 4
+
+	// The locations of the FAT sectors are listed in the header,
+	// and then in a chain of DIFAT sectors.  The chain is followed
+	// until it has listed as many FAT sectors as the header says
+	// there are, and as it can't visit a sector twice, it can't be
+	// longer than the file.
+	fatCount := int(le.Uint32(header[44:]))
+	fatSectors := []uint32{}
+	for i := 0; i < cfbHeaderDIFAT; i++ {
+		fatSectors = append(fatSectors, le.Uint32(header[76+4*i:]))
+	}
+	difat := le.Uint32(header[68:])
+	visited := make(map[uint32]bool)
+	for n := le.Uint32(header[72:]); n > 0 && difat < cfbDIFSect && len(fatSectors) < fatCount; n-- {
+		if visited[difat] {
+			return nil, fmt.Errorf("compound file has a broken DIFAT chain")
+		}
+		visited[difat] = true
+		sector, err := cfb.readSector(difat)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < perSector-1; i++ {
+			fatSectors = append(fatSectors, le.Uint32(sector[4*i:]))
+		}
+		difat = le.Uint32(sector[4*(perSector-1):])
+	}
+	for _, fatSector := range fatSectors {
+		if len(cfb.fat) >= fatCount*perSector || fatSector >= cfbDIFSect {
+			break
+		}
+		sector, err := cfb.readSector(fatSector)
+		if err != nil {
+			return nil, err
+		}
+		cfb.fat = append(cfb.fat, uint32s(sector)...)
+	}
+
+	dir, err := cfb.readChain(le.Uint32(header[48:]), 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+cfbDirEntrySize <= len(dir); i += cfbDirEntrySize {
+		cfb.entries = append(cfb.entries, parseCFBEntry(dir[i:i+cfbDirEntrySize]))
+	}
+	if len(cfb.entries) == 0 || cfb.entries[0].kind != cfbTypeRoot {
+		return nil, fmt.Errorf("compound file has no root entry")
+	}
+
+	if start := le.Uint32(header[60:]); start < cfbDIFSect {
+		miniFAT, err := cfb.readChain(start, 0)
+		if err != nil {
+			return nil, err
+		}
+		cfb.miniFAT = uint32s(miniFAT)
+	}
+	root := cfb.entries[0]
+	if root.start < cfbDIFSect {
+		cfb.miniStream, err = cfb.readChain(root.start, root.size)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cfb, nil
+}
+
+func (cfb *cfbReader) sectorSize() int {
+	return 1 << cfb.sectorShift
+}
+
+// readSector returns the content of a sector, which are numbered from
+// the end of the header.
+func (cfb *cfbReader) readSector(n uint32) ([]byte, error) {
+	sector := make([]byte, cfb.sectorSize())
+	_, err := cfb.r.ReadAt(sector, int64(n+1)<<cfb.sectorShift)
+	if err != nil {
+		return nil, fmt.Errorf("can't read compound file sector %d: %s", n, err)
+	}
+	return sector, nil
+}
+
+// readChain returns the content of the chain of sectors that starts
+// at start, cut down to size unless that is 0.
+func (cfb *cfbReader) readChain(start uint32, size uint64) ([]byte, error) {
+	var data []byte
+	for n, count := start, 0; n != cfbEndOfChain; count++ {
+		if int(n) >= len(cfb.fat) || count > len(cfb.fat) {
+			return nil, fmt.Errorf("compound file has a broken sector chain")
+		}
+		sector, err := cfb.readSector(n)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, sector...)
+		n = cfb.fat[n]
+	}
+	return truncate(data, size)
+}
+
+// readMiniChain returns the content of the chain of mini sectors that
+// starts at start, cut down to size.
+func (cfb *cfbReader) readMiniChain(start uint32, size uint64) ([]byte, error) {
+	var data []byte
+	for n, count := start, 0; n != cfbEndOfChain; count++ {
+		offset := int(n) * cfbMiniSectorSize
+		if int(n) >= len(cfb.miniFAT) || count > len(cfb.miniFAT) || offset+cfbMiniSectorSize > len(cfb.miniStream) {
+			return nil, fmt.Errorf("compound file has a broken mini sector chain")
+		}
+		data = append(data, cfb.miniStream[offset:offset+cfbMiniSectorSize]...)
+		n = cfb.miniFAT[n]
+	}
+	return truncate(data, size)
+}
+
+func truncate(data []byte, size uint64) ([]byte, error) {
+	if size == 0 {
+		return data, nil
+	}
+	if uint64(len(data)) < size {
+		return nil, fmt.Errorf("compound file stream is shorter than its size")
+	}
+	return data[:size], nil
+}
+
+// find returns the entry at the given path of storage and stream
+// names, such as "EncryptionInfo", or nil if there isn't one.
+func (cfb *cfbReader) find(path ...string) *cfbEntry {
+	entry := cfb.entries[0]
+	for _, name := range path {
+		entry = cfb.findChild(entry, name)
+		if entry == nil {
+			return nil
+		}
+	}
+	return entry
+}
+
+// findChild looks for the named entry amongst the children of a
+// storage.  The children form a tree, which we walk in full rather
+// than trusting its order.
+func (cfb *cfbReader) findChild(storage *cfbEntry, name string) *cfbEntry {
+	visited := map[uint32]bool{}
+	pending := []uint32{storage.child}
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if id == cfbNoStream || int(id) >= len(cfb.entries) || visited[id] {
+			continue
+		}
+		visited[id] = true
+		entry := cfb.entries[id]
+		if strings.EqualFold(entry.name, name) {
+			return entry
+		}
+		pending = append(pending, entry.left, entry.right)
+	}
+	return nil
+}
+
+// readStream returns the content of the stream at the given path.
+func (cfb *cfbReader) readStream(path ...string) ([]byte, error) {
+	entry := cfb.find(path...)
+	if entry == nil || entry.kind != cfbTypeStream {
+		return nil, fmt.Errorf("compound file has no %q stream", strings.Join(path, "/"))
+	}
+	if entry.size == 0 {
+		return []byte{}, nil
+	}
+	if entry.size < cfbMiniCutoff {
+		return cfb.readMiniChain(entry.start, entry.size)
+	}
+	return cfb.readChain(entry.start, entry.size)
+}
+
+// parseCFBEntry decodes a directory entry.
+func parseCFBEntry(b []byte) *cfbEntry {
+	le := binary.LittleEndian
+	nameLen := int(le.Uint16(b[64:]))/2 - 1
+	if nameLen < 0 || nameLen > 31 {
+		nameLen = 0
+	}
+	units := make([]uint16, nameLen)
+	for i := range units {
+		units[i] = le.Uint16(b[2*i:])
+	}
+	return &cfbEntry{
+		name:  string(utf16.Decode(units)),
+		kind:  b[66],
+		left:  le.Uint32(b[68:]),
+		right: le.Uint32(b[72:]),
+		child: le.Uint32(b[76:]),
+		start: le.Uint32(b[116:]),
+		// Version 3 files may leave junk in the high half.
+		size: uint64(le.Uint32(b[120:])),
+	}
+}
+
+func uint32s(b []byte) []uint32 {
+	result := make([]uint32, len(b)/4)
+	for i := range result {
+		result[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return result
+}
+
+// newCFBStorage returns a storage, for writing, holding the given
+// entries.
+func newCFBStorage(name string, children ...*cfbEntry) *cfbEntry {
+	return &cfbEntry{name: name, kind: cfbTypeStorage, children: children}
+}
+
+// newCFBStream returns a stream, for writing, holding data.
+func newCFBStream(name string, data []byte) *cfbEntry {
+	return &cfbEntry{name: name, kind: cfbTypeStream, data: data}
+}
+
+// writeCFB writes a compound file whose root storage holds the given
+// entries.
+func writeCFB(w io.Writer, children ...*cfbEntry) error {
+	root := &cfbEntry{name: "Root Entry", kind: cfbTypeRoot, children: children}
+
+	// Number the entries, a storage's children following it, and
+	// link each storage's children into a tree.
+	entries := []*cfbEntry{}
+	var number func(entry *cfbEntry) uint32
+	number = func(entry *cfbEntry) uint32 {
+		id := uint32(len(entries))
+		entries = append(entries, entry)
+		entry.left, entry.right, entry.child = cfbNoStream, cfbNoStream, cfbNoStream
+		sorted := append([]*cfbEntry{}, entry.children...)
+		sort.Sort(cfbByName(sorted))
+		ids := make([]uint32, len(sorted))
+		for i, child := range sorted {
+			ids[i] = number(child)
+		}
+		entry.child = linkCFBTree(sorted, ids)
+		return id
+	}
+	number(root)
+
+	// Small streams are kept in the mini stream, in 64 byte mini
+	// sectors; the rest get sectors of their own.
+	var miniStream []byte
+	var miniFAT []uint32
+	var large []*cfbEntry
+	for _, entry := range entries {
+		entry.size = uint64(len(entry.data))
+		entry.start = cfbEndOfChain
+		switch {
+		case entry.kind != cfbTypeStream || len(entry.data) == 0:
+		case len(entry.data) < cfbMiniCutoff:
+			entry.start = uint32(len(miniFAT))
+			miniFAT = appendChain(miniFAT, entry.start, sectorCount(len(entry.data), cfbMiniSectorSize))
+			miniStream = append(miniStream, pad(entry.data, cfbMiniSectorSize)...)
+		default:
+			large = append(large, entry)
+		}
+	}
+	root.data = miniStream
+	root.size = uint64(len(miniStream))
+	large = append([]*cfbEntry{root}, large...)
+
+	dirSectors := sectorCount(len(entries)*cfbDirEntrySize, cfbSectorSize)
+	miniFATSectors := sectorCount(len(miniFAT)*4, cfbSectorSize)
+	dataSectors := dirSectors + miniFATSectors
+	for _, entry := range large {
+		dataSectors += sectorCount(len(entry.data), cfbSectorSize)
+	}
+	// The FAT has to cover itself, and the DIFAT sectors that list
+	// the FAT sectors beyond the first 109.
+	fatSectors, difatSectors := 0, 0
+	for {
+		total := dataSectors + fatSectors + difatSectors
+		f := sectorCount(total*4, cfbSectorSize)
+		d := 0
+		if f > cfbHeaderDIFAT {
+			d = sectorCount(f-cfbHeaderDIFAT, cfbSectorSize/4-1)
+		}
+		if f == fatSectors && d == difatSectors {
+			break
+		}
+		fatSectors, difatSectors = f, d
+	}
+
+	fat := []uint32{}
+	for i := 0; i < fatSectors; i++ {
+		fat = append(fat, cfbFATSect)
+	}
+	for i := 0; i < difatSectors; i++ {
+		fat = append(fat, cfbDIFSect)
+	}
+	dirStart := uint32(len(fat))
+	fat = appendChain(fat, dirStart, dirSectors)
+	miniFATStart := uint32(cfbEndOfChain)
+	if miniFATSectors > 0 {
+		miniFATStart = uint32(len(fat))
+		fat = appendChain(fat, miniFATStart, miniFATSectors)
+	}
+	for _, entry := range large {
+		if len(entry.data) > 0 {
+			entry.start = uint32(len(fat))
+			fat = appendChain(fat, entry.start, sectorCount(len(entry.data), cfbSectorSize))
+		}
+	}
+	for len(fat) < fatSectors*cfbSectorSize/4 {
+		fat = append(fat, cfbFreeSect)
+	}
+
+	le := binary.LittleEndian
+	var buf bytes.Buffer
+	header := make([]byte, cfbHeaderSize)
+	copy(header, cfbSignature)
+	le.PutUint16(header[24:], 0x003e)
+	le.PutUint16(header[26:], 3)
+	le.PutUint16(header[28:], 0xfffe)
+	le.PutUint16(header[30:], 9)
+	le.PutUint16(header[32:], 6)
+	le.PutUint32(header[44:], uint32(fatSectors))
+	le.PutUint32(header[48:], dirStart)
+	le.PutUint32(header[56:], cfbMiniCutoff)
+	le.PutUint32(header[60:], miniFATStart)
+	le.PutUint32(header[64:], uint32(miniFATSectors))
+	le.PutUint32(header[68:], cfbEndOfChain)
+	if difatSectors > 0 {
+		le.PutUint32(header[68:], uint32(fatSectors))
+	}
+	le.PutUint32(header[72:], uint32(difatSectors))
+	for i := 0; i < cfbHeaderDIFAT; i++ {
+		sector := uint32(cfbFreeSect)
+		if i < fatSectors {
+			sector = uint32(i)
+		}
+		le.PutUint32(header[76+4*i:], sector)
+	}
+	buf.Write(header)
+
+	buf.Write(putUint32s(fat))
+	perDIFAT := cfbSectorSize/4 - 1
+	for i := 0; i < difatSectors; i++ {
+		difat := make([]uint32, perDIFAT+1)
+		for j := range difat[:perDIFAT] {
+			difat[j] = cfbFreeSect
+			if n := cfbHeaderDIFAT + i*perDIFAT + j; n < fatSectors {
+				difat[j] = uint32(n)
+			}
+		}
+		difat[perDIFAT] = cfbEndOfChain
+		if i+1 < difatSectors {
+			difat[perDIFAT] = uint32(fatSectors + i + 1)
+		}
+		buf.Write(putUint32s(difat))
+	}
+
+	dir := make([]byte, dirSectors*cfbSectorSize)
+	for i := 0; i < dirSectors*cfbSectorSize/cfbDirEntrySize; i++ {
+		entry := &cfbEntry{left: cfbNoStream, right: cfbNoStream, child: cfbNoStream}
+		if i < len(entries) {
+			entry = entries[i]
+		}
+		entry.marshal(dir[i*cfbDirEntrySize:])
+	}
+	buf.Write(dir)
+
+	for len(miniFAT)%(cfbSectorSize/4) != 0 {
+		miniFAT = append(miniFAT, cfbFreeSect)
+	}
+	buf.Write(putUint32s(miniFAT))
+	for _, entry := range large {
+		buf.Write(pad(entry.data, cfbSectorSize))
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// marshal encodes the entry into a 128 byte directory entry.
+func (entry *cfbEntry) marshal(b []byte) {
+	le := binary.LittleEndian
+	units := utf16.Encode([]rune(entry.name))
+	for i, unit := range units {
+		le.PutUint16(b[2*i:], unit)
+	}
+	if entry.kind != 0 {
+		le.PutUint16(b[64:], uint16(2*len(units)+2))
+	}
+	b[66] = entry.kind
+	b[67] = 1 // Black; see linkCFBTree.
+	le.PutUint32(b[68:], entry.left)
+	le.PutUint32(b[72:], entry.right)
+	le.PutUint32(b[76:], entry.child)
+	le.PutUint32(b[116:], entry.start)
+	le.PutUint64(b[120:], entry.size)
+}
+
+// linkCFBTree links the sorted entries, whose ids are given, into a
+// balanced binary tree, and returns the id of its root.  As Apache POI
+// does, every node is coloured black, which readers accept.
+func linkCFBTree(entries []*cfbEntry, ids []uint32) uint32 {
+	if len(entries) == 0 {
+		return cfbNoStream
+	}
+	mid := len(entries) / 2
+	entries[mid].left = linkCFBTree(entries[:mid], ids[:mid])
+	entries[mid].right = linkCFBTree(entries[mid+1:], ids[mid+1:])
+	return ids[mid]
+}
+
+// cfbByName orders entries as the compound file format requires:
+// shorter names first, then by their upper case form.
+type cfbByName []*cfbEntry
+
+func (a cfbByName) Len() int      { return len(a) }
+func (a cfbByName) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a cfbByName) Less(i, j int) bool {
+	ni, nj := utf16.Encode([]rune(a[i].name)), utf16.Encode([]rune(a[j].name))
+	if len(ni) != len(nj) {
+		return len(ni) < len(nj)
+	}
+	return strings.ToUpper(a[i].name) < strings.ToUpper(a[j].name)
+}
+
+// appendChain adds a chain of count sectors, starting with start, to
+// an allocation table.
+func appendChain(table []uint32, start uint32, count int) []uint32 {
+	for i := 1; i < count; i++ {
+		table = append(table, start+uint32(i))
+	}
+	return append(table, cfbEndOfChain)
+}
+
+// sectorCount returns the number of sectors of the given size that
+// are needed to hold n bytes.
+func sectorCount(n, size int) int {
+	return (n + size - 1) / size
+}
+
+// pad returns data padded with zeros to a whole number of sectors of
+// the given size.
+func pad(data []byte, size int) []byte {
+	if len(data)%size == 0 {
+		return data
+	}
+	return append(append([]byte{}, data...), make([]byte, size-len(data)%size)...)
+}
+
+func putUint32s(values []uint32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
@@ -0,0 +1,89 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	. "gopkg.in/check.v1"
+)
+
+type CFBSuite struct{}
+
+var _ = Suite(&CFBSuite{})
+
+// Streams, both those small enough for the mini stream and those that
+// aren't, can be read back from the compound file they're written to.
+func (s *CFBSuite) TestWriteAndReadCFB(c *C) {
+	small := []byte("a small stream")
+	large := bytes.Repeat([]byte("0123456789"), 1000)
+	var buf bytes.Buffer
+	err := writeCFB(&buf,
+		newCFBStorage("Storage",
+			newCFBStream("Inner", small)),
+		newCFBStream("Small", small),
+		newCFBStream("Large", large),
+		newCFBStream("Empty", []byte{}))
+	c.Assert(err, IsNil)
+	c.Assert(buf.Len()%cfbSectorSize, Equals, 0)
+
+	cfb, err := newCFBReader(bytes.NewReader(buf.Bytes()))
+	c.Assert(err, IsNil)
+	data, err := cfb.readStream("Small")
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, small)
+	data, err = cfb.readStream("large")
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, large)
+	data, err = cfb.readStream("Storage", "Inner")
+	c.Assert(err, IsNil)
+	c.Assert(data, DeepEquals, small)
+	data, err = cfb.readStream("Empty")
+	c.Assert(err, IsNil)
+	c.Assert(data, HasLen, 0)
+	_, err = cfb.readStream("Missing")
+	c.Assert(err, ErrorMatches, `compound file has no "Missing" stream`)
+}
+
+// A stream large enough to need more than 109 FAT sectors has the rest
+// listed in DIFAT sectors.
+func (s *CFBSuite) TestWriteAndReadLargeCFB(c *C) {
+	large := make([]byte, 8*1024*1024)
+	for i := range large {
+		large[i] = byte(i % 251)
+	}
+	var buf bytes.Buffer
+	c.Assert(writeCFB(&buf, newCFBStream("Large", large)), IsNil)
+	cfb, err := newCFBReader(bytes.NewReader(buf.Bytes()))
+	c.Assert(err, IsNil)
+	data, err := cfb.readStream("Large")
+	c.Assert(err, IsNil)
+	c.Assert(bytes.Equal(data, large), Equals, true)
+}
+
+func (s *CFBSuite) TestReadNotCFB(c *C) {
+	_, err := newCFBReader(bytes.NewReader(make([]byte, 1024)))
+	c.Assert(err, ErrorMatches, "not a compound file")
+}
+
+// A chain of DIFAT sectors that loops back on itself is an error,
+// rather than being followed for as long as the header says.
+func (s *CFBSuite) TestReadCyclicDIFAT(c *C) {
+	data := make([]byte, cfbHeaderSize+cfbSectorSize)
+	copy(data, cfbSignature)
+	le := binary.LittleEndian
+	le.PutUint16(data[30:], 9)
+	le.PutUint32(data[44:], 0xffffffff)
+	le.PutUint32(data[68:], 0)
+	le.PutUint32(data[72:], 0xffffffff)
+	for i := 0; i < cfbHeaderDIFAT; i++ {
+		le.PutUint32(data[76+4*i:], cfbFreeSect)
+	}
+	// The one DIFAT sector lists no FAT sectors, and is followed
+	// by itself.
+	for i := 0; i < cfbSectorSize/4-1; i++ {
+		le.PutUint32(data[cfbHeaderSize+4*i:], cfbFreeSect)
+	}
+	le.PutUint32(data[len(data)-4:], 0)
+	_, err := newCFBReader(bytes.NewReader(data))
+	c.Assert(err, ErrorMatches, "compound file has a broken DIFAT chain")
+}
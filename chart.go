@@ -0,0 +1,355 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	chartRelType     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/chart"
+	chartContentType = "application/vnd.openxmlformats-officedocument.drawingml.chart+xml"
+
+	// The size, in pixels, that charts are drawn at unless they
+	// are given another.
+	defaultChartWidth  = 480
+	defaultChartHeight = 288
+)
+
+// chartLegendPositions maps the legend positions of a Chart to those
+// of DrawingML.
+var chartLegendPositions = map[string]string{
+	"":       "r",
+	"right":  "r",
+	"left":   "l",
+	"top":    "t",
+	"bottom": "b",
+}
+
+// Chart is an Excel chart, of data in ranges of cells, that is drawn
+// on a Sheet with AddChart.  As the chart refers to the cells, rather
+// than holding a copy of the data, it is redrawn when they are edited
+// in Excel.
+//
+// For example:
+//
+//	chart := NewChart("column", "Sales").
+//	    AddSeries("2016", "Sheet1!$A$2:$A$5", "Sheet1!$B$2:$B$5").
+//	    AddSeries("2017", "Sheet1!$A$2:$A$5", "Sheet1!$C$2:$C$5")
+//	err := sheet.AddChart("E2", chart, 0, 0)
+type Chart struct {
+	Type   string // "bar", "column", "line", "pie", "scatter" or "area".
+	Title  string
+	Series []ChartSeries
+	XAxis  ChartAxis // The category axis, or for scatter charts the X axis.
+	YAxis  ChartAxis // The value axis.
+	// Legend is where the legend is drawn: "right" (the default),
+	// "left", "top" or "bottom", or "none" to leave it out.
+	Legend string
+}
+
+// ChartSeries is a series of values plotted on a Chart.  Categories
+// and Values are references to ranges of cells, such as
+// "Sheet1!$B$2:$B$5".  For scatter charts the Categories are the X
+// values, and for other charts they label the values.  They may be
+// left empty, in which case the values are numbered.
+type ChartSeries struct {
+	Name       string
+	Categories string
+	Values     string
+}
+
+// ChartAxis is an axis of a Chart.
+type ChartAxis struct {
+	Title          string
+	MajorGridlines bool
+}
+
+// NewChart returns a Chart, of the given type, with no series.  The
+// gridlines of its value axis are drawn.
+func NewChart(chartType, title string) *Chart {
+	return &Chart{
+		Type:  chartType,
+		Title: title,
+		YAxis: ChartAxis{MajorGridlines: true},
+	}
+}
+
+// AddSeries adds a series of values to the Chart, and returns the
+// Chart so that calls can be chained.
+func (c *Chart) AddSeries(name, categories, values string) *Chart {
+	c.Series = append(c.Series, ChartSeries{Name: name, Categories: categories, Values: values})
+	return c
+}
+
+// sheetChart is a Chart drawn on a Sheet, at a cell.
+type sheetChart struct {
+	chart         *Chart
+	cell          string
+	width, height int
+}
+
+// AddChart draws a Chart on the Sheet with its top left corner at the
+// cell cellRef, such as "E2".  width and height are in pixels, and a
+// width or height of 0 gives the default size of 480 by 288 pixels.
+func (s *Sheet) AddChart(cellRef string, chart *Chart, width, height int) error {
+	switch chart.Type {
+	case "bar", "column", "line", "pie", "scatter", "area":
+	default:
+		return fmt.Errorf("unknown chart type %q", chart.Type)
+	}
+	if _, ok := chartLegendPositions[chart.Legend]; !ok && chart.Legend != "none" {
+		return fmt.Errorf("unknown chart legend position %q", chart.Legend)
+	}
+	if len(chart.Series) == 0 {
+		return fmt.Errorf("chart %q has no series", chart.Title)
+	}
+	for _, series := range chart.Series {
+		if series.Values == "" {
+			return fmt.Errorf("series %q of chart %q has no values", series.Name, chart.Title)
+		}
+	}
+	if _, _, err := getCoordsFromCellIDString(cellRef); err != nil {
+		return fmt.Errorf("invalid cell reference %q: %s", cellRef, err)
+	}
+	if width <= 0 {
+		width = defaultChartWidth
+	}
+	if height <= 0 {
+		height = defaultChartHeight
+	}
+	s.charts = append(s.charts, &sheetChart{chart: chart, cell: cellRef, width: width, height: height})
+	return nil
+}
+
+// makeDrawingAnchor returns where the chart is drawn on s.  Charts are
+// moved and resized with the cells they cover, as they are in Excel.
+func (sc *sheetChart) makeDrawingAnchor(s *Sheet) (drawingAnchor, error) {
+	col, row, err := getCoordsFromCellIDString(sc.cell)
+	if err != nil {
+		return drawingAnchor{}, err
+	}
+	return s.makeDrawingAnchor("twoCell", col, row, 0, 0, sc.width, sc.height), nil
+}
+
+// marshalGraphicFrame returns the xdr:graphicFrame element, with the
+// given drawing object id, that shows the chart that is the drawing's
+// relationship rId.
+func (sc *sheetChart) marshalGraphicFrame(id int, rId string, anchor drawingAnchor) string {
+	return fmt.Sprintf(`<xdr:graphicFrame macro=""><xdr:nvGraphicFramePr><xdr:cNvPr id="%d" name="Chart %d" descr="%s"/><xdr:cNvGraphicFramePr/></xdr:nvGraphicFramePr>`+
+		`<xdr:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></xdr:xfrm>`+
+		`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/chart"><c:chart xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" r:id="%s"/></a:graphicData></a:graphic></xdr:graphicFrame>`,
+		id, id-1, escapeAttr(sc.chart.Title),
+		anchor.x*emuPerPixel, anchor.y*emuPerPixel, anchor.width*emuPerPixel, anchor.height*emuPerPixel, rId)
+}
+
+// marshal returns the chart part, c:chartSpace, for the Chart.  The
+// caches of the values of its series are filled from the cells of f
+// that they refer to.
+func (c *Chart) marshal(f *File) string {
+	var plot string
+	axes := `<c:axId val="1"/><c:axId val="2"/>`
+	switch c.Type {
+	case "bar", "column":
+		barDir := "col"
+		if c.Type == "bar" {
+			barDir = "bar"
+		}
+		plot = `<c:barChart><c:barDir val="` + barDir + `"/><c:grouping val="clustered"/><c:varyColors val="0"/>` +
+			c.marshalSeries(f) + `<c:gapWidth val="150"/>` + axes + `</c:barChart>` +
+			c.marshalAxes(barDir == "bar")
+	case "line":
+		plot = `<c:lineChart><c:grouping val="standard"/><c:varyColors val="0"/>` +
+			c.marshalSeries(f) + `<c:marker val="1"/>` + axes + `</c:lineChart>` +
+			c.marshalAxes(false)
+	case "area":
+		plot = `<c:areaChart><c:grouping val="standard"/><c:varyColors val="0"/>` +
+			c.marshalSeries(f) + axes + `</c:areaChart>` +
+			c.marshalAxes(false)
+	case "scatter":
+		plot = `<c:scatterChart><c:scatterStyle val="lineMarker"/><c:varyColors val="0"/>` +
+			c.marshalSeries(f) + axes + `</c:scatterChart>` +
+			c.marshalAxes(false)
+	case "pie":
+		plot = `<c:pieChart><c:varyColors val="1"/>` +
+			c.marshalSeries(f) + `<c:firstSliceAng val="0"/></c:pieChart>`
+	}
+
+	title := `<c:autoTitleDeleted val="1"/>`
+	if c.Title != "" {
+		title = marshalChartTitle(c.Title) + `<c:autoTitleDeleted val="0"/>`
+	}
+	legend := ""
+	if c.Legend != "none" {
+		legend = `<c:legend><c:legendPos val="` + chartLegendPositions[c.Legend] + `"/><c:overlay val="0"/></c:legend>`
+	}
+	return `<c:chartSpace xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="` + relationshipsNS + `">` +
+		`<c:roundedCorners val="0"/><c:chart>` + title +
+		`<c:plotArea><c:layout/>` + plot + `</c:plotArea>` +
+		legend + `<c:plotVisOnly val="1"/><c:dispBlanksAs val="gap"/></c:chart></c:chartSpace>`
+}
+
+// marshalSeries returns the c:ser elements of the Chart.
+func (c *Chart) marshalSeries(f *File) string {
+	result := ""
+	for i, series := range c.Series {
+		result += fmt.Sprintf(`<c:ser><c:idx val="%d"/><c:order val="%d"/>`, i, i)
+		if series.Name != "" {
+			result += `<c:tx><c:v>` + escapeAttr(series.Name) + `</c:v></c:tx>`
+		}
+		switch c.Type {
+		case "bar", "column":
+			result += `<c:invertIfNegative val="0"/>`
+		case "line":
+			result += `<c:marker><c:symbol val="none"/></c:marker>`
+		case "scatter":
+			result += `<c:spPr><a:ln w="19050"><a:noFill/></a:ln></c:spPr>`
+		}
+		if c.Type == "scatter" {
+			if series.Categories != "" {
+				result += `<c:xVal>` + marshalChartNumRef(f, series.Categories) + `</c:xVal>`
+			}
+			result += `<c:yVal>` + marshalChartNumRef(f, series.Values) + `</c:yVal><c:smooth val="0"/>`
+		} else {
+			if series.Categories != "" {
+				result += `<c:cat>` + marshalChartStrRef(f, series.Categories) + `</c:cat>`
+			}
+			result += `<c:val>` + marshalChartNumRef(f, series.Values) + `</c:val>`
+			if c.Type == "line" {
+				result += `<c:smooth val="0"/>`
+			}
+		}
+		result += `</c:ser>`
+	}
+	return result
+}
+
+// marshalAxes returns the axes of the Chart, whose ids are 1 for the
+// category, or X, axis and 2 for the value axis.  Bar charts have
+// their category axis on the left, and their value axis at the bottom.
+func (c *Chart) marshalAxes(horizontal bool) string {
+	catPos, valPos := "b", "l"
+	if horizontal {
+		catPos, valPos = "l", "b"
+	}
+	axis := func(element string, id, crossAx int, pos string, a ChartAxis, extra string) string {
+		result := fmt.Sprintf(`<c:%s><c:axId val="%d"/><c:scaling><c:orientation val="minMax"/></c:scaling><c:delete val="0"/><c:axPos val="%s"/>`, element, id, pos)
+		if a.MajorGridlines {
+			result += `<c:majorGridlines/>`
+		}
+		if a.Title != "" {
+			result += marshalChartTitle(a.Title)
+		}
+		return result + fmt.Sprintf(`<c:numFmt formatCode="General" sourceLinked="1"/><c:majorTickMark val="out"/><c:minorTickMark val="none"/><c:tickLblPos val="nextTo"/><c:crossAx val="%d"/><c:crosses val="autoZero"/>`, crossAx) +
+			extra + `</c:` + element + `>`
+	}
+	if c.Type == "scatter" {
+		return axis("valAx", 1, 2, catPos, c.XAxis, `<c:crossBetween val="midCat"/>`) +
+			axis("valAx", 2, 1, valPos, c.YAxis, `<c:crossBetween val="midCat"/>`)
+	}
+	return axis("catAx", 1, 2, catPos, c.XAxis, `<c:auto val="1"/><c:lblAlgn val="ctr"/><c:lblOffset val="100"/><c:noMultiLvlLbl val="0"/>`) +
+		axis("valAx", 2, 1, valPos, c.YAxis, `<c:crossBetween val="between"/>`)
+}
+
+// marshalChartTitle returns a c:title element with the given text.
+func marshalChartTitle(text string) string {
+	return `<c:title><c:tx><c:rich><a:bodyPr/><a:p><a:r><a:t>` + escapeAttr(text) + `</a:t></a:r></a:p></c:rich></c:tx><c:overlay val="0"/></c:title>`
+}
+
+// marshalChartNumRef returns a c:numRef element that refers to the
+// cells in ref, with a cache of their numeric values.
+func marshalChartNumRef(f *File, ref string) string {
+	result := `<c:numRef><c:f>` + escapeAttr(ref) + `</c:f>`
+	if cells, ok := f.rangeCells(ref); ok {
+		result += fmt.Sprintf(`<c:numCache><c:formatCode>General</c:formatCode><c:ptCount val="%d"/>`, len(cells))
+		for i, cell := range cells {
+			if cell == nil {
+				continue
+			}
+			if value, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+				result += fmt.Sprintf(`<c:pt idx="%d"><c:v>%s</c:v></c:pt>`, i, strconv.FormatFloat(value, 'g', -1, 64))
+			}
+		}
+		result += `</c:numCache>`
+	}
+	return result + `</c:numRef>`
+}
+
+// marshalChartStrRef returns a c:strRef element that refers to the
+// cells in ref, with a cache of their values as they are displayed.
+func marshalChartStrRef(f *File, ref string) string {
+	result := `<c:strRef><c:f>` + escapeAttr(ref) + `</c:f>`
+	if cells, ok := f.rangeCells(ref); ok {
+		result += fmt.Sprintf(`<c:strCache><c:ptCount val="%d"/>`, len(cells))
+		for i, cell := range cells {
+			if cell != nil {
+				result += fmt.Sprintf(`<c:pt idx="%d"><c:v>%s</c:v></c:pt>`, i, escapeAttr(cell.String()))
+			}
+		}
+		result += `</c:strCache>`
+	}
+	return result + `</c:strRef>`
+}
+
+// rangeCells returns the cells, row by row, in the range that ref,
+// such as "Sheet1!$B$2:$B$5" or "'My Sheet'!A1:C1", refers to.  Cells
+// in the range that don't exist are returned as nil.  ok is false if
+// ref isn't a range of a Sheet in the File.
+func (f *File) rangeCells(ref string) (cells []*Cell, ok bool) {
+	name, x1, y1, x2, y2, ok := parseRangeRef(ref)
+	if !ok {
+		return nil, false
+	}
+	sheet, ok := f.Sheet[name]
+	if !ok {
+		return nil, false
+	}
+	for y := y1; y <= y2; y++ {
+		for x := x1; x <= x2; x++ {
+			var cell *Cell
+			if y < len(sheet.Rows) && sheet.Rows[y] != nil && x < len(sheet.Rows[y].Cells) {
+				cell = sheet.Rows[y].Cells[x]
+			}
+			cells = append(cells, cell)
+		}
+	}
+	return cells, true
+}
+
+// parseRangeRef splits a reference to a range of cells on a sheet,
+// such as "Sheet1!$B$2:$B$5" or "'My Sheet'!A1", into the name of the
+// sheet and the zero based coordinates of the range's corners.  ok is
+// false if ref isn't such a reference.
+func parseRangeRef(ref string) (sheet string, x1, y1, x2, y2 int, ok bool) {
+	sheet, cells, err := splitSheetRef(ref)
+	if err != nil {
+		return "", 0, 0, 0, 0, false
+	}
+	x1, y1, x2, y2, ok = parseCellRange(cells)
+	if !ok {
+		return "", 0, 0, 0, 0, false
+	}
+	return sheet, x1, y1, x2, y2, true
+}
+
+// parseCellRange returns the zero based coordinates of the corners of
+// a range of cells, such as "$B$2:$B$5" or "A1", without a sheet.  ok
+// is false if cells isn't such a range.
+func parseCellRange(cells string) (x1, y1, x2, y2 int, ok bool) {
+	bounds := strings.Split(strings.Replace(cells, "$", "", -1), ":")
+	if len(bounds) == 1 {
+		bounds = append(bounds, bounds[0])
+	}
+	if len(bounds) != 2 {
+		return 0, 0, 0, 0, false
+	}
+	x1, y1, err := getCoordsFromCellIDString(bounds[0])
+	if err != nil {
+		return 0, 0, 0, 0, false
+	}
+	x2, y2, err = getCoordsFromCellIDString(bounds[1])
+	if err != nil || x1 < 0 || y1 < 0 || x2 < x1 || y2 < y1 {
+		return 0, 0, 0, 0, false
+	}
+	return x1, y1, x2, y2, true
+}
@@ -0,0 +1,167 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type ChartSuite struct{}
+
+var _ = Suite(&ChartSuite{})
+
+// salesFile returns a File with a sheet of sales by quarter.
+func salesFile() (*File, *Sheet) {
+	f := NewFile()
+	sheet := f.AddSheet("Sales & Costs")
+	row := sheet.AddRow()
+	row.AddCell().SetString("Quarter")
+	row.AddCell().SetString("Sales")
+	row = sheet.AddRow()
+	row.AddCell().SetString("Q1")
+	row.AddCell().SetFloat(10)
+	row = sheet.AddRow()
+	row.AddCell().SetString("Q2")
+	row.AddCell().SetFloat(12.5)
+	return f, sheet
+}
+
+func (s *ChartSuite) TestAddChart(c *C) {
+	f, sheet := salesFile()
+	chart := NewChart("column", "Sales by quarter").
+		AddSeries("Sales", "'Sales & Costs'!$A$2:$A$3", "'Sales & Costs'!$B$2:$B$3")
+	chart.XAxis.Title = "Quarter"
+	c.Assert(sheet.AddChart("D2", chart, 0, 0), IsNil)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/drawings/_rels/drawing1.xml.rels"], Equals, `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Target="../charts/chart1.xml" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/chart"></Relationship></Relationships>`)
+	c.Assert(strings.Contains(parts["xl/drawings/drawing1.xml"], `<xdr:twoCellAnchor><xdr:from><xdr:col>3</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>1</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from><xdr:to><xdr:col>10</xdr:col><xdr:colOff>171450</xdr:colOff><xdr:row>17</xdr:row><xdr:rowOff>152400</xdr:rowOff></xdr:to><xdr:graphicFrame macro="">`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/drawings/drawing1.xml"], `<c:chart xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" r:id="rId1"/>`), Equals, true)
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], `<Override PartName="/xl/charts/chart1.xml" ContentType="application/vnd.openxmlformats-officedocument.drawingml.chart+xml"></Override>`), Equals, true)
+
+	part := parts["xl/charts/chart1.xml"]
+	c.Assert(xml.Unmarshal([]byte(part), new(struct{})), IsNil)
+	c.Assert(strings.Contains(part, `<c:title><c:tx><c:rich><a:bodyPr/><a:p><a:r><a:t>Sales by quarter</a:t></a:r></a:p></c:rich></c:tx><c:overlay val="0"/></c:title><c:autoTitleDeleted val="0"/>`), Equals, true)
+	c.Assert(strings.Contains(part, `<c:barChart><c:barDir val="col"/><c:grouping val="clustered"/><c:varyColors val="0"/><c:ser><c:idx val="0"/><c:order val="0"/><c:tx><c:v>Sales</c:v></c:tx><c:invertIfNegative val="0"/>`+
+		`<c:cat><c:strRef><c:f>&#39;Sales &amp; Costs&#39;!$A$2:$A$3</c:f><c:strCache><c:ptCount val="2"/><c:pt idx="0"><c:v>Q1</c:v></c:pt><c:pt idx="1"><c:v>Q2</c:v></c:pt></c:strCache></c:strRef></c:cat>`+
+		`<c:val><c:numRef><c:f>&#39;Sales &amp; Costs&#39;!$B$2:$B$3</c:f><c:numCache><c:formatCode>General</c:formatCode><c:ptCount val="2"/><c:pt idx="0"><c:v>10</c:v></c:pt><c:pt idx="1"><c:v>12.5</c:v></c:pt></c:numCache></c:numRef></c:val></c:ser>`), Equals, true)
+	c.Assert(strings.Contains(part, `<c:catAx><c:axId val="1"/><c:scaling><c:orientation val="minMax"/></c:scaling><c:delete val="0"/><c:axPos val="b"/><c:title>`), Equals, true)
+	c.Assert(strings.Contains(part, `<c:valAx><c:axId val="2"/><c:scaling><c:orientation val="minMax"/></c:scaling><c:delete val="0"/><c:axPos val="l"/><c:majorGridlines/>`), Equals, true)
+	c.Assert(strings.Contains(part, `<c:legend><c:legendPos val="r"/><c:overlay val="0"/></c:legend>`), Equals, true)
+}
+
+func (s *ChartSuite) TestChartTypes(c *C) {
+	f, sheet := salesFile()
+	for chartType, element := range map[string]string{
+		"bar":     `<c:barChart><c:barDir val="bar"/>`,
+		"line":    `<c:lineChart>`,
+		"area":    `<c:areaChart>`,
+		"scatter": `<c:scatterChart>`,
+		"pie":     `<c:pieChart>`,
+	} {
+		chart := NewChart(chartType, "").AddSeries("", "'Sales & Costs'!B2:B3", "'Sales & Costs'!B2:B3")
+		chart.Legend = "none"
+		part := chart.marshal(f)
+		c.Assert(strings.Contains(part, element), Equals, true, Commentf(chartType))
+		c.Assert(strings.Contains(part, `<c:autoTitleDeleted val="1"/>`), Equals, true)
+		c.Assert(strings.Contains(part, `<c:legend>`), Equals, false)
+		c.Assert(strings.Contains(part, `<c:catAx>`), Equals, chartType != "pie" && chartType != "scatter", Commentf(chartType))
+		c.Assert(sheet.AddChart("D2", chart, 100, 100), IsNil)
+	}
+	c.Assert(sheet.charts, HasLen, 5)
+}
+
+func (s *ChartSuite) TestAddChartErrors(c *C) {
+	sheet := NewFile().AddSheet("Sheet1")
+	c.Assert(sheet.AddChart("A1", NewChart("radar", ""), 0, 0), ErrorMatches, `unknown chart type "radar"`)
+	c.Assert(sheet.AddChart("A1", NewChart("pie", "Empty"), 0, 0), ErrorMatches, `chart "Empty" has no series`)
+	c.Assert(sheet.AddChart("A1", NewChart("pie", "Pie").AddSeries("", "", ""), 0, 0), ErrorMatches, `series "" of chart "Pie" has no values`)
+	chart := NewChart("pie", "Pie").AddSeries("", "", "Sheet1!A1:A2")
+	chart.Legend = "middle"
+	c.Assert(sheet.AddChart("A1", chart, 0, 0), ErrorMatches, `unknown chart legend position "middle"`)
+	c.Assert(sheet.charts, HasLen, 0)
+}
+
+func (s *ChartSuite) TestRangeCells(c *C) {
+	f, _ := salesFile()
+	cells, ok := f.rangeCells("='Sales & Costs'!$A$1:$B$2")
+	c.Assert(ok, Equals, true)
+	c.Assert(cells, HasLen, 4)
+	c.Assert(cells[1].Value, Equals, "Sales")
+	c.Assert(cells[2].Value, Equals, "Q1")
+	cells, ok = f.rangeCells("'Sales & Costs'!C5")
+	c.Assert(ok, Equals, true)
+	c.Assert(cells, DeepEquals, []*Cell{nil})
+	_, ok = f.rangeCells("Missing!A1:A2")
+	c.Assert(ok, Equals, false)
+	_, ok = f.rangeCells("A1:A2")
+	c.Assert(ok, Equals, false)
+	// Rows and columns before the first aren't a range.
+	_, ok = f.rangeCells("'Sales & Costs'!$A$0:$A$3")
+	c.Assert(ok, Equals, false)
+}
+
+// A chart whose series refer to a range before the first row is
+// written without values, rather than panicking.
+func (s *ChartSuite) TestChartWithInvalidRange(c *C) {
+	f, sheet := salesFile()
+	c.Assert(sheet.AddChart("D2", NewChart("line", "").AddSeries("Sales", "", "'Sales & Costs'!$A$0:$A$3"), 0, 0), IsNil)
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+}
+
+// A chart and a picture share the drawing of their sheet, and the
+// drawing is written back out as it was when the file is read.
+func (s *ChartSuite) TestChartAndPictureRoundTrip(c *C) {
+	f, sheet := salesFile()
+	c.Assert(sheet.AddChart("D2", NewChart("line", "").AddSeries("Sales", "", "'Sales & Costs'!$B$2:$B$3"), 0, 0), IsNil)
+	c.Assert(sheet.AddPicture("A5", testPNG(c, 2, 2), "png", nil), IsNil)
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	drawing := parts["xl/drawings/drawing1.xml"]
+	c.Assert(strings.Contains(drawing, `<xdr:cNvPr id="2" name="Picture 1" descr=""/>`), Equals, true)
+	c.Assert(strings.Contains(drawing, `<xdr:cNvPr id="3" name="Chart 2" descr=""/>`), Equals, true)
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	c.Assert(f2.Sheet["Sales & Costs"].Pictures(), HasLen, 1)
+	parts2, err := f2.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts2["xl/drawings/drawing1.xml"], Equals, drawing)
+	c.Assert(parts2["xl/charts/chart1.xml"], Equals, parts["xl/charts/chart1.xml"])
+	c.Assert(strings.Contains(parts2["[Content_Types].xml"], `<Override PartName="/xl/charts/chart1.xml"`), Equals, true)
+}
+
+// A chart added to a Sheet read with a drawing is added to that
+// drawing, beside what was already on it.
+func (s *ChartSuite) TestAddChartToRetainedDrawing(c *C) {
+	f, sheet := salesFile()
+	c.Assert(sheet.AddPicture("A5", testPNG(c, 2, 2), "png", nil), IsNil)
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+
+	sheet2 := f2.Sheet["Sales & Costs"]
+	c.Assert(sheet2.AddChart("D2", NewChart("column", "Sales").AddSeries("Sales", "", "'Sales & Costs'!$B$2:$B$3"), 0, 0), IsNil)
+	parts, err := f2.MarshallParts()
+	c.Assert(err, IsNil)
+	drawing := parts["xl/drawings/drawing1.xml"]
+	c.Assert(strings.Contains(drawing, `<xdr:cNvPr id="2" name="Picture 1" descr=""/>`), Equals, true)
+	c.Assert(strings.Contains(drawing, `<xdr:cNvPr id="3" name="Chart 2" descr="Sales"/>`), Equals, true)
+	c.Assert(strings.Contains(drawing, `<c:chart xmlns:c="http://schemas.openxmlformats.org/drawingml/2006/chart" r:id="rId2"/>`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/drawings/_rels/drawing1.xml.rels"], `<Relationship Id="rId2" Target="../charts/chart1.xml" Type="`+chartRelType+`">`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/charts/chart1.xml"], "<c:barChart>"), Equals, true)
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], `<Override PartName="/xl/charts/chart1.xml"`), Equals, true)
+}
@@ -0,0 +1,553 @@
+package xlsx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// CSVOptions control how ReadCSV reads comma separated values into a
+// Sheet.
+type CSVOptions struct {
+	// Delimiter separates the fields of a record.  It defaults to
+	// ','.
+	Delimiter rune
+	// Quote encloses fields that hold delimiters, quotes or line
+	// breaks, and is doubled to put it in such a field.  It defaults
+	// to '"'.
+	Quote rune
+	// Encoding is "utf-8", "utf-16le", "utf-16be", "utf-16" (which
+	// goes by the byte order mark, or is little endian without one)
+	// or "windows-1252".  If it is empty the byte order mark, if
+	// there is one, says whether it is UTF-8 or UTF-16, and otherwise
+	// it is UTF-8.
+	Encoding string
+	// NoTypeInference reads every field as a string.  Otherwise
+	// fields that look like numbers, dates or booleans become cells
+	// of those types.  Numbers with leading zeros, such as "007",
+	// stay strings.
+	NoTypeInference bool
+	// DateLayouts are the layouts, in the form of the time package,
+	// that dates are parsed with.  They default to "2006-01-02",
+	// "2006-01-02 15:04:05" and time.RFC3339.
+	DateLayouts []string
+	// DateFormat is the number format of the dates.  It defaults to
+	// "yyyy-mm-dd", or "yyyy-mm-dd hh:mm:ss" for dates with a time.
+	DateFormat string
+	// Header reads the first record as a header row, which is left
+	// as strings and, if HeaderStyle is set, given that Style.
+	Header      bool
+	HeaderStyle *Style
+}
+
+var defaultCSVDateLayouts = []string{"2006-01-02", "2006-01-02 15:04:05", time.RFC3339}
+
+// ImportCSV adds a Sheet called sheetName to the File, and reads the
+// comma separated values from r into it with ReadCSV.  opts may be
+// nil.
+func (f *File) ImportCSV(r io.Reader, sheetName string, opts *CSVOptions) (*Sheet, error) {
+	if _, exists := f.Sheet[sheetName]; exists {
+		return nil, fmt.Errorf("the file already has a sheet called %q", sheetName)
+	}
+	records, err := readCSVRecords(r, opts)
+	if err != nil {
+		return nil, err
+	}
+	sheet := f.AddSheet(sheetName)
+	sheet.addCSVRecords(records, opts)
+	return sheet, nil
+}
+
+// ReadCSV reads comma separated values from r, and adds a row to the
+// Sheet, below any rows it already has, for each record.  opts may be
+// nil.  Nothing is added if the values can't be read.
+func (s *Sheet) ReadCSV(r io.Reader, opts *CSVOptions) error {
+	records, err := readCSVRecords(r, opts)
+	if err != nil {
+		return err
+	}
+	s.addCSVRecords(records, opts)
+	return nil
+}
+
+// addCSVRecords adds a row to the Sheet for each record.
+func (s *Sheet) addCSVRecords(records [][]string, opts *CSVOptions) {
+	options := CSVOptions{}
+	if opts != nil {
+		options = *opts
+	}
+	if options.DateLayouts == nil {
+		options.DateLayouts = defaultCSVDateLayouts
+	}
+	for i, record := range records {
+		row := s.AddRow()
+		for _, field := range record {
+			cell := row.AddCell()
+			if i == 0 && options.Header {
+				cell.SetString(field)
+				if options.HeaderStyle != nil {
+					cell.SetStyle(options.HeaderStyle)
+				}
+				continue
+			}
+			options.setCell(cell, field)
+		}
+	}
+}
+
+// setCell sets the value of cell to field, as the type that it looks
+// like unless NoTypeInference is set.
+func (o *CSVOptions) setCell(cell *Cell, field string) {
+	if field == "" {
+		return
+	}
+	if o.NoTypeInference {
+		cell.SetString(field)
+		return
+	}
+	switch {
+	case strings.EqualFold(field, "true"):
+		cell.SetBool(true)
+		return
+	case strings.EqualFold(field, "false"):
+		cell.SetBool(false)
+		return
+	}
+	if isCSVNumber(field) {
+		if n, err := strconv.ParseInt(field, 10, 64); err == nil {
+			cell.SetInt64(n)
+			return
+		}
+		if n, err := strconv.ParseFloat(field, 64); err == nil && !math.IsInf(n, 0) {
+			cell.setGeneralFloat(n)
+			return
+		}
+	}
+	for _, layout := range o.DateLayouts {
+		t, err := time.Parse(layout, field)
+		if err != nil {
+			continue
+		}
+		format := o.DateFormat
+		if format == "" {
+			format = "yyyy-mm-dd"
+			if t.Hour() != 0 || t.Minute() != 0 || t.Second() != 0 || t.Nanosecond() != 0 {
+				format = "yyyy-mm-dd hh:mm:ss"
+			}
+		}
+		cell.SetDateTimeWithFormat(t, format)
+		return
+	}
+	cell.SetString(field)
+}
+
+// isCSVNumber reports whether s is a decimal number, with an optional
+// sign, fraction and exponent, that doesn't start with a zero that
+// would be lost, as the zero of "007" would.  Nor can it have more than
+// the 15 significant digits that Excel keeps, so that card numbers and
+// long ids aren't rounded.
+func isCSVNumber(s string) bool {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	start := i
+	for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+		i++
+	}
+	digits := i - start
+	if digits > 1 && s[start] == '0' {
+		return false
+	}
+	mantissa := s[start:i]
+	if i < len(s) && s[i] == '.' {
+		i++
+		fraction := i
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		digits += i - fraction
+		mantissa += s[fraction:i]
+	}
+	if digits == 0 || len(strings.TrimLeft(mantissa, "0")) > 15 {
+		return false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		exponent := i
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		if i == exponent {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// readCSVRecords reads all of r, decodes it and splits it into
+// records.
+func readCSVRecords(r io.Reader, opts *CSVOptions) ([][]string, error) {
+	options := CSVOptions{}
+	if opts != nil {
+		options = *opts
+	}
+	if options.Delimiter == 0 {
+		options.Delimiter = ','
+	}
+	if options.Quote == 0 {
+		options.Quote = '"'
+	}
+	if options.Delimiter == options.Quote || options.Delimiter == '\r' || options.Delimiter == '\n' ||
+		options.Quote == '\r' || options.Quote == '\n' {
+		return nil, fmt.Errorf("invalid CSV delimiter %q and quote %q", options.Delimiter, options.Quote)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text, err := decodeCSV(data, options.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	return parseCSV(text, options.Delimiter, options.Quote)
+}
+
+var (
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+)
+
+// decodeCSV returns data, in the given encoding, as a string, without
+// a byte order mark.
+func decodeCSV(data []byte, encoding string) (string, error) {
+	switch strings.ToLower(encoding) {
+	case "":
+		switch {
+		case bytes.HasPrefix(data, utf16LEBOM):
+			return decodeUTF16(data[2:], false)
+		case bytes.HasPrefix(data, utf16BEBOM):
+			return decodeUTF16(data[2:], true)
+		}
+		fallthrough
+	case "utf-8", "utf8":
+		data = bytes.TrimPrefix(data, utf8BOM)
+		if !utf8.Valid(data) {
+			return "", fmt.Errorf("the CSV isn't valid UTF-8, so its encoding needs to be given")
+		}
+		return string(data), nil
+	case "utf-16":
+		if bytes.HasPrefix(data, utf16BEBOM) {
+			return decodeUTF16(data[2:], true)
+		}
+		return decodeUTF16(bytes.TrimPrefix(data, utf16LEBOM), false)
+	case "utf-16le":
+		return decodeUTF16(bytes.TrimPrefix(data, utf16LEBOM), false)
+	case "utf-16be":
+		return decodeUTF16(bytes.TrimPrefix(data, utf16BEBOM), true)
+	case "windows-1252", "cp1252":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+			if 0x80 <= b && b < 0xa0 {
+				runes[i] = windows1252[b-0x80]
+			}
+		}
+		return string(runes), nil
+	}
+	return "", fmt.Errorf("unknown CSV encoding %q", encoding)
+}
+
+// decodeUTF16 returns the UTF-16 data as a string.
+func decodeUTF16(data []byte, bigEndian bool) (string, error) {
+	if len(data)%2 != 0 {
+		return "", fmt.Errorf("the CSV has an odd number of bytes, so it can't be UTF-16")
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// windows1252 holds the characters of the bytes 0x80 to 0x9f in
+// Windows-1252, which, unlike the rest, differ from ISO-8859-1.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// csvParser splits decoded CSV text into records of fields.
+type csvParser struct {
+	runes     []rune
+	pos       int
+	line      int
+	delimiter rune
+	quote     rune
+}
+
+// parseCSV splits text into records of fields.  Records end with
+// "\n", "\r\n" or "\r", and a line break at the end of the text
+// doesn't start another record.  Blank lines are records without any
+// fields.
+func parseCSV(text string, delimiter, quote rune) ([][]string, error) {
+	p := &csvParser{runes: []rune(text), line: 1, delimiter: delimiter, quote: quote}
+	var records [][]string
+	for p.pos < len(p.runes) {
+		if p.atLineBreak() {
+			p.skipLineBreak()
+			records = append(records, nil)
+			continue
+		}
+		var record []string
+		for {
+			field, err := p.field()
+			if err != nil {
+				return nil, err
+			}
+			record = append(record, field)
+			if p.pos < len(p.runes) && p.runes[p.pos] == p.delimiter {
+				p.pos++
+				continue
+			}
+			p.skipLineBreak()
+			break
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// field reads the field that starts at the parser's position, which is
+// left at the delimiter or line break after it, or the end of the
+// text.
+func (p *csvParser) field() (string, error) {
+	start := p.pos
+	if start == len(p.runes) || p.runes[start] != p.quote {
+		for p.pos < len(p.runes) && p.runes[p.pos] != p.delimiter && !p.atLineBreak() {
+			p.pos++
+		}
+		return string(p.runes[start:p.pos]), nil
+	}
+	// A quoted field runs to the next quote that isn't doubled.
+	line := p.line
+	var field []rune
+	p.pos++
+	for {
+		if p.pos == len(p.runes) {
+			return "", fmt.Errorf("line %d of the CSV has a quoted field without a closing quote", line)
+		}
+		r := p.runes[p.pos]
+		p.pos++
+		if r == '\n' {
+			p.line++
+		}
+		if r != p.quote {
+			field = append(field, r)
+		} else if p.pos < len(p.runes) && p.runes[p.pos] == p.quote {
+			field = append(field, r)
+			p.pos++
+		} else {
+			break
+		}
+	}
+	if p.pos < len(p.runes) && p.runes[p.pos] != p.delimiter && !p.atLineBreak() {
+		return "", fmt.Errorf("line %d of the CSV has %q after a quoted field", p.line, p.runes[p.pos])
+	}
+	return string(field), nil
+}
+
+// atLineBreak reports whether the parser is at a line break.
+func (p *csvParser) atLineBreak() bool {
+	return p.pos < len(p.runes) && (p.runes[p.pos] == '\r' || p.runes[p.pos] == '\n')
+}
+
+// skipLineBreak moves the parser past the line break it is at, if
+// it is at one.
+func (p *csvParser) skipLineBreak() {
+	if !p.atLineBreak() {
+		return
+	}
+	if p.runes[p.pos] == '\r' && p.pos+1 < len(p.runes) && p.runes[p.pos+1] == '\n' {
+		p.pos++
+	}
+	p.pos++
+	p.line++
+}
+
+// CSVQuoting says which fields WriteCSV puts in quotes.
+type CSVQuoting int
+
+const (
+	// CSVQuoteMinimal quotes the fields that hold the delimiter, a
+	// quote or a line break, or start with a space.
+	CSVQuoteMinimal CSVQuoting = iota
+	// CSVQuoteAll quotes every field.
+	CSVQuoteAll
+	// CSVQuoteNonNumeric quotes every field but those of numeric
+	// cells that aren't dates, and those that are empty.
+	CSVQuoteNonNumeric
+	// CSVQuoteNever quotes nothing, even fields that need it.
+	CSVQuoteNever
+)
+
+// CSVWriteOptions control how WriteCSV writes a Sheet as comma
+// separated values.
+type CSVWriteOptions struct {
+	// Delimiter separates the fields of a record.  It defaults to
+	// ',', and '\t' gives tab separated values.
+	Delimiter rune
+	// Quoting says which fields are put in quotes.
+	Quoting CSVQuoting
+	// UseCRLF ends records with "\r\n" rather than "\n".
+	UseCRLF bool
+	// Raw writes the values of the cells as they're stored, rather
+	// than as their number formats show them.
+	Raw bool
+	// DateLayout, if set, is the layout, in the form of the time
+	// package, that the cells whose number formats show dates are
+	// written in, whether or not Raw is set.
+	DateLayout string
+	// SkipHiddenRows and SkipHiddenCols leave out the rows and
+	// columns that are hidden.
+	SkipHiddenRows bool
+	SkipHiddenCols bool
+	// FillMerged writes the value of a merged range, which is kept
+	// in its top left cell, in each of its cells.
+	FillMerged bool
+	// TrimTrailingEmpty leaves out the empty fields at the end of
+	// each record.  Otherwise every record has a field for each
+	// column.
+	TrimTrailingEmpty bool
+	// Range, such as "B2:D10", is the range of cells that is
+	// written.  It defaults to all of the Sheet.
+	Range string
+}
+
+// WriteCSV writes the Sheet to w as comma separated values, with a
+// record for each row.  opts may be nil.
+func (s *Sheet) WriteCSV(w io.Writer, opts *CSVWriteOptions) error {
+	options := CSVWriteOptions{}
+	if opts != nil {
+		options = *opts
+	}
+	if options.Delimiter == 0 {
+		options.Delimiter = ','
+	}
+	if options.Delimiter == '"' || options.Delimiter == '\r' || options.Delimiter == '\n' {
+		return fmt.Errorf("invalid CSV delimiter %q", options.Delimiter)
+	}
+	bounds, err := s.rangeBounds(options.Range)
+	if err != nil {
+		return err
+	}
+	hiddenCols := make(map[int]bool)
+	if options.SkipHiddenCols {
+		for _, col := range s.Cols {
+			for x := col.Min - 1; col.Hidden && x < col.Max; x++ {
+				hiddenCols[x] = true
+			}
+		}
+	}
+	var merged map[[2]int]*Cell
+	if options.FillMerged {
+		merged = s.mergedCells()
+	}
+	lineEnd := "\n"
+	if options.UseCRLF {
+		lineEnd = "\r\n"
+	}
+
+	bw := bufio.NewWriter(w)
+	var fields []string
+	for y := bounds.y1; y <= bounds.y2; y++ {
+		var row *Row
+		if y < len(s.Rows) {
+			row = s.Rows[y]
+		}
+		if options.SkipHiddenRows && row != nil && row.Hidden {
+			continue
+		}
+		fields = fields[:0]
+		for x := bounds.x1; x <= bounds.x2; x++ {
+			if hiddenCols[x] {
+				continue
+			}
+			var cell *Cell
+			if row != nil && x < len(row.Cells) {
+				cell = row.Cells[x]
+			}
+			if topLeft, ok := merged[[2]int{x, y}]; ok {
+				cell = topLeft
+			}
+			fields = append(fields, options.field(cell))
+		}
+		if options.TrimTrailingEmpty {
+			for len(fields) > 0 && fields[len(fields)-1] == "" {
+				fields = fields[:len(fields)-1]
+			}
+		}
+		for i, field := range fields {
+			if i > 0 {
+				bw.WriteRune(options.Delimiter)
+			}
+			bw.WriteString(field)
+		}
+		bw.WriteString(lineEnd)
+	}
+	return bw.Flush()
+}
+
+// field returns the text of cell, which may be nil, quoted as the
+// options say.
+func (o *CSVWriteOptions) field(cell *Cell) string {
+	text := ""
+	numeric := false
+	if cell != nil {
+		numeric = cell.Type() == CellTypeNumeric && !cell.IsDate()
+		switch {
+		case cell.Type() == CellTypeBool:
+			text = strings.ToUpper(strconv.FormatBool(cell.Bool()))
+		case o.DateLayout != "" && cell.IsDate() && cell.Value != "":
+			text = cell.Value
+			if f, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+				text = TimeFromExcelTime(f, cell.isDate1904()).Format(o.DateLayout)
+			}
+		case o.Raw:
+			text = cell.Value
+		default:
+			text = cell.FormattedValue()
+		}
+	}
+	quote := false
+	switch o.Quoting {
+	case CSVQuoteMinimal:
+		quote = strings.HasPrefix(text, " ") || strings.ContainsAny(text, string([]rune{'"', '\r', '\n', o.Delimiter}))
+	case CSVQuoteAll:
+		quote = true
+	case CSVQuoteNonNumeric:
+		quote = text != "" && !numeric
+	}
+	if !quote {
+		return text
+	}
+	return `"` + strings.Replace(text, `"`, `""`, -1) + `"`
+}
@@ -0,0 +1,227 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type CSVSuite struct{}
+
+var _ = Suite(&CSVSuite{})
+
+func (s *CSVSuite) TestImportCSV(c *C) {
+	bold := NewStyle()
+	bold.Font.Bold = true
+	f := NewFile()
+	csv := "\xef\xbb\xbfName,Code,Count,Price,Joined,Seen,Active\r\n" +
+		"\"Smith, Ann\",007,12,1.5,2015-01-02,2015-01-02 12:30:00,TRUE\r\n" +
+		"\"Bob \"\"B\"\"\",0,-3,1.25e-3,someday,,false\r\n"
+	sheet, err := f.ImportCSV(strings.NewReader(csv), "People", &CSVOptions{Header: true, HeaderStyle: bold})
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheet["People"], Equals, sheet)
+	c.Assert(sheet.Rows, HasLen, 3)
+
+	header := sheet.Rows[0].Cells
+	c.Assert(header[0].Value, Equals, "Name")
+	c.Assert(header[0].GetStyle(), Equals, bold)
+	c.Assert(header[6].Value, Equals, "Active")
+
+	ann := sheet.Rows[1].Cells
+	c.Assert(ann[0].TypedValue(), Equals, "Smith, Ann")
+	c.Assert(ann[1].TypedValue(), Equals, "007")
+	c.Assert(ann[2].TypedValue(), Equals, int64(12))
+	c.Assert(ann[2].FormattedValue(), Equals, "12")
+	c.Assert(ann[3].TypedValue(), Equals, 1.5)
+	c.Assert(ann[4].TypedValue(), Equals, time.Date(2015, 1, 2, 0, 0, 0, 0, time.UTC))
+	c.Assert(ann[4].FormattedValue(), Equals, "2015-01-02")
+	c.Assert(ann[5].FormattedValue(), Equals, "2015-01-02 12:30:00")
+	c.Assert(ann[6].TypedValue(), Equals, true)
+
+	bob := sheet.Rows[2].Cells
+	c.Assert(bob, HasLen, 7)
+	c.Assert(bob[0].Value, Equals, `Bob "B"`)
+	c.Assert(bob[1].TypedValue(), Equals, int64(0))
+	c.Assert(bob[2].TypedValue(), Equals, int64(-3))
+	c.Assert(bob[3].TypedValue(), Equals, 0.00125)
+	c.Assert(bob[4].TypedValue(), Equals, "someday")
+	c.Assert(bob[5].Value, Equals, "")
+	c.Assert(bob[6].TypedValue(), Equals, false)
+
+	_, err = f.ImportCSV(strings.NewReader(csv), "People", nil)
+	c.Assert(err, ErrorMatches, `the file already has a sheet called "People"`)
+}
+
+// Dates read into a File whose dates count from 1904 count from 1904
+// too.
+func (s *CSVSuite) TestImportCSVDate1904(c *C) {
+	f := NewFile()
+	f.Date1904 = true
+	sheet, err := f.ImportCSV(strings.NewReader("2020-01-01\n"), "Dates", nil)
+	c.Assert(err, IsNil)
+	cell := sheet.Cell(0, 0)
+	c.Assert(cell.Value, Equals, "42369")
+	c.Assert(cell.FormattedValue(), Equals, "2020-01-01")
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f, err = ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheet["Dates"].Cell(0, 0).FormattedValue(), Equals, "2020-01-01")
+}
+
+func (s *CSVSuite) TestReadCSVOptions(c *C) {
+	sheet := NewFile().AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("Imported")
+	csv := "'a;b';02/01/2015;3\n\n'multi\nline';x;'it''s'"
+	opts := &CSVOptions{Delimiter: ';', Quote: '\'', DateLayouts: []string{"02/01/2006"}, DateFormat: "d-mmm-yy"}
+	c.Assert(sheet.ReadCSV(strings.NewReader(csv), opts), IsNil)
+	c.Assert(sheet.Rows, HasLen, 4)
+	c.Assert(sheet.Rows[1].Cells[0].Value, Equals, "a;b")
+	c.Assert(sheet.Rows[1].Cells[1].FormattedValue(), Equals, "2-Jan-15")
+	c.Assert(sheet.Rows[2].Cells, HasLen, 0)
+	c.Assert(sheet.Rows[3].Cells[0].Value, Equals, "multi\nline")
+	c.Assert(sheet.Rows[3].Cells[2].Value, Equals, "it's")
+
+	sheet = NewFile().AddSheet("Sheet1")
+	c.Assert(sheet.ReadCSV(strings.NewReader("1,true,\n"), &CSVOptions{NoTypeInference: true}), IsNil)
+	c.Assert(sheet.Rows, HasLen, 1)
+	c.Assert(sheet.Rows[0].Cells, HasLen, 3)
+	c.Assert(sheet.Rows[0].Cells[0].Type(), Equals, CellTypeString)
+	c.Assert(sheet.Rows[0].Cells[1].TypedValue(), Equals, "true")
+
+	// Numbers with more digits than Excel keeps are kept as text.
+	sheet = NewFile().AddSheet("Sheet1")
+	c.Assert(sheet.ReadCSV(strings.NewReader("4111111111111111,12345678901234567890,123456789012345\n"), nil), IsNil)
+	c.Assert(sheet.Rows[0].Cells[0].TypedValue(), Equals, "4111111111111111")
+	c.Assert(sheet.Rows[0].Cells[1].TypedValue(), Equals, "12345678901234567890")
+	c.Assert(sheet.Rows[0].Cells[2].TypedValue(), Equals, int64(123456789012345))
+}
+
+func (s *CSVSuite) TestReadCSVEncodings(c *C) {
+	for _, test := range []struct {
+		encoding string
+		data     string
+	}{
+		{"", "\xff\xfec\x00a\x00f\x00\xe9\x00"},
+		{"", "\xfe\xff\x00c\x00a\x00f\x00\xe9"},
+		{"UTF-16LE", "c\x00a\x00f\x00\xe9\x00"},
+		{"utf-16", "\xfe\xff\x00c\x00a\x00f\x00\xe9"},
+		{"windows-1252", "caf\xe9"},
+	} {
+		sheet := NewFile().AddSheet("Sheet1")
+		c.Assert(sheet.ReadCSV(strings.NewReader(test.data), &CSVOptions{Encoding: test.encoding}), IsNil)
+		c.Assert(sheet.Rows[0].Cells[0].Value, Equals, "café")
+	}
+	sheet := NewFile().AddSheet("Sheet1")
+	c.Assert(sheet.ReadCSV(strings.NewReader("\x80 \x93hi\x94"), &CSVOptions{Encoding: "cp1252"}), IsNil)
+	c.Assert(sheet.Rows[0].Cells[0].Value, Equals, "€ “hi”")
+
+	c.Assert(sheet.ReadCSV(strings.NewReader("caf\xe9"), nil), ErrorMatches, "the CSV isn't valid UTF-8, so its encoding needs to be given")
+	c.Assert(sheet.ReadCSV(strings.NewReader("a\x00b"), &CSVOptions{Encoding: "utf-16le"}), ErrorMatches, "the CSV has an odd number of bytes, so it can't be UTF-16")
+	c.Assert(sheet.ReadCSV(strings.NewReader(""), &CSVOptions{Encoding: "latin-9"}), ErrorMatches, `unknown CSV encoding "latin-9"`)
+}
+
+func (s *CSVSuite) TestReadCSVErrors(c *C) {
+	sheet := NewFile().AddSheet("Sheet1")
+	c.Assert(sheet.ReadCSV(strings.NewReader("a,b\n\"c,d\n"), nil), ErrorMatches, `line 2 of the CSV has a quoted field without a closing quote`)
+	c.Assert(sheet.ReadCSV(strings.NewReader("a,\"b\"c\n"), nil), ErrorMatches, `line 1 of the CSV has 'c' after a quoted field`)
+	c.Assert(sheet.ReadCSV(strings.NewReader("a"), &CSVOptions{Delimiter: '"'}), ErrorMatches, `invalid CSV delimiter '"' and quote '"'`)
+	c.Assert(sheet.Rows, HasLen, 0)
+}
+
+func (s *CSVSuite) TestIsCSVNumber(c *C) {
+	for _, number := range []string{"0", "-0", "+12", "1.5", ".5", "0.25", "5.", "1e3", "-2.5E-7", "123456789012345", "0.000123456789012345", "1.23456789012345e30"} {
+		c.Assert(isCSVNumber(number), Equals, true, Commentf(number))
+	}
+	for _, text := range []string{"", "-", ".", "007", "00.5", "1,000", "1e", "0x10", "Inf", "NaN", "1 ",
+		"4111111111111111", "12345678901234567890", "1234567890.123456", "1.234567890123456e5"} {
+		c.Assert(isCSVNumber(text), Equals, false, Commentf(text))
+	}
+}
+
+// makeExportSheet returns a Sheet with values of several types, a
+// hidden row and column, and a merged range.
+func makeExportSheet() *Sheet {
+	sheet := NewFile().AddSheet("Export")
+	row := sheet.AddRow()
+	row.AddCell().SetString("Name")
+	row.AddCell().SetString("Secret")
+	row.AddCell().SetString("Joined")
+	row.AddCell().SetString("Total")
+	row.AddCell().SetString("Member")
+	row = sheet.AddRow()
+	row.AddCell().SetString(`Ann "A", Smith`)
+	row.AddCell().SetString("x")
+	row.AddCell().SetDateTimeWithFormat(time.Date(2015, 1, 2, 0, 0, 0, 0, time.UTC), "d-mmm-yy")
+	row.AddCell().SetFloatWithFormat(1234.5, "0.00")
+	row.AddCell().SetBool(true)
+	row = sheet.AddRow()
+	row.Hidden = true
+	row.AddCell().SetString("hidden")
+	row = sheet.AddRow()
+	row.AddCell().SetString("Merged")
+	row.AddCell()
+	row.AddCell()
+	sheet.Cols[1].Hidden = true
+	sheet.unmodelled = &xlsxWorksheet{MergeCells: &xlsxRawXML{InnerXML: `<mergeCell ref="A4:C4"/>`}}
+	return sheet
+}
+
+func (s *CSVSuite) TestWriteCSV(c *C) {
+	sheet := makeExportSheet()
+	var buf bytes.Buffer
+	c.Assert(sheet.WriteCSV(&buf, nil), IsNil)
+	c.Assert(buf.String(), Equals, "Name,Secret,Joined,Total,Member\n"+
+		`"Ann ""A"", Smith",x,2-Jan-15,1234.50,TRUE`+"\n"+
+		"hidden,,,,\n"+
+		"Merged,,,,\n")
+
+	buf.Reset()
+	c.Assert(sheet.WriteCSV(&buf, &CSVWriteOptions{
+		Delimiter:         '\t',
+		Raw:               true,
+		DateLayout:        "2006-01-02",
+		SkipHiddenRows:    true,
+		SkipHiddenCols:    true,
+		FillMerged:        true,
+		TrimTrailingEmpty: true,
+		UseCRLF:           true}), IsNil)
+	c.Assert(buf.String(), Equals, "Name\tJoined\tTotal\tMember\r\n"+
+		`"Ann ""A"", Smith"`+"\t2015-01-02\t1234.50\tTRUE\r\n"+
+		"Merged\tMerged\r\n")
+}
+
+func (s *CSVSuite) TestWriteCSVQuotingAndRange(c *C) {
+	sheet := makeExportSheet()
+	var buf bytes.Buffer
+	c.Assert(sheet.WriteCSV(&buf, &CSVWriteOptions{Quoting: CSVQuoteNonNumeric, Range: "C1:E2"}), IsNil)
+	c.Assert(buf.String(), Equals, `"Joined","Total","Member"`+"\n"+`"2-Jan-15",1234.50,"TRUE"`+"\n")
+
+	buf.Reset()
+	c.Assert(sheet.WriteCSV(&buf, &CSVWriteOptions{Quoting: CSVQuoteAll, Range: "Export!D3:E3"}), IsNil)
+	c.Assert(buf.String(), Equals, `"",""`+"\n")
+
+	buf.Reset()
+	c.Assert(sheet.WriteCSV(&buf, &CSVWriteOptions{Quoting: CSVQuoteNever, Range: "A2"}), IsNil)
+	c.Assert(buf.String(), Equals, `Ann "A", Smith`+"\n")
+
+	c.Assert(sheet.WriteCSV(&buf, &CSVWriteOptions{Range: "Other!A1:B2"}), ErrorMatches, `invalid range "Other!A1:B2"`)
+	c.Assert(sheet.WriteCSV(&buf, &CSVWriteOptions{Range: "Export!$A$0:$A$3"}), ErrorMatches, `invalid range "Export!\$A\$0:\$A\$3"`)
+	c.Assert(sheet.WriteCSV(&buf, &CSVWriteOptions{Delimiter: '\n'}), ErrorMatches, `invalid CSV delimiter '\\n'`)
+}
+
+// What WriteCSV writes, ReadCSV reads back.
+func (s *CSVSuite) TestWriteCSVRoundTrip(c *C) {
+	sheet := NewFile().AddSheet("Sheet1")
+	csv := "Name,Note\n\"Smith, Ann\",\"line one\nline \"\"two\"\"\"\n"
+	c.Assert(sheet.ReadCSV(strings.NewReader(csv), nil), IsNil)
+	var buf bytes.Buffer
+	c.Assert(sheet.WriteCSV(&buf, nil), IsNil)
+	c.Assert(buf.String(), Equals, csv)
+}
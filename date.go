@@ -96,3 +96,18 @@ func TimeFromExcelTime(excelTime float64, date1904 bool) time.Time {
 	durationPart := time.Duration(dayNanoSeconds * floatPart)
 	return date.Add(durationDays).Add(durationPart)
 }
+
+// Convert a time.Time to the excelTime representation (a floating
+// point number of days) that TimeFromExcelTime converts back.  The
+// time is taken as it is on the clock, whatever its time zone.
+func TimeToExcelTime(t time.Time, date1904 bool) float64 {
+	var epoch time.Time
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	} else {
+		epoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	}
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	seconds := t.Unix() - epoch.Unix()
+	return (float64(seconds) + float64(t.Nanosecond())/1e9) / (24 * 60 * 60)
+}
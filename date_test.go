@@ -69,3 +69,13 @@ func (d *DateSuite) TestTimeFromExcelTimeWith1904Offest(c *C) {
 	c.Assert(date1904Offset, Equals, time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC))
 
 }
+
+func (d *DateSuite) TestTimeToExcelTime(c *C) {
+	c.Assert(TimeToExcelTime(time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC), false), Equals, 41275.0)
+	c.Assert(TimeToExcelTime(time.Date(2003, 11, 22, 18, 0, 0, 0, time.UTC), false), Equals, 37947.75)
+	c.Assert(TimeToExcelTime(time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC), true), Equals, 39813.0)
+	// The time on the clock is what counts.
+	c.Assert(TimeToExcelTime(time.Date(2003, 11, 22, 18, 0, 0, 0, time.FixedZone("EST", -5*60*60)), false), Equals, 37947.75)
+	date := TimeFromExcelTime(TimeToExcelTime(time.Date(2016, 2, 29, 9, 34, 0, 0, time.UTC), false), false)
+	c.Assert(date.Round(time.Second), Equals, time.Date(2016, 2, 29, 9, 34, 0, 0, time.UTC))
+}
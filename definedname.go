@@ -0,0 +1,240 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefinedName is a name given to a formula, most often a reference to
+// a range of cells, so that it can be used in its place.  A name
+// scoped to a Sheet is only visible from that Sheet, and takes
+// precedence there over a workbook-wide name of the same name.
+type DefinedName struct {
+	Name    string
+	Formula string // For example "Sheet1!$A$1:$B$10".
+	Sheet   *Sheet // The Sheet the name is scoped to, or nil for the workbook.
+	Comment string
+	Hidden  bool
+
+	// attrs holds the attributes, read with the name, that aren't
+	// modelled above, so that they are written back out.
+	attrs []xml.Attr
+}
+
+// cellLikeName matches names that would be confused with a cell
+// reference, in either A1 or R1C1 style.
+var cellLikeName = regexp.MustCompile(`(?i)^([a-z]{1,3}[0-9]+|r[0-9]*c[0-9]*|r|c)$`)
+
+// validDefinedName returns an error if name can't be used as the name
+// of a DefinedName.
+func validDefinedName(name string) error {
+	if name == "" {
+		return fmt.Errorf("a defined name can't be empty")
+	}
+	if len(name) > 255 {
+		return fmt.Errorf("defined name %q is longer than 255 characters", name)
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r), r == '_', r == '\\':
+		case i > 0 && (unicode.IsDigit(r) || r == '.' || r == '?'):
+		default:
+			return fmt.Errorf("defined name %q contains the invalid character %q", name, r)
+		}
+	}
+	if cellLikeName.MatchString(name) {
+		return fmt.Errorf("defined name %q looks like a cell reference", name)
+	}
+	return nil
+}
+
+// DefinedNames returns the names defined in the File, both those
+// scoped to the workbook and those scoped to one of its Sheets.
+func (f *File) DefinedNames() []*DefinedName {
+	names := make([]*DefinedName, len(f.definedNames))
+	copy(names, f.definedNames)
+	return names
+}
+
+// AddDefinedName adds a name to the File.  An error is returned if the
+// name isn't valid, is scoped to a Sheet that isn't part of the File,
+// or is already defined in the same scope.
+func (f *File) AddDefinedName(name *DefinedName) error {
+	err := validDefinedName(name.Name)
+	if err != nil {
+		return err
+	}
+	if name.Sheet != nil && f.sheetIndex(name.Sheet) < 0 {
+		return fmt.Errorf("defined name %q is scoped to sheet %q, which isn't part of the file", name.Name, name.Sheet.Name)
+	}
+	if f.findDefinedName(name.Name, name.Sheet) >= 0 {
+		return fmt.Errorf("defined name %q already exists", name.Name)
+	}
+	f.definedNames = append(f.definedNames, name)
+	return nil
+}
+
+// RemoveDefinedName removes the name from the given scope, which is
+// the workbook if sheet is nil.  An error is returned if there is no
+// such name.
+func (f *File) RemoveDefinedName(name string, sheet *Sheet) error {
+	i := f.findDefinedName(name, sheet)
+	if i < 0 {
+		return fmt.Errorf("defined name %q doesn't exist", name)
+	}
+	f.definedNames = append(f.definedNames[:i], f.definedNames[i+1:]...)
+	return nil
+}
+
+// ResolveDefinedName looks up a name, as seen from the given Sheet,
+// and returns the Sheet and the range of cells, such as "A1:B10", it
+// refers to.  With a nil sheet only workbook-wide names are found.
+// An error is returned if there is no such name, or if its formula is
+// anything other than a reference to a single range of cells.
+func (f *File) ResolveDefinedName(name string, sheet *Sheet) (*Sheet, string, error) {
+	i := -1
+	if sheet != nil {
+		i = f.findDefinedName(name, sheet)
+	}
+	if i < 0 {
+		i = f.findDefinedName(name, nil)
+	}
+	if i < 0 {
+		return nil, "", fmt.Errorf("defined name %q doesn't exist", name)
+	}
+	sheetName, ref, err := splitSheetRef(f.definedNames[i].Formula)
+	if err != nil {
+		return nil, "", fmt.Errorf("defined name %q: %s", name, err)
+	}
+	target, ok := f.Sheet[sheetName]
+	if !ok {
+		return nil, "", fmt.Errorf("defined name %q refers to sheet %q, which doesn't exist", name, sheetName)
+	}
+	return target, strings.Replace(ref, "$", "", -1), nil
+}
+
+// findDefinedName returns the index of the name, in the given scope,
+// within f.definedNames, or -1.  Names are case insensitive.
+func (f *File) findDefinedName(name string, sheet *Sheet) int {
+	for i, dn := range f.definedNames {
+		if dn.Sheet == sheet && strings.EqualFold(dn.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sheetIndex returns the position of sheet within f.Sheets, or -1.
+func (f *File) sheetIndex(sheet *Sheet) int {
+	for i, s := range f.Sheets {
+		if s == sheet {
+			return i
+		}
+	}
+	return -1
+}
+
+// readDefinedNames sets up the defined names of a File, that has been
+// read, from its workbook and its sheets, in workbook order.
+func (f *File) readDefinedNames(names []xlsxDefinedName, sheets []*Sheet) {
+	for _, xName := range names {
+		name := &DefinedName{
+			Name:    xName.Name,
+			Formula: xName.Data,
+			Comment: xName.Comment,
+			Hidden:  xName.Hidden,
+			attrs:   xName.Attrs}
+		if xName.LocalSheetID != "" {
+			index, err := strconv.Atoi(xName.LocalSheetID)
+			if err != nil || index < 0 || index >= len(sheets) {
+				continue
+			}
+			name.Sheet = sheets[index]
+		}
+		f.definedNames = append(f.definedNames, name)
+	}
+}
+
+// makeXLSXDefinedNames returns the defined names of the File as they
+// are written to the workbook.  Names scoped to a Sheet that has since
+// been removed from the File are dropped.
+func (f *File) makeXLSXDefinedNames() xlsxDefinedNames {
+	xNames := xlsxDefinedNames{}
+	for _, name := range f.definedNames {
+		xName := xlsxDefinedName{
+			Name:    name.Name,
+			Data:    name.Formula,
+			Comment: name.Comment,
+			Hidden:  name.Hidden,
+			Attrs:   name.attrs}
+		if name.Sheet != nil {
+			index := f.sheetIndex(name.Sheet)
+			if index < 0 {
+				continue
+			}
+			xName.LocalSheetID = strconv.Itoa(index)
+		}
+		xNames.DefinedName = append(xNames.DefinedName, xName)
+	}
+	return xNames
+}
+
+// quoteSheetName returns the name of a sheet as it has to be written
+// in a reference, quoted if it contains anything other than letters,
+// digits, underscores and periods.
+func quoteSheetName(name string) string {
+	plain := name != ""
+	for i, r := range name {
+		if !(unicode.IsLetter(r) || r == '_' || (i > 0 && (unicode.IsDigit(r) || r == '.'))) {
+			plain = false
+			break
+		}
+	}
+	if plain && !cellLikeName.MatchString(name) {
+		return name
+	}
+	return "'" + strings.Replace(name, "'", "''", -1) + "'"
+}
+
+// splitSheetRef splits a reference such as "'My Sheet'!$A$1:$B$2"
+// into the name of the sheet and the reference within it.  It returns
+// an error if the reference isn't to a single range on a named sheet.
+func splitSheetRef(formula string) (sheetName, ref string, err error) {
+	formula = strings.TrimPrefix(formula, "=")
+	if strings.HasPrefix(formula, "'") {
+		i := 1
+		for {
+			j := strings.Index(formula[i:], "'")
+			if j < 0 {
+				return "", "", fmt.Errorf("unterminated sheet name in %q", formula)
+			}
+			i += j + 1
+			if i < len(formula) && formula[i] == '\'' {
+				i++
+				continue
+			}
+			break
+		}
+		sheetName = strings.Replace(formula[1:i-1], "''", "'", -1)
+		ref = formula[i:]
+	} else {
+		i := strings.Index(formula, "!")
+		if i < 0 {
+			return "", "", fmt.Errorf("%q isn't a reference to a range on a sheet", formula)
+		}
+		sheetName = formula[:i]
+		ref = formula[i:]
+	}
+	if !strings.HasPrefix(ref, "!") {
+		return "", "", fmt.Errorf("%q isn't a reference to a range on a sheet", formula)
+	}
+	ref = ref[1:]
+	if ref == "" || strings.ContainsAny(ref, ",!() ") || strings.Contains(ref, "#REF") {
+		return "", "", fmt.Errorf("%q isn't a reference to a single range", formula)
+	}
+	return sheetName, ref, nil
+}
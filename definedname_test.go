@@ -0,0 +1,119 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type DefinedNameSuite struct{}
+
+var _ = Suite(&DefinedNameSuite{})
+
+func (s *DefinedNameSuite) TestAddAndRemoveDefinedName(c *C) {
+	f := NewFile()
+	sheet1 := f.AddSheet("Sheet1")
+	sheet2 := f.AddSheet("Sheet2")
+
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "Total", Formula: "Sheet1!$B$10"}), IsNil)
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "Total", Formula: "Sheet2!$C$5", Sheet: sheet2}), IsNil)
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "total", Formula: "Sheet1!$B$11"}), ErrorMatches, `defined name "total" already exists`)
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "A1", Formula: "Sheet1!$A$1"}), ErrorMatches, `.*looks like a cell reference`)
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "My Name", Formula: "Sheet1!$A$1"}), ErrorMatches, `.*invalid character ' '`)
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "Other", Formula: "Sheet1!$A$1", Sheet: &Sheet{Name: "Elsewhere"}}), ErrorMatches, `.*isn't part of the file`)
+	c.Assert(f.DefinedNames(), HasLen, 2)
+
+	c.Assert(f.RemoveDefinedName("Total", sheet1), ErrorMatches, `defined name "Total" doesn't exist`)
+	c.Assert(f.RemoveDefinedName("TOTAL", nil), IsNil)
+	names := f.DefinedNames()
+	c.Assert(names, HasLen, 1)
+	c.Assert(names[0].Sheet, Equals, sheet2)
+}
+
+// Sheet scoped names take precedence, from their sheet, over
+// workbook-wide ones.
+func (s *DefinedNameSuite) TestResolveDefinedName(c *C) {
+	f := NewFile()
+	sheet1 := f.AddSheet("Sheet1")
+	sheet2 := f.AddSheet("O'Brien's data")
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "Data", Formula: "Sheet1!$A$1:$B$10"}), IsNil)
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "Data", Formula: "'O''Brien''s data'!$C:$C", Sheet: sheet2}), IsNil)
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "Titles", Formula: "Sheet1!$A:$A,Sheet1!$1:$1"}), IsNil)
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "Rate", Formula: "0.2"}), IsNil)
+
+	sheet, ref, err := f.ResolveDefinedName("data", nil)
+	c.Assert(err, IsNil)
+	c.Assert(sheet, Equals, sheet1)
+	c.Assert(ref, Equals, "A1:B10")
+
+	sheet, ref, err = f.ResolveDefinedName("Data", sheet1)
+	c.Assert(err, IsNil)
+	c.Assert(sheet, Equals, sheet1)
+
+	sheet, ref, err = f.ResolveDefinedName("Data", sheet2)
+	c.Assert(err, IsNil)
+	c.Assert(sheet, Equals, sheet2)
+	c.Assert(ref, Equals, "C:C")
+
+	_, _, err = f.ResolveDefinedName("Titles", nil)
+	c.Assert(err, ErrorMatches, `.*isn't a reference to a single range`)
+	_, _, err = f.ResolveDefinedName("Rate", nil)
+	c.Assert(err, ErrorMatches, `.*isn't a reference to a range on a sheet`)
+	_, _, err = f.ResolveDefinedName("Missing", nil)
+	c.Assert(err, ErrorMatches, `defined name "Missing" doesn't exist`)
+}
+
+func (s *DefinedNameSuite) TestQuoteSheetName(c *C) {
+	c.Assert(quoteSheetName("Sheet1"), Equals, "Sheet1")
+	c.Assert(quoteSheetName("My Sheet"), Equals, "'My Sheet'")
+	c.Assert(quoteSheetName("O'Brien"), Equals, "'O''Brien'")
+	c.Assert(quoteSheetName("AB12"), Equals, "'AB12'")
+	c.Assert(quoteSheetName("2015"), Equals, "'2015'")
+}
+
+// Defined names are written to the workbook and read back, with
+// their scope resolved to a Sheet.
+func (s *DefinedNameSuite) TestDefinedNamesRoundTrip(c *C) {
+	f := NewFile()
+	f.AddSheet("Sheet1")
+	sheet2 := f.AddSheet("Sheet2")
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "Total", Formula: "Sheet1!$B$10", Comment: "The total"}), IsNil)
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "Local", Formula: "Sheet2!$A$1", Sheet: sheet2, Hidden: true}), IsNil)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/workbook.xml"], `<definedNames><definedName name="Total" comment="The total">Sheet1!$B$10</definedName><definedName name="Local" localSheetId="1" hidden="true">Sheet2!$A$1</definedName></definedNames>`), Equals, true)
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	names := f2.DefinedNames()
+	c.Assert(names, HasLen, 2)
+	c.Assert(*names[0], DeepEquals, DefinedName{Name: "Total", Formula: "Sheet1!$B$10", Comment: "The total"})
+	c.Assert(names[1].Sheet, Equals, f2.Sheet["Sheet2"])
+	c.Assert(names[1].Hidden, Equals, true)
+}
+
+// Attributes of a defined name that aren't modelled are written back
+// out as they were read.
+func (s *DefinedNameSuite) TestDefinedNameKeepsUnmodelledAttrs(c *C) {
+	workbook := new(xlsxWorkbook)
+	c.Assert(xml.Unmarshal([]byte(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><definedNames>`+
+		`<definedName name="Macro1" function="1" vbProcedure="1" xlm="1" functionGroupId="14" shortcutKey="m" description="Runs it">Sheet1!$A$1</definedName>`+
+		`</definedNames></workbook>`), workbook), IsNil)
+	workbook.flattenRawAttrs()
+	f := NewFile()
+	f.readDefinedNames(workbook.DefinedNames.DefinedName, nil)
+	c.Assert(f.DefinedNames(), HasLen, 1)
+	c.Assert(f.DefinedNames()[0].Name, Equals, "Macro1")
+
+	output, err := xml.Marshal(f.makeXLSXDefinedNames())
+	c.Assert(err, IsNil)
+	c.Assert(string(output), Equals, `<xlsxDefinedNames><definedName name="Macro1" function="1" vbProcedure="1" xlm="1" functionGroupId="14" shortcutKey="m" description="Runs it">Sheet1!$A$1</definedName></xlsxDefinedNames>`)
+}
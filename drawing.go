@@ -0,0 +1,436 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	drawingRelType     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing"
+	imageRelType       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+	drawingContentType = "application/vnd.openxmlformats-officedocument.drawing+xml"
+	relationshipsNS    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+
+	// drawingNamespaces declares the prefixes that the DrawingML we
+	// write uses.
+	drawingNamespaces = `xmlns:xdr="http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="` + relationshipsNS + `"`
+
+	// DrawingML measures positions and sizes in English Metric
+	// Units, of which there are 9525 to a pixel.
+	emuPerPixel = 9525
+
+	// defaultRowHeight is the height, in points, of the rows of the
+	// worksheets we write, as set in newXlsxWorksheet.
+	defaultRowHeight = 12.85
+)
+
+// drawingAnchor is where on a Sheet something in its drawing, such as
+// a Picture, is placed.  Positions and sizes are in pixels.
+type drawingAnchor struct {
+	kind                                     string // "oneCell", "twoCell" or "absolute"
+	fromCol, fromColOff, fromRow, fromRowOff int
+	toCol, toColOff, toRow, toRowOff         int
+	x, y, width, height                      int
+}
+
+// colWidthPixels returns the width, in pixels, of the column with the
+// given zero based index, as Excel displays it using the default font.
+func (s *Sheet) colWidthPixels(col int) int {
+	width := ColWidth
+	for _, c := range s.Cols {
+		if c != nil && c.Min <= col+1 && col+1 <= c.Max && c.Width != 0 {
+			width = c.Width
+		}
+	}
+	// The default font's digits are 7 pixels wide, and the
+	// width includes padding of Truncate(128/7) 256ths of one.
+	return int((256*width + 18) / 256 * 7)
+}
+
+// rowHeightPixels returns the height, in pixels, of the row with the
+// given zero based index at 96 dpi.  Rows without a height of their
+// own are as high as the default of the Sheet, which is the one it
+// was read with, if any.
+func (s *Sheet) rowHeightPixels(row int) int {
+	height := defaultRowHeight
+	if s.unmodelled != nil && s.unmodelled.SheetFormatPr.DefaultRowHeight > 0 {
+		height = s.unmodelled.SheetFormatPr.DefaultRowHeight
+	}
+	if row < len(s.Rows) && s.Rows[row] != nil && s.Rows[row].Height > 0 {
+		height = s.Rows[row].Height
+	}
+	return int(height * 96 / 72)
+}
+
+// makeDrawingAnchor returns the anchor for something of the given
+// size whose top left corner is offset by offsetX and offsetY pixels
+// from the top left of the cell at col and row.  Offsets that take it
+// beyond the cell are carried into the cells that follow.
+func (s *Sheet) makeDrawingAnchor(kind string, col, row, offsetX, offsetY, width, height int) drawingAnchor {
+	a := drawingAnchor{kind: kind, width: width, height: height}
+	a.fromCol, a.fromColOff = col, offsetX
+	for a.fromColOff >= s.colWidthPixels(a.fromCol) {
+		a.fromColOff -= s.colWidthPixels(a.fromCol)
+		a.fromCol++
+	}
+	a.fromRow, a.fromRowOff = row, offsetY
+	for a.fromRowOff >= s.rowHeightPixels(a.fromRow) {
+		a.fromRowOff -= s.rowHeightPixels(a.fromRow)
+		a.fromRow++
+	}
+	for i := 0; i < a.fromCol; i++ {
+		a.x += s.colWidthPixels(i)
+	}
+	a.x += a.fromColOff
+	for i := 0; i < a.fromRow; i++ {
+		a.y += s.rowHeightPixels(i)
+	}
+	a.y += a.fromRowOff
+	a.toCol, a.toColOff = a.fromCol, a.fromColOff+width
+	for a.toColOff > s.colWidthPixels(a.toCol) {
+		a.toColOff -= s.colWidthPixels(a.toCol)
+		a.toCol++
+	}
+	a.toRow, a.toRowOff = a.fromRow, a.fromRowOff+height
+	for a.toRowOff > s.rowHeightPixels(a.toRow) {
+		a.toRowOff -= s.rowHeightPixels(a.toRow)
+		a.toRow++
+	}
+	return a
+}
+
+// marshal returns the anchor as DrawingML, wrapped around content,
+// which is the xdr:pic or xdr:graphicFrame that it places.
+func (a drawingAnchor) marshal(content string) string {
+	marker := func(name string, col, colOff, row, rowOff int) string {
+		return fmt.Sprintf(`<xdr:%s><xdr:col>%d</xdr:col><xdr:colOff>%d</xdr:colOff><xdr:row>%d</xdr:row><xdr:rowOff>%d</xdr:rowOff></xdr:%s>`,
+			name, col, colOff*emuPerPixel, row, rowOff*emuPerPixel, name)
+	}
+	ext := fmt.Sprintf(`<xdr:ext cx="%d" cy="%d"/>`, a.width*emuPerPixel, a.height*emuPerPixel)
+	switch a.kind {
+	case "twoCell":
+		return `<xdr:twoCellAnchor>` +
+			marker("from", a.fromCol, a.fromColOff, a.fromRow, a.fromRowOff) +
+			marker("to", a.toCol, a.toColOff, a.toRow, a.toRowOff) +
+			content + `<xdr:clientData/></xdr:twoCellAnchor>`
+	case "absolute":
+		return fmt.Sprintf(`<xdr:absoluteAnchor><xdr:pos x="%d" y="%d"/>`, a.x*emuPerPixel, a.y*emuPerPixel) +
+			ext + content + `<xdr:clientData/></xdr:absoluteAnchor>`
+	}
+	return `<xdr:oneCellAnchor>` +
+		marker("from", a.fromCol, a.fromColOff, a.fromRow, a.fromRowOff) +
+		ext + content + `<xdr:clientData/></xdr:oneCellAnchor>`
+}
+
+// hasRetainedDrawing reports whether the Sheet was read from a file
+// with a drawing, which is written back out as it was read, along with
+// anything that has since been drawn on the Sheet.
+func (s *Sheet) hasRetainedDrawing() bool {
+	return s.unmodelled != nil && s.unmodelled.Drawing != nil
+}
+
+// retainedDrawingPart returns the name of the part that holds the
+// drawing the Sheet was read with.
+func (s *Sheet) retainedDrawingPart() (string, error) {
+	rId := ""
+	for _, attr := range s.unmodelled.Drawing.Attrs {
+		if attr.Name.Local == "id" || strings.HasSuffix(attr.Name.Local, ":id") {
+			rId = attr.Value
+		}
+	}
+	return findRelTarget(s.rels, "xl/worksheets/sheet.xml", rId)
+}
+
+// drawingObjectId matches the ids of the objects in a drawing.
+var drawingObjectId = regexp.MustCompile(`<(?:\w+:)?cNvPr\b[^>]*?\sid="(\d+)"`)
+
+// drawingEnd matches the end of a drawing, before which more anchors
+// are added.
+var drawingEnd = regexp.MustCompile(`</(?:\w+:)?wsDr>\s*$`)
+
+// partNamer picks names for the parts, such as drawings and media,
+// that are numbered within a package, skipping those already taken by
+// parts retained from the file that was read.
+type partNamer struct {
+	taken  map[string]string
+	counts map[string]int
+}
+
+func newPartNamer(taken map[string]string) *partNamer {
+	return &partNamer{taken: taken, counts: make(map[string]int)}
+}
+
+// next returns the next free name made by formatting a number with
+// format, such as "xl/drawings/drawing%d.xml".
+func (p *partNamer) next(format string) string {
+	for {
+		p.counts[format]++
+		name := fmt.Sprintf(format, p.counts[format])
+		if _, ok := p.taken[name]; !ok {
+			return name
+		}
+	}
+}
+
+// makeDrawingParts adds the drawing of the Sheet, and the parts it
+// refers to, to parts, and points worksheet at it.  It returns the
+// relationships of the worksheet, which include the drawing.  Nothing
+// is done, and the Sheet's own relationships are returned, when there
+// is nothing to draw.  A drawing retained from a file, which parts
+// already holds, has anything drawn on the Sheet since added to it.
+func (s *Sheet) makeDrawingParts(worksheet *xlsxWorksheet, names *partNamer, parts map[string]string, types *xlsxTypes) (string, error) {
+	pictures := s.pictures
+	retained := s.hasRetainedDrawing()
+	if retained {
+		pictures = pictures[s.retainedPictures:]
+	}
+	if len(pictures)+len(s.charts) == 0 {
+		return s.rels, nil
+	}
+	drawingPart := ""
+	drawingRels := xlsxWorkbookRels{}
+	id := 1
+	if retained {
+		var err error
+		drawingPart, err = s.retainedDrawingPart()
+		if err != nil {
+			return "", err
+		}
+		if _, ok := parts[drawingPart]; !ok {
+			return "", fmt.Errorf("the drawing of sheet %q is missing", s.Name)
+		}
+		if rels := parts[relsPartName(drawingPart)]; rels != "" {
+			if err := xml.Unmarshal([]byte(rels), &drawingRels); err != nil {
+				return "", err
+			}
+		}
+		for _, match := range drawingObjectId.FindAllStringSubmatch(parts[drawingPart], -1) {
+			if n, err := strconv.Atoi(match[1]); err == nil && n > id {
+				id = n
+			}
+		}
+	} else {
+		drawingPart = names.next("xl/drawings/drawing%d.xml")
+	}
+	usedRelIds := make(map[string]bool, len(drawingRels.Relationships))
+	for _, rel := range drawingRels.Relationships {
+		usedRelIds[rel.Id] = true
+	}
+	relCount := 0
+	addDrawingRel := func(relType, target string) string {
+		rId := nextFreeRelId(&relCount, usedRelIds)
+		drawingRels.Relationships = append(drawingRels.Relationships, xlsxWorkbookRelation{
+			Id:     rId,
+			Target: relTarget(drawingPart, target),
+			Type:   relType})
+		return rId
+	}
+	var anchors []string
+	for _, picture := range pictures {
+		mediaPart := names.next("xl/media/image%d." + picture.Format)
+		parts[mediaPart] = string(picture.Data)
+		if !types.hasDefault(picture.Format) {
+			types.Defaults = append(types.Defaults, xlsxDefault{
+				Extension:   picture.Format,
+				ContentType: pictureContentTypes[picture.Format]})
+		}
+		rId := addDrawingRel(imageRelType, mediaPart)
+		anchor, err := picture.makeDrawingAnchor(s)
+		if err != nil {
+			return "", err
+		}
+		id++
+		anchors = append(anchors, anchor.marshal(picture.marshal(id, rId, anchor)))
+	}
+	for _, chart := range s.charts {
+		chartPart := names.next("xl/charts/chart%d.xml")
+		parts[chartPart] = xml.Header + chart.chart.marshal(s.File)
+		types.Overrides = append(types.Overrides, xlsxOverride{
+			PartName:    "/" + chartPart,
+			ContentType: chartContentType})
+		rId := addDrawingRel(chartRelType, chartPart)
+		anchor, err := chart.makeDrawingAnchor(s)
+		if err != nil {
+			return "", err
+		}
+		id++
+		anchors = append(anchors, anchor.marshal(chart.marshalGraphicFrame(id, rId, anchor)))
+	}
+	body, err := xml.Marshal(drawingRels)
+	if err != nil {
+		return "", err
+	}
+	parts[relsPartName(drawingPart)] = xml.Header + string(body)
+
+	if retained {
+		// The prefixes the retained drawing declares may not be
+		// ours, so each anchor declares its own.
+		drawing := parts[drawingPart]
+		end := drawingEnd.FindStringIndex(drawing)
+		if end == nil {
+			return "", fmt.Errorf("the drawing of sheet %q isn't complete", s.Name)
+		}
+		content := ""
+		for _, anchor := range anchors {
+			open := strings.Index(anchor, ">")
+			content += anchor[:open] + " " + drawingNamespaces + anchor[open:]
+		}
+		parts[drawingPart] = drawing[:end[0]] + content + drawing[end[0]:]
+		return s.rels, nil
+	}
+
+	parts[drawingPart] = xml.Header + `<xdr:wsDr ` + drawingNamespaces + `>` + strings.Join(anchors, "") + `</xdr:wsDr>`
+	types.Overrides = append(types.Overrides, xlsxOverride{
+		PartName:    "/" + drawingPart,
+		ContentType: drawingContentType})
+
+	rels, rId, err := addRel(s.rels, drawingRelType, relTarget("xl/worksheets/sheet.xml", drawingPart))
+	if err != nil {
+		return "", err
+	}
+	worksheet.Drawing = &xlsxRawXML{
+		XMLName: xml.Name{Local: "drawing"},
+		Attrs: []xml.Attr{
+			{Name: xml.Name{Local: "xmlns:r"}, Value: relationshipsNS},
+			{Name: xml.Name{Local: "r:id"}, Value: rId}}}
+	return rels, nil
+}
+
+// readDrawing reads the pictures in the drawing of a Sheet that has
+// been read from a file, from the parts retained from that file.
+func (s *Sheet) readDrawing(retainedParts map[string]string) error {
+	if !s.hasRetainedDrawing() || s.rels == "" {
+		return nil
+	}
+	drawingPart, err := s.retainedDrawingPart()
+	if err != nil {
+		return err
+	}
+	drawing, ok := retainedParts[drawingPart]
+	if !ok {
+		return nil
+	}
+	wsDr := new(xlsxWsDr)
+	if err := xml.Unmarshal([]byte(drawing), wsDr); err != nil {
+		return err
+	}
+	drawingRels := new(xlsxWorkbookRels)
+	if rels, ok := retainedParts[relsPartName(drawingPart)]; ok {
+		if err := xml.Unmarshal([]byte(rels), drawingRels); err != nil {
+			return err
+		}
+	}
+	for _, anchor := range wsDr.Anchors {
+		// Pictures that are linked, rather than embedded, and
+		// those whose relationships we can't follow, are left in
+		// the drawing as they are.
+		if anchor.Pic == nil || anchor.Pic.Blip.Embed == "" {
+			continue
+		}
+		mediaPart := ""
+		for _, rel := range drawingRels.Relationships {
+			if rel.Id == anchor.Pic.Blip.Embed && rel.TargetMode != "External" {
+				mediaPart = resolveRelTarget(drawingPart, rel.Target)
+			}
+		}
+		media, ok := retainedParts[mediaPart]
+		if !ok {
+			continue
+		}
+		s.pictures = append(s.pictures, readPicture(anchor, []byte(media), path.Ext(mediaPart)))
+	}
+	s.retainedPictures = len(s.pictures)
+	return nil
+}
+
+// relTarget returns the target, relative to the part source, by which
+// a relationship refers to the part target.
+func relTarget(source, target string) string {
+	dir := path.Dir(source)
+	up := ""
+	for !strings.HasPrefix(target, dir+"/") && dir != "." {
+		dir = path.Dir(dir)
+		up += "../"
+	}
+	if dir == "." {
+		return up + target
+	}
+	return up + strings.TrimPrefix(target, dir+"/")
+}
+
+// resolveRelTarget returns the name of the part that a relationship
+// of the part source refers to by target.
+func resolveRelTarget(source, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return target[1:]
+	}
+	return path.Join(path.Dir(source), target)
+}
+
+// relsPartName returns the name of the part that holds the
+// relationships of the part with the given name.
+func relsPartName(part string) string {
+	return path.Join(path.Dir(part), "_rels", path.Base(part)+".rels")
+}
+
+// findRelTarget returns the name of the part referred to by the
+// relationship, of the part source, with the given id.
+func findRelTarget(rels, source, id string) (string, error) {
+	xRels := new(xlsxWorkbookRels)
+	if err := xml.Unmarshal([]byte(rels), xRels); err != nil {
+		return "", err
+	}
+	for _, rel := range xRels.Relationships {
+		if rel.Id == id {
+			return resolveRelTarget(source, rel.Target), nil
+		}
+	}
+	return "", fmt.Errorf("relationship %q not found", id)
+}
+
+// findRelTargets returns the names of the parts referred to by the
+// relationships, of the part source, with the given type.
+func findRelTargets(rels, source, relType string) ([]string, error) {
+	xRels := new(xlsxWorkbookRels)
+	if err := xml.Unmarshal([]byte(rels), xRels); err != nil {
+		return nil, err
+	}
+	var targets []string
+	for _, rel := range xRels.Relationships {
+		if rel.Type == relType {
+			targets = append(targets, resolveRelTarget(source, rel.Target))
+		}
+	}
+	return targets, nil
+}
+
+// addRel adds a relationship, of the given type and target, to the
+// relationships in rels, which may be empty.  It returns the
+// relationships, and the id given to the new one.
+func addRel(rels, relType, target string) (string, string, error) {
+	xRels := new(xlsxWorkbookRels)
+	if rels != "" {
+		if err := xml.Unmarshal([]byte(rels), xRels); err != nil {
+			return "", "", err
+		}
+	}
+	used := make(map[string]bool, len(xRels.Relationships))
+	for _, rel := range xRels.Relationships {
+		used[rel.Id] = true
+	}
+	n := 0
+	id := nextFreeRelId(&n, used)
+	xRels.Relationships = append(xRels.Relationships, xlsxWorkbookRelation{
+		Id:     id,
+		Target: target,
+		Type:   relType})
+	body, err := xml.Marshal(xRels)
+	if err != nil {
+		return "", "", err
+	}
+	return xml.Header + string(body), id, nil
+}
@@ -0,0 +1,77 @@
+package xlsx
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type DrawingSuite struct{}
+
+var _ = Suite(&DrawingSuite{})
+
+func (s *DrawingSuite) TestMakeDrawingAnchor(c *C) {
+	sheet := NewFile().AddSheet("Sheet1")
+	c.Assert(sheet.colWidthPixels(0), Equals, 66)
+	c.Assert(sheet.rowHeightPixels(0), Equals, 17)
+	c.Assert(sheet.SetColWidth(1, 1, 20), IsNil)
+	c.Assert(sheet.colWidthPixels(1), Equals, 140)
+
+	// Offsets beyond the cell carry into those that follow.
+	a := sheet.makeDrawingAnchor("twoCell", 0, 1, 70, 20, 150, 10)
+	c.Assert(a.fromCol, Equals, 1)
+	c.Assert(a.fromColOff, Equals, 4)
+	c.Assert(a.fromRow, Equals, 2)
+	c.Assert(a.fromRowOff, Equals, 3)
+	c.Assert(a.x, Equals, 70)
+	c.Assert(a.y, Equals, 37)
+	c.Assert(a.toCol, Equals, 2)
+	c.Assert(a.toColOff, Equals, 14)
+	c.Assert(a.toRow, Equals, 2)
+	c.Assert(a.toRowOff, Equals, 13)
+
+	// Rows with a height of their own are as high as that.
+	sheet.AddRow()
+	sheet.AddRow().Height = 30
+	c.Assert(sheet.rowHeightPixels(1), Equals, 40)
+	c.Assert(sheet.rowHeightPixels(0), Equals, 17)
+	a = sheet.makeDrawingAnchor("twoCell", 0, 0, 0, 20, 10, 10)
+	c.Assert(a.fromRow, Equals, 1)
+	c.Assert(a.fromRowOff, Equals, 3)
+	c.Assert(a.toRow, Equals, 1)
+	c.Assert(a.toRowOff, Equals, 13)
+	a = sheet.makeDrawingAnchor("twoCell", 0, 0, 0, 60, 10, 10)
+	c.Assert(a.fromRow, Equals, 2)
+	c.Assert(a.fromRowOff, Equals, 3)
+}
+
+func (s *DrawingSuite) TestRelTargets(c *C) {
+	c.Assert(relTarget("xl/worksheets/sheet1.xml", "xl/drawings/drawing1.xml"), Equals, "../drawings/drawing1.xml")
+	c.Assert(relTarget("xl/workbook.xml", "xl/worksheets/sheet1.xml"), Equals, "worksheets/sheet1.xml")
+	c.Assert(resolveRelTarget("xl/drawings/drawing1.xml", "../media/image1.png"), Equals, "xl/media/image1.png")
+	c.Assert(resolveRelTarget("xl/drawings/drawing1.xml", "/xl/media/image1.png"), Equals, "xl/media/image1.png")
+	c.Assert(relsPartName("xl/drawings/drawing1.xml"), Equals, "xl/drawings/_rels/drawing1.xml.rels")
+}
+
+func (s *DrawingSuite) TestAddRel(c *C) {
+	rels, id, err := addRel(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Target="../comments1.xml" Type="comments"/></Relationships>`, drawingRelType, "../drawings/drawing1.xml")
+	c.Assert(err, IsNil)
+	c.Assert(id, Equals, "rId2")
+	target, err := findRelTarget(rels, "xl/worksheets/sheet1.xml", id)
+	c.Assert(err, IsNil)
+	c.Assert(target, Equals, "xl/drawings/drawing1.xml")
+	_, err = findRelTarget(rels, "xl/worksheets/sheet1.xml", "rId3")
+	c.Assert(err, ErrorMatches, `relationship "rId3" not found`)
+}
+
+// Sheets read with a default row height of their own use it.
+func (s *DrawingSuite) TestRowHeightPixelsUsesReadDefault(c *C) {
+	f, err := OpenFile("./testdocs/macExcelTest.xlsx")
+	c.Assert(err, IsNil)
+	sheet := f.Sheets[0]
+	c.Assert(sheet.rowHeightPixels(5), Equals, 33)
+	a := sheet.makeDrawingAnchor("twoCell", 0, 3, 0, 40, 10, 30)
+	c.Assert(a.fromRow, Equals, 4)
+	c.Assert(a.fromRowOff, Equals, 7)
+	c.Assert(a.y, Equals, 4*33+7)
+	c.Assert(a.toRow, Equals, 5)
+	c.Assert(a.toRowOff, Equals, 4)
+}
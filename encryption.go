@@ -0,0 +1,596 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"unicode/utf16"
+)
+
+// An encrypted workbook is a compound file holding the encrypted zip
+// package, along with a description of how it was encrypted
+// (MS-OFFCRYPTO).  We decrypt both Agile and Standard encryption, and
+// encrypt with Agile encryption, using AES-256 and SHA-512, as Excel
+// does.
+
+const (
+	encryptionSegmentSize = 4096
+	agileSpinCount        = 100000
+	standardSpinCount     = 50000
+)
+
+// The block keys that Agile encryption mixes into its hashes.
+var (
+	agileVerifierInputBlock  = []byte{0xfe, 0xa7, 0xd2, 0x76, 0x3b, 0x4b, 0x9e, 0x79}
+	agileVerifierValueBlock  = []byte{0xd7, 0xaa, 0x0f, 0x6d, 0x30, 0x61, 0x34, 0x4e}
+	agileKeyValueBlock       = []byte{0x14, 0x6e, 0x0b, 0xe7, 0xab, 0xac, 0xd0, 0xd6}
+	agileIntegrityKeyBlock   = []byte{0x5f, 0xb2, 0xad, 0x01, 0x0c, 0xb9, 0xe1, 0xf6}
+	agileIntegrityValueBlock = []byte{0xa0, 0x67, 0x7f, 0x02, 0xb2, 0x2c, 0x84, 0x33}
+)
+
+// OpenFileWithPassword takes the name of an XLSX file that has been
+// encrypted with a password and returns a populated xlsx.File struct
+// for it.
+func OpenFileWithPassword(filename, password string) (*File, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadEncrypted(f, password)
+}
+
+// ReadEncrypted decrypts an XLSX file that has been encrypted with a
+// password, and returns a populated xlsx.File struct for it.
+func ReadEncrypted(r io.ReaderAt, password string) (*File, error) {
+	cfb, err := newCFBReader(r)
+	if err != nil {
+		return nil, err
+	}
+	info, err := cfb.readStream("EncryptionInfo")
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := cfb.readStream("EncryptedPackage")
+	if err != nil {
+		return nil, err
+	}
+	plain, err := decryptPackage(info, pkg, password)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(plain), int64(len(plain)))
+	if err != nil {
+		return nil, err
+	}
+	return ReadZipReader(zr)
+}
+
+// WriteEncrypted writes the File to an io.Writer as an XLSX file that
+// is encrypted with password, which has to be given to open it.
+func (f *File) WriteEncrypted(w io.Writer, password string) error {
+	var buf bytes.Buffer
+	err := f.Write(&buf)
+	if err != nil {
+		return err
+	}
+	info, pkg, err := encryptAgile(buf.Bytes(), password)
+	if err != nil {
+		return err
+	}
+	return writeCFB(w,
+		newCFBStorage("\x06DataSpaces",
+			newCFBStream("Version", makeDataSpaceVersion()),
+			newCFBStream("DataSpaceMap", makeDataSpaceMap()),
+			newCFBStorage("DataSpaceInfo",
+				newCFBStream("StrongEncryptionDataSpace", makeDataSpaceDefinition())),
+			newCFBStorage("TransformInfo",
+				newCFBStorage("StrongEncryptionTransform",
+					newCFBStream("\x06Primary", makeTransformInfo())))),
+		newCFBStream("EncryptionInfo", info),
+		newCFBStream("EncryptedPackage", pkg))
+}
+
+// decryptPackage returns the zip package held, encrypted, in pkg,
+// using whichever encryption info describes.
+func decryptPackage(info, pkg []byte, password string) ([]byte, error) {
+	if len(info) < 8 || len(pkg) < 8 {
+		return nil, fmt.Errorf("encryption info is too short")
+	}
+	major := binary.LittleEndian.Uint16(info)
+	minor := binary.LittleEndian.Uint16(info[2:])
+	switch {
+	case major == 4 && minor == 4:
+		return decryptAgile(info[8:], pkg, password)
+	case (major == 2 || major == 3 || major == 4) && minor == 2:
+		return decryptStandard(info[4:], pkg, password)
+	}
+	return nil, fmt.Errorf("unsupported encryption version %d.%d", major, minor)
+}
+
+// xlsxEncryption directly maps the encryption element in the namespace
+// http://schemas.microsoft.com/office/2006/encryption - currently I
+// have not checked it for completeness - it does as much as I need.
+type xlsxEncryption struct {
+	KeyData       xlsxKeyData        `xml:"keyData"`
+	DataIntegrity *xlsxDataIntegrity `xml:"dataIntegrity"`
+	KeyEncryptors []xlsxEncryptedKey `xml:"keyEncryptors>keyEncryptor>encryptedKey"`
+}
+
+// xlsxDataIntegrity directly maps the dataIntegrity element in the
+// namespace http://schemas.microsoft.com/office/2006/encryption -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxDataIntegrity struct {
+	EncryptedHmacKey   string `xml:"encryptedHmacKey,attr"`
+	EncryptedHmacValue string `xml:"encryptedHmacValue,attr"`
+}
+
+// xlsxKeyData directly maps the keyData element in the namespace
+// http://schemas.microsoft.com/office/2006/encryption - currently I
+// have not checked it for completeness - it does as much as I need.
+type xlsxKeyData struct {
+	SaltSize        int    `xml:"saltSize,attr"`
+	BlockSize       int    `xml:"blockSize,attr"`
+	KeyBits         int    `xml:"keyBits,attr"`
+	HashSize        int    `xml:"hashSize,attr"`
+	CipherAlgorithm string `xml:"cipherAlgorithm,attr"`
+	CipherChaining  string `xml:"cipherChaining,attr"`
+	HashAlgorithm   string `xml:"hashAlgorithm,attr"`
+	SaltValue       string `xml:"saltValue,attr"`
+}
+
+// xlsxEncryptedKey directly maps the encryptedKey element in the
+// namespace
+// http://schemas.microsoft.com/office/2006/keyEncryptor/password -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxEncryptedKey struct {
+	xlsxKeyData
+	SpinCount                  int    `xml:"spinCount,attr"`
+	EncryptedVerifierHashInput string `xml:"encryptedVerifierHashInput,attr"`
+	EncryptedVerifierHashValue string `xml:"encryptedVerifierHashValue,attr"`
+	EncryptedKeyValue          string `xml:"encryptedKeyValue,attr"`
+}
+
+// hashFunc returns the hash function with the name used by Agile
+// encryption.
+func hashFunc(name string) (func() hash.Hash, error) {
+	switch name {
+	case "SHA1", "SHA-1":
+		return sha1.New, nil
+	case "SHA256", "SHA-256":
+		return sha256.New, nil
+	case "SHA384", "SHA-384":
+		return sha512.New384, nil
+	case "SHA512", "SHA-512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("unsupported hash algorithm %q", name)
+}
+
+// decryptAgile returns the zip package held in pkg, given the XML
+// description of its Agile encryption.
+func decryptAgile(info, pkg []byte, password string) ([]byte, error) {
+	var encryption xlsxEncryption
+	err := xml.Unmarshal(info, &encryption)
+	if err != nil {
+		return nil, err
+	}
+	if len(encryption.KeyEncryptors) == 0 {
+		return nil, fmt.Errorf("the file isn't encrypted with a password")
+	}
+	ek := encryption.KeyEncryptors[0]
+	for _, keyData := range []xlsxKeyData{encryption.KeyData, ek.xlsxKeyData} {
+		if keyData.CipherAlgorithm != "AES" || keyData.CipherChaining != "ChainingModeCBC" {
+			return nil, fmt.Errorf("unsupported cipher %s %s", keyData.CipherAlgorithm, keyData.CipherChaining)
+		}
+		if !validAESKeySize(keyData.KeyBits) {
+			return nil, fmt.Errorf("unsupported key size %d", keyData.KeyBits)
+		}
+		if keyData.BlockSize != aes.BlockSize {
+			return nil, fmt.Errorf("unsupported block size %d", keyData.BlockSize)
+		}
+		if keyData.SaltSize < 1 || keyData.SaltSize > 65536 {
+			return nil, fmt.Errorf("unsupported salt size %d", keyData.SaltSize)
+		}
+	}
+	if len(pkg) < 8 {
+		return nil, fmt.Errorf("encrypted package is too short")
+	}
+	newHash, err := hashFunc(ek.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := base64.StdEncoding.DecodeString(ek.SaltValue)
+	if err != nil {
+		return nil, err
+	}
+	iterated := spinPasswordHash(newHash, password, salt, ek.SpinCount)
+	iv := fixSize(salt, ek.BlockSize, 0x36)
+	keyBytes := ek.KeyBits / 8
+	decrypt := func(block []byte, value string) ([]byte, error) {
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		key := fixSize(hashOf(newHash, iterated, block), keyBytes, 0x36)
+		return aesCBC(key, iv, data, false)
+	}
+	verifierInput, err := decrypt(agileVerifierInputBlock, ek.EncryptedVerifierHashInput)
+	if err != nil {
+		return nil, err
+	}
+	verifierHash, err := decrypt(agileVerifierValueBlock, ek.EncryptedVerifierHashValue)
+	if err != nil {
+		return nil, err
+	}
+	expected := hashOf(newHash, fixSize(verifierInput, ek.SaltSize, 0))
+	if len(verifierHash) < len(expected) || !hmac.Equal(verifierHash[:len(expected)], expected) {
+		return nil, fmt.Errorf("incorrect password")
+	}
+	secretKey, err := decrypt(agileKeyValueBlock, ek.EncryptedKeyValue)
+	if err != nil {
+		return nil, err
+	}
+	if len(secretKey) < keyBytes {
+		return nil, fmt.Errorf("encrypted key is too short")
+	}
+	secretKey = secretKey[:keyBytes]
+
+	keyData := encryption.KeyData
+	newHash, err = hashFunc(keyData.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	keySalt, err := base64.StdEncoding.DecodeString(keyData.SaltValue)
+	if err != nil {
+		return nil, err
+	}
+	if encryption.DataIntegrity != nil {
+		err = checkAgileIntegrity(encryption.DataIntegrity, newHash, secretKey, keySalt, pkg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	size := binary.LittleEndian.Uint64(pkg)
+	var plain []byte
+	for i, offset := 0, 8; offset < len(pkg); i, offset = i+1, offset+encryptionSegmentSize {
+		end := offset + encryptionSegmentSize
+		if end > len(pkg) {
+			end = len(pkg)
+		}
+		segment := pkg[offset:end]
+		segment = segment[:len(segment)-len(segment)%aes.BlockSize]
+		segmentIV := fixSize(hashOf(newHash, keySalt, uint32LE(uint32(i))), keyData.BlockSize, 0x36)
+		decrypted, err := aesCBC(secretKey, segmentIV, segment, false)
+		if err != nil {
+			return nil, err
+		}
+		plain = append(plain, decrypted...)
+	}
+	if uint64(len(plain)) < size {
+		return nil, fmt.Errorf("encrypted package is shorter than its size")
+	}
+	return plain[:size], nil
+}
+
+// checkAgileIntegrity returns an error unless the HMAC of the
+// encrypted package pkg is the one that the data integrity of its
+// Agile encryption records, which shows that it hasn't been altered.
+func checkAgileIntegrity(integrity *xlsxDataIntegrity, newHash func() hash.Hash, secretKey, keySalt, pkg []byte) error {
+	decrypt := func(block []byte, value string) ([]byte, error) {
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+		iv := fixSize(hashOf(newHash, keySalt, block), aes.BlockSize, 0x36)
+		return aesCBC(secretKey, iv, data, false)
+	}
+	hmacKey, err := decrypt(agileIntegrityKeyBlock, integrity.EncryptedHmacKey)
+	if err != nil {
+		return err
+	}
+	hmacValue, err := decrypt(agileIntegrityValueBlock, integrity.EncryptedHmacValue)
+	if err != nil {
+		return err
+	}
+	size := newHash().Size()
+	if len(hmacKey) < size || len(hmacValue) < size {
+		return fmt.Errorf("the data integrity of the encrypted package is too short")
+	}
+	mac := hmac.New(newHash, hmacKey[:size])
+	mac.Write(pkg)
+	if !hmac.Equal(mac.Sum(nil), hmacValue[:size]) {
+		return fmt.Errorf("the encrypted package has been altered")
+	}
+	return nil
+}
+
+// encryptAgile encrypts the zip package plain, returning the content
+// of the EncryptionInfo and EncryptedPackage streams.
+func encryptAgile(plain []byte, password string) (info, pkg []byte, err error) {
+	random := func(n int) []byte {
+		b := make([]byte, n)
+		if err == nil {
+			_, err = rand.Read(b)
+		}
+		return b
+	}
+	keySalt := random(16)
+	passwordSalt := random(16)
+	secretKey := random(32)
+	verifierInput := random(16)
+	hmacKey := random(64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't generate encryption keys: %s", err)
+	}
+
+	iterated := spinPasswordHash(sha512.New, password, passwordSalt, agileSpinCount)
+	encrypt := func(key, iv, data []byte) string {
+		encrypted, _ := aesCBC(key, iv, pad(data, aes.BlockSize), true)
+		return base64.StdEncoding.EncodeToString(encrypted)
+	}
+	passwordKey := func(block []byte) []byte {
+		return fixSize(hashOf(sha512.New, iterated, block), 32, 0x36)
+	}
+	ek := xlsxEncryptedKey{
+		SpinCount:                  agileSpinCount,
+		EncryptedVerifierHashInput: encrypt(passwordKey(agileVerifierInputBlock), passwordSalt, verifierInput),
+		EncryptedVerifierHashValue: encrypt(passwordKey(agileVerifierValueBlock), passwordSalt, hashOf(sha512.New, verifierInput)),
+		EncryptedKeyValue:          encrypt(passwordKey(agileKeyValueBlock), passwordSalt, secretKey),
+	}
+
+	pkg = make([]byte, 8, 8+len(plain)+aes.BlockSize)
+	binary.LittleEndian.PutUint64(pkg, uint64(len(plain)))
+	for i, offset := 0, 0; offset < len(plain); i, offset = i+1, offset+encryptionSegmentSize {
+		end := offset + encryptionSegmentSize
+		if end > len(plain) {
+			end = len(plain)
+		}
+		iv := fixSize(hashOf(sha512.New, keySalt, uint32LE(uint32(i))), aes.BlockSize, 0x36)
+		encrypted, _ := aesCBC(secretKey, iv, pad(plain[offset:end], aes.BlockSize), true)
+		pkg = append(pkg, encrypted...)
+	}
+
+	mac := hmac.New(sha512.New, hmacKey)
+	mac.Write(pkg)
+	integrityIV := func(block []byte) []byte {
+		return fixSize(hashOf(sha512.New, keySalt, block), aes.BlockSize, 0x36)
+	}
+	encryptedHmacKey := encrypt(secretKey, integrityIV(agileIntegrityKeyBlock), hmacKey)
+	encryptedHmacValue := encrypt(secretKey, integrityIV(agileIntegrityValueBlock), mac.Sum(nil))
+
+	params := `saltSize="16" blockSize="16" keyBits="256" hashSize="64" cipherAlgorithm="AES" cipherChaining="ChainingModeCBC" hashAlgorithm="SHA512"`
+	description := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<encryption xmlns="http://schemas.microsoft.com/office/2006/encryption" xmlns:p="http://schemas.microsoft.com/office/2006/keyEncryptor/password"><keyData %s saltValue="%s"/><dataIntegrity encryptedHmacKey="%s" encryptedHmacValue="%s"/><keyEncryptors><keyEncryptor uri="http://schemas.microsoft.com/office/2006/keyEncryptor/password"><p:encryptedKey spinCount="%d" %s saltValue="%s" encryptedVerifierHashInput="%s" encryptedVerifierHashValue="%s" encryptedKeyValue="%s"/></keyEncryptor></keyEncryptors></encryption>`,
+		params, base64.StdEncoding.EncodeToString(keySalt),
+		encryptedHmacKey, encryptedHmacValue,
+		ek.SpinCount, params, base64.StdEncoding.EncodeToString(passwordSalt),
+		ek.EncryptedVerifierHashInput, ek.EncryptedVerifierHashValue, ek.EncryptedKeyValue)
+	info = []byte{4, 0, 4, 0, 0x40, 0, 0, 0}
+	info = append(info, description...)
+	return info, pkg, nil
+}
+
+// decryptStandard returns the zip package held in pkg, given the
+// binary description of its Standard encryption, which follows the
+// version.
+func decryptStandard(info, pkg []byte, password string) ([]byte, error) {
+	le := binary.LittleEndian
+	if len(info) < 8 {
+		return nil, fmt.Errorf("encryption info is too short")
+	}
+	headerSize := int(le.Uint32(info[4:]))
+	header := info[8:]
+	if headerSize < 32 || len(header) < headerSize+4+16+16+4+32 {
+		return nil, fmt.Errorf("encryption info is too short")
+	}
+	algID := le.Uint32(header[8:])
+	keySize := int(le.Uint32(header[16:]))
+	if algID != 0x660e && algID != 0x660f && algID != 0x6610 {
+		return nil, fmt.Errorf("unsupported encryption algorithm %#x", algID)
+	}
+	if !validAESKeySize(keySize) {
+		return nil, fmt.Errorf("unsupported key size %d", keySize)
+	}
+	if len(pkg) < 8 {
+		return nil, fmt.Errorf("encrypted package is too short")
+	}
+	verifier := header[headerSize:]
+	saltSize := int(le.Uint32(verifier))
+	if saltSize != 16 {
+		return nil, fmt.Errorf("unsupported salt size %d", saltSize)
+	}
+	salt := verifier[4:20]
+	encryptedVerifier := verifier[20:36]
+	encryptedVerifierHash := verifier[40:72]
+
+	key := standardPasswordKey(password, salt, keySize/8)
+	decryptedVerifier, err := aesECB(key, encryptedVerifier)
+	if err != nil {
+		return nil, err
+	}
+	decryptedHash, err := aesECB(key, encryptedVerifierHash)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(hashOf(sha1.New, decryptedVerifier), decryptedHash[:sha1.Size]) {
+		return nil, fmt.Errorf("incorrect password")
+	}
+
+	size := le.Uint64(pkg)
+	data := pkg[8:]
+	plain, err := aesECB(key, data[:len(data)-len(data)%aes.BlockSize])
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(plain)) < size {
+		return nil, fmt.Errorf("encrypted package is shorter than its size")
+	}
+	return plain[:size], nil
+}
+
+// validAESKeySize reports whether AES has keys of the given number of
+// bits.
+func validAESKeySize(bits int) bool {
+	return bits == 128 || bits == 192 || bits == 256
+}
+
+// standardPasswordKey derives the key for Standard encryption from
+// the password.
+func standardPasswordKey(password string, salt []byte, keyBytes int) []byte {
+	final := hashOf(sha1.New, spinPasswordHash(sha1.New, password, salt, standardSpinCount), uint32LE(0))
+	derive := func(fill byte) []byte {
+		buf := bytes.Repeat([]byte{fill}, 64)
+		for i, b := range final {
+			buf[i] ^= b
+		}
+		return hashOf(sha1.New, buf)
+	}
+	return append(derive(0x36), derive(0x5c)...)[:keyBytes]
+}
+
+// spinPasswordHash hashes the salted password, and then rehashes it,
+// preceded by the number of the iteration, spinCount times.  Note that
+// protection puts the iteration after the hash instead; see
+// passwordHash.
+func spinPasswordHash(newHash func() hash.Hash, password string, salt []byte, spinCount int) []byte {
+	h := newHash()
+	h.Write(salt)
+	h.Write(utf16LE(password))
+	result := h.Sum(nil)
+	iteration := make([]byte, 4)
+	for i := 0; i < spinCount; i++ {
+		binary.LittleEndian.PutUint32(iteration, uint32(i))
+		h.Reset()
+		h.Write(iteration)
+		h.Write(result)
+		result = h.Sum(result[:0])
+	}
+	return result
+}
+
+// hashOf returns the hash of the concatenated parts.
+func hashOf(newHash func() hash.Hash, parts ...[]byte) []byte {
+	h := newHash()
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return h.Sum(nil)
+}
+
+// fixSize truncates b to size, or pads it to size with fill.
+func fixSize(b []byte, size int, fill byte) []byte {
+	if len(b) >= size {
+		return b[:size]
+	}
+	return append(append([]byte{}, b...), bytes.Repeat([]byte{fill}, size-len(b))...)
+}
+
+// aesCBC encrypts or decrypts data, which has to be a whole number of
+// blocks, in CBC mode.
+func aesCBC(key, iv, data []byte, encrypt bool) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%aes.BlockSize != 0 || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("encrypted data isn't a whole number of blocks")
+	}
+	result := make([]byte, len(data))
+	if encrypt {
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(result, data)
+	} else {
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(result, data)
+	}
+	return result, nil
+}
+
+// aesECB decrypts data, which has to be a whole number of blocks, in
+// ECB mode.
+func aesECB(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("encrypted data isn't a whole number of blocks")
+	}
+	result := make([]byte, len(data))
+	for i := 0; i < len(data); i += aes.BlockSize {
+		block.Decrypt(result[i:], data[i:])
+	}
+	return result, nil
+}
+
+func uint32LE(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+// The streams of the \x06DataSpaces storage, which declare that the
+// EncryptedPackage stream is encrypted.
+
+func makeDataSpaceVersion() []byte {
+	b := unicodeLPP4("Microsoft.Container.DataSpaces")
+	for i := 0; i < 3; i++ {
+		b = append(b, 1, 0, 0, 0) // Reader, updater and writer versions 1.0.
+	}
+	return b
+}
+
+func makeDataSpaceMap() []byte {
+	entry := append(uint32LE(1), uint32LE(0)...)
+	entry = append(entry, unicodeLPP4("EncryptedPackage")...)
+	entry = append(entry, unicodeLPP4("StrongEncryptionDataSpace")...)
+	b := append(uint32LE(8), uint32LE(1)...)
+	b = append(b, uint32LE(uint32(len(entry)+4))...)
+	return append(b, entry...)
+}
+
+func makeDataSpaceDefinition() []byte {
+	b := append(uint32LE(8), uint32LE(1)...)
+	return append(b, unicodeLPP4("StrongEncryptionTransform")...)
+}
+
+func makeTransformInfo() []byte {
+	id := unicodeLPP4("{FF9A3F03-56EF-4613-BDD5-5A41C1D07246}")
+	b := append(uint32LE(uint32(8+len(id))), uint32LE(1)...)
+	b = append(b, id...)
+	b = append(b, unicodeLPP4("Microsoft.Container.EncryptionTransform")...)
+	for i := 0; i < 3; i++ {
+		b = append(b, 1, 0, 0, 0)
+	}
+	// An empty encryption name, a block size and cipher mode of 0,
+	// and a reserved 4.
+	b = append(b, uint32LE(0)...)
+	b = append(b, uint32LE(0)...)
+	b = append(b, uint32LE(0)...)
+	return append(b, uint32LE(4)...)
+}
+
+// unicodeLPP4 returns s as a length prefixed UTF-16 string, padded to
+// a multiple of 4 bytes.
+func unicodeLPP4(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := uint32LE(uint32(2 * len(units)))
+	for _, unit := range units {
+		b = append(b, byte(unit), byte(unit>>8))
+	}
+	return pad(b, 4)
+}
@@ -0,0 +1,185 @@
+package xlsx
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha1"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+)
+
+type EncryptionSuite struct{}
+
+var _ = Suite(&EncryptionSuite{})
+
+func (s *EncryptionSuite) TestWriteAndReadEncrypted(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Secret")
+	sheet.AddRow().AddCell().SetString("classified")
+
+	var buf bytes.Buffer
+	c.Assert(f.WriteEncrypted(&buf, "Pässwörd"), IsNil)
+	c.Assert(buf.String()[:8], Equals, cfbSignature)
+
+	f2, err := ReadEncrypted(bytes.NewReader(buf.Bytes()), "Pässwörd")
+	c.Assert(err, IsNil)
+	c.Assert(f2.Sheet["Secret"].Rows[0].Cells[0].Value, Equals, "classified")
+
+	_, err = ReadEncrypted(bytes.NewReader(buf.Bytes()), "password")
+	c.Assert(err, ErrorMatches, "incorrect password")
+
+	// The streams that declare the package to be encrypted are there
+	// too.
+	cfb, err := newCFBReader(bytes.NewReader(buf.Bytes()))
+	c.Assert(err, IsNil)
+	primary, err := cfb.readStream("\x06DataSpaces", "TransformInfo", "StrongEncryptionTransform", "\x06Primary")
+	c.Assert(err, IsNil)
+	c.Assert(binary.LittleEndian.Uint32(primary), Equals, uint32(0x58))
+}
+
+func (s *EncryptionSuite) TestOpenFileWithPassword(c *C) {
+	dir, err := ioutil.TempDir("", "xlsx")
+	c.Assert(err, IsNil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "encrypted.xlsx")
+
+	f := NewFile()
+	f.AddSheet("Sheet1").AddRow().AddCell().SetInt(42)
+	var buf bytes.Buffer
+	c.Assert(f.WriteEncrypted(&buf, "secret"), IsNil)
+	c.Assert(ioutil.WriteFile(path, buf.Bytes(), 0600), IsNil)
+
+	f2, err := OpenFileWithPassword(path, "secret")
+	c.Assert(err, IsNil)
+	c.Assert(f2.Sheet["Sheet1"].Rows[0].Cells[0].Value, Equals, "42")
+}
+
+// A package encrypted with Standard encryption, as older versions of
+// Excel did, is decrypted.
+func (s *EncryptionSuite) TestDecryptStandard(c *C) {
+	f := NewFile()
+	f.AddSheet("Sheet1").AddRow().AddCell().SetString("standard")
+	var plain bytes.Buffer
+	c.Assert(f.Write(&plain), IsNil)
+
+	salt := bytes.Repeat([]byte{7}, 16)
+	key := standardPasswordKey("secret", salt, 16)
+	block, err := aes.NewCipher(key)
+	c.Assert(err, IsNil)
+	encryptECB := func(data []byte) []byte {
+		data = pad(data, aes.BlockSize)
+		result := make([]byte, len(data))
+		for i := 0; i < len(data); i += aes.BlockSize {
+			block.Encrypt(result[i:], data[i:])
+		}
+		return result
+	}
+
+	verifier := bytes.Repeat([]byte{9}, 16)
+	verifierHash := sha1.Sum(verifier)
+	info := []byte{3, 0, 2, 0, 0x24, 0, 0, 0}
+	header := make([]byte, 32)
+	binary.LittleEndian.PutUint32(header[0:], 0x24)
+	binary.LittleEndian.PutUint32(header[8:], 0x660e)
+	binary.LittleEndian.PutUint32(header[12:], 0x8004)
+	binary.LittleEndian.PutUint32(header[16:], 128)
+	info = append(info, uint32LE(uint32(len(header)))...)
+	info = append(info, header...)
+	info = append(info, uint32LE(16)...)
+	info = append(info, salt...)
+	info = append(info, encryptECB(verifier)...)
+	info = append(info, uint32LE(20)...)
+	info = append(info, encryptECB(verifierHash[:])...)
+
+	pkg := make([]byte, 8)
+	binary.LittleEndian.PutUint64(pkg, uint64(plain.Len()))
+	pkg = append(pkg, encryptECB(plain.Bytes())...)
+
+	var buf bytes.Buffer
+	c.Assert(writeCFB(&buf,
+		newCFBStream("EncryptionInfo", info),
+		newCFBStream("EncryptedPackage", pkg)), IsNil)
+
+	f2, err := ReadEncrypted(bytes.NewReader(buf.Bytes()), "secret")
+	c.Assert(err, IsNil)
+	c.Assert(f2.Sheet["Sheet1"].Rows[0].Cells[0].Value, Equals, "standard")
+	_, err = ReadEncrypted(bytes.NewReader(buf.Bytes()), "wrong")
+	c.Assert(err, ErrorMatches, "incorrect password")
+
+	// Malformed encryption gives an error rather than a panic.
+	bad := append([]byte{}, info...)
+	binary.LittleEndian.PutUint32(bad[28:], 1024)
+	_, err = decryptPackage(bad, pkg, "secret")
+	c.Assert(err, ErrorMatches, "unsupported key size 1024")
+	_, err = decryptStandard(info[4:], pkg[:4], "secret")
+	c.Assert(err, ErrorMatches, "encrypted package is too short")
+}
+
+// A package that has been altered since it was encrypted, or whose
+// encryption is malformed, gives an error rather than what it holds.
+func (s *EncryptionSuite) TestDecryptAgileChecks(c *C) {
+	f := NewFile()
+	f.AddSheet("Sheet1").AddRow().AddCell().SetString("agile")
+	var plain bytes.Buffer
+	c.Assert(f.Write(&plain), IsNil)
+	info, pkg, err := encryptAgile(plain.Bytes(), "secret")
+	c.Assert(err, IsNil)
+
+	decrypted, err := decryptPackage(info, pkg, "secret")
+	c.Assert(err, IsNil)
+	c.Assert(decrypted, DeepEquals, plain.Bytes())
+
+	altered := append([]byte{}, pkg...)
+	altered[len(altered)-1] ^= 1
+	_, err = decryptPackage(info, altered, "secret")
+	c.Assert(err, ErrorMatches, "the encrypted package has been altered")
+
+	_, err = decryptAgile(info[8:], pkg[:4], "secret")
+	c.Assert(err, ErrorMatches, "encrypted package is too short")
+	for _, test := range []struct{ from, to, err string }{
+		{`keyBits="256"`, `keyBits="4096"`, "unsupported key size 4096"},
+		{`blockSize="16"`, `blockSize="-1"`, "unsupported block size -1"},
+		{`saltSize="16"`, `saltSize="0"`, "unsupported salt size 0"},
+	} {
+		badInfo := bytes.Replace(info, []byte(test.from), []byte(test.to), 1)
+		_, err = decryptPackage(badInfo, pkg, "secret")
+		c.Assert(err, ErrorMatches, test.err)
+	}
+}
+
+// Files that were encrypted by Excel, with the password "password",
+// are decrypted, using both Agile and Standard encryption.  They are
+// test/encryptSHA1.xlsx and test/encryptAES.xlsx, respectively, from
+// github.com/xuri/excelize.
+func (s *EncryptionSuite) TestOpenFileEncryptedByExcel(c *C) {
+	for _, test := range []struct {
+		name         string
+		major, minor uint16
+	}{
+		{"encryptedAgile.xlsx", 4, 4},
+		{"encryptedStandard.xlsx", 3, 2},
+	} {
+		path := filepath.Join("testdocs", test.name)
+		r, err := os.Open(path)
+		c.Assert(err, IsNil)
+		cfb, err := newCFBReader(r)
+		c.Assert(err, IsNil)
+		info, err := cfb.readStream("EncryptionInfo")
+		r.Close()
+		c.Assert(err, IsNil)
+		c.Assert(binary.LittleEndian.Uint16(info), Equals, test.major)
+		c.Assert(binary.LittleEndian.Uint16(info[2:]), Equals, test.minor)
+
+		f, err := OpenFileWithPassword(path, "password")
+		c.Assert(err, IsNil)
+		c.Assert(f.Sheets, HasLen, 1)
+		c.Assert(f.Sheet["Sheet1"].Cell(0, 0).String(), Equals, "SECRET")
+
+		_, err = OpenFileWithPassword(path, "passwd")
+		c.Assert(err, ErrorMatches, "incorrect password")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"strconv"
 )
 
@@ -15,10 +16,33 @@ type File struct {
 	worksheets     map[string]*zip.File
 	referenceTable *RefTable
 	Date1904       bool
+	// InlineStrings, when set, makes Write store the value of
+	// string cells in the worksheets themselves, rather than in the
+	// shared strings table.  Cells read from inline strings are
+	// always written back that way.
+	InlineStrings bool
 	styles         *xlsxStyleSheet
 	Sheets         []*Sheet
 	Sheet          map[string]*Sheet
 	theme          *theme
+	definedNames   []*DefinedName
+	// workbookProtection is how the structure of the workbook is
+	// protected, if at all.
+	workbookProtection xlsxWorkbookProtection
+
+	// What follows is only set for a File that has been read, and
+	// holds what we don't model, so that it survives being written
+	// back out: the workbook's unmodelled elements, its relationships
+	// to parts other than the ones we generate, the parts themselves
+	// and the content types declared for them.
+	worksheetRels map[string]*zip.File
+	unmodelled    *xlsxWorkbook
+	workbookRels  []xlsxWorkbookRelation
+	retainedParts map[string]string
+	contentTypes  *xlsxTypes
+	// readSheetNames are the names of the sheets, in order, as they
+	// were read, which the retained docProps/app.xml lists.
+	readSheetNames []string
 }
 
 // Create a new File
@@ -124,7 +148,8 @@ func (f *File) makeWorkbook() xlsxWorkbook {
 	workbook.FileVersion.AppName = "Go XLSX"
 	workbook.WorkbookPr = xlsxWorkbookPr{
 		BackupFile:  false,
-		ShowObjects: "all"}
+		ShowObjects: "all",
+		Date1904:    f.Date1904}
 	workbook.BookViews = xlsxBookViews{}
 	workbook.BookViews.WorkBookView = make([]xlsxWorkBookView, 1)
 	workbook.BookViews.WorkBookView[0] = xlsxWorkBookView{
@@ -144,6 +169,11 @@ func (f *File) makeWorkbook() xlsxWorkbook {
 	workbook.CalcPr.RefMode = "A1"
 	workbook.CalcPr.Iterate = false
 	workbook.CalcPr.IterateDelta = 0.001
+	workbook.WorkbookProtection = f.workbookProtection
+	workbook.DefinedNames = f.makeXLSXDefinedNames()
+	if f.unmodelled != nil {
+		workbook.copyUnmodelled(f.unmodelled)
+	}
 	return workbook
 }
 
@@ -167,16 +197,55 @@ func (f *File) MarshallParts() (map[string]string, error) {
 	}
 
 	parts = make(map[string]string)
+	parts["_rels/.rels"] = TEMPLATE__RELS_DOT_RELS
+	parts["docProps/app.xml"] = TEMPLATE_DOCPROPS_APP
+	// TODO - do this properly, modification and revision information
+	parts["docProps/core.xml"] = TEMPLATE_DOCPROPS_CORE
+	parts["xl/theme/theme1.xml"] = TEMPLATE_XL_THEME_THEME
+	// The parts retained from the file that was read go in first, so
+	// that those we write, such as a retained drawing that has had
+	// more drawn on it, take their place.
+	for partName, part := range f.retainedParts {
+		if partName == "docProps/app.xml" && f.sheetsChanged() {
+			part = dropSheetTitles(part)
+		}
+		parts[partName] = part
+	}
 	workbook = f.makeWorkbook()
 	sheetIndex := 1
 
+	// The relationships we retain keep their ids, as the parts that
+	// refer to them do so by id, so ours have to work around them.
+	reservedRelIds := make(map[string]bool, len(f.workbookRels))
+	for _, rel := range f.workbookRels {
+		reservedRelIds[rel.Id] = true
+	}
+	relCount := 0
+	names := newPartNamer(f.retainedParts)
+	pivotCaches := newPivotCacheList(workbook.PivotCaches)
+	lastTableId := maxTableId(f.retainedParts)
+	tableNames := f.tableNames()
+
 	if f.styles == nil {
 		f.styles = newXlsxStyleSheet(f.theme)
 	}
 	f.styles.reset()
 	for _, sheet := range f.Sheets {
 		xSheet := sheet.makeXLSXSheet(refTable, f.styles)
-		rId := fmt.Sprintf("rId%d", sheetIndex)
+		var sheetRels string
+		sheetRels, err = sheet.makeDrawingParts(xSheet, names, parts, &types)
+		if err != nil {
+			return parts, err
+		}
+		sheetRels, err = sheet.makePivotTableParts(sheetRels, names, parts, &types, pivotCaches)
+		if err != nil {
+			return parts, err
+		}
+		sheetRels, err = sheet.makeTableParts(xSheet, sheetRels, names, parts, &types, &lastTableId, tableNames)
+		if err != nil {
+			return parts, err
+		}
+		rId := nextFreeRelId(&relCount, reservedRelIds)
 		sheetId := strconv.Itoa(sheetIndex)
 		sheetPath := fmt.Sprintf("worksheets/sheet%d.xml", sheetIndex)
 		partName := "xl/" + sheetPath
@@ -190,38 +259,42 @@ func (f *File) MarshallParts() (map[string]string, error) {
 			Name:    sheet.Name,
 			SheetId: sheetId,
 			Id:      rId,
-			State:   "visible"}
+			State:   sheet.state()}
 		parts[partName], err = marshal(xSheet)
 		if err != nil {
 			return parts, err
 		}
+		if sheetRels != "" {
+			parts[fmt.Sprintf("xl/worksheets/_rels/sheet%d.xml.rels", sheetIndex)] = sheetRels
+		}
 		sheetIndex++
 	}
 
+	var pivotCacheRels []xlsxWorkbookRelation
+	workbook.PivotCaches, pivotCacheRels = pivotCaches.makeXLSXPivotCaches(&relCount, reservedRelIds)
 	parts["xl/workbook.xml"], err = marshal(workbook)
 	if err != nil {
 		return parts, err
 	}
 
-	parts["_rels/.rels"] = TEMPLATE__RELS_DOT_RELS
-	parts["docProps/app.xml"] = TEMPLATE_DOCPROPS_APP
-	// TODO - do this properly, modification and revision information
-	parts["docProps/core.xml"] = TEMPLATE_DOCPROPS_CORE
-	parts["xl/theme/theme1.xml"] = TEMPLATE_XL_THEME_THEME
-
 	xSST := refTable.makeXLSXSST()
 	parts["xl/sharedStrings.xml"], err = marshal(xSST)
 	if err != nil {
 		return parts, err
 	}
 
-	xWRel := workbookRels.MakeXLSXWorkbookRels()
+	xWRel := workbookRels.makeXLSXWorkbookRels(reservedRelIds)
+	xWRel.Relationships = append(xWRel.Relationships, f.workbookRels...)
+	xWRel.Relationships = append(xWRel.Relationships, pivotCacheRels...)
 
 	parts["xl/_rels/workbook.xml.rels"], err = marshal(xWRel)
 	if err != nil {
 		return parts, err
 	}
 
+	if f.contentTypes != nil {
+		types.keepOriginal(f.contentTypes, f.retainedParts)
+	}
 	parts["[Content_Types].xml"], err = marshal(types)
 	if err != nil {
 		return parts, err
@@ -266,3 +339,27 @@ func (file *File) ToSlice() (output [][][]string, err error) {
 	}
 	return output, nil
 }
+
+// sheetsChanged reports whether sheets have been added, removed,
+// renamed or reordered since the File was read.
+func (f *File) sheetsChanged() bool {
+	if len(f.Sheets) != len(f.readSheetNames) {
+		return true
+	}
+	for i, sheet := range f.Sheets {
+		if sheet.Name != f.readSheetNames[i] {
+			return true
+		}
+	}
+	return false
+}
+
+var sheetTitlesRegexp = regexp.MustCompile(`(?s)<(\w+:)?(HeadingPairs|TitlesOfParts)\b.*?</(\w+:)?(HeadingPairs|TitlesOfParts)>`)
+
+// dropSheetTitles returns the extended properties of app, a
+// docProps/app.xml part, without the HeadingPairs and TitlesOfParts
+// elements, which list the sheets.  They are optional, and Excel
+// writes them afresh when it saves the file.
+func dropSheetTitles(app string) string {
+	return sheetTitlesRegexp.ReplaceAllString(app, "")
+}
@@ -1,8 +1,11 @@
 package xlsx
 
 import (
+	"archive/zip"
+	"bytes"
 	"encoding/xml"
 	"path/filepath"
+	"strings"
 
 	. "gopkg.in/check.v1"
 )
@@ -782,3 +785,185 @@ func (s *SliceReaderSuite) TestFileWithEmptyCols(c *C) {
   c.Assert(sheet.Cell(0, 0).String(), Equals, "")
   c.Assert(sheet.Cell(0, 2).String(), Equals, "C1")
 }
+
+// When we write a File that was read from disk the original
+// stylesheet is retained, and cells whose style hasn't changed keep
+// their original style index.
+func (l *FileSuite) TestMarshalPreservesOriginalStyles(c *C) {
+	f, err := OpenFile("./testdocs/testfile.xlsx")
+	c.Assert(err, IsNil)
+	sheet := f.Sheet["Tabelle1"]
+	sheet.Cell(0, 0).SetString("Changed")
+	bold := *sheet.Cell(1, 1).GetStyle()
+	bold.Font.Bold = true
+	sheet.Cell(1, 1).SetStyle(&bold)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	styles := parts["xl/styles.xml"]
+	c.Assert(strings.Contains(styles, `<numFmts count="1"><numFmt numFmtId="164" formatCode="GENERAL"/></numFmts>`), Equals, true)
+	c.Assert(strings.Contains(styles, `<cellStyle builtinId="0" customBuiltin="false" name="Normal" xfId="0"/>`), Equals, true)
+	c.Assert(strings.Contains(styles, `<rgbColor rgb="FF333333"/>`), Equals, true)
+	c.Assert(strings.Contains(styles, `<cellXfs count="4">`), Equals, true)
+
+	worksheet := new(xlsxWorksheet)
+	err = xml.NewDecoder(strings.NewReader(parts["xl/worksheets/sheet1.xml"])).Decode(worksheet)
+	c.Assert(err, IsNil)
+	xRows := worksheet.SheetData.Row
+	c.Assert(xRows[0].C[0].S, Equals, 1)
+	c.Assert(xRows[0].C[1].S, Equals, 0)
+	c.Assert(xRows[1].C[0].S, Equals, 0)
+	c.Assert(xRows[1].C[1].S, Equals, 3)
+
+	// Writing again doesn't accumulate styles.
+	parts, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/styles.xml"], `<cellXfs count="4">`), Equals, true)
+
+	// And the result can be read back, with the same styles.
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	sheet2 := f2.Sheet["Tabelle1"]
+	c.Assert(sheet2.Cell(0, 0).String(), Equals, "Changed")
+	c.Assert(sheet2.Cell(0, 0).GetStyle().Fill.BgColor, Equals, "FF33CCCC")
+	c.Assert(sheet2.Cell(1, 1).GetStyle().Border.Left, Equals, "thin")
+	c.Assert(sheet2.Cell(1, 1).GetStyle().Font.Bold, Equals, true)
+	c.Assert(sheet2.Cell(1, 0).GetStyle().Font.Bold, Equals, false)
+}
+
+// When we write a File that was read from disk, the parts and
+// worksheet elements that we don't model are written back out, along
+// with the relationships that tie them together.
+func (l *FileSuite) TestMarshalRetainsUnknownParts(c *C) {
+	f, err := OpenFile("./testdocs/googleDocsTest.xlsx")
+	c.Assert(err, IsNil)
+	f.Sheets[0].Cell(0, 0).SetString("Changed")
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.HasPrefix(parts["xl/drawings/drawing1.xml"], `<?xml`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/_rels/sheet1.xml.rels"], `Target="../drawings/drawing1.xml"`), Equals, true)
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], `<Override PartName="/xl/drawings/drawing1.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing+xml"></Override>`), Equals, true)
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	c.Assert(strings.Contains(sheet, `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`), Equals, true)
+	c.Assert(strings.HasSuffix(sheet, `</headerFooter><drawing r:id="rId1"></drawing></worksheet>`), Equals, true)
+
+	// And the result reads back the same way.
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	c.Assert(f2.Sheets[0].Cell(0, 0).String(), Equals, "Changed")
+	parts2, err := f2.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts2["xl/worksheets/sheet1.xml"], Equals, sheet)
+	c.Assert(parts2["xl/drawings/drawing1.xml"], Equals, parts["xl/drawings/drawing1.xml"])
+}
+
+// Retained workbook relationships keep their ids, and the ones we
+// generate work around them.  The content types needed by retained
+// parts are kept too, including the workbook's own.
+func (l *FileSuite) TestMarshalRetainsWorkbookRelationships(c *C) {
+	macroEnabled := "application/vnd.ms-excel.sheet.macroEnabled.main+xml"
+	f := NewFile()
+	f.AddSheet("Sheet1")
+	f.workbookRels = []xlsxWorkbookRelation{{
+		Id:     "rId1",
+		Target: "vbaProject.bin",
+		Type:   "http://schemas.microsoft.com/office/2006/relationships/vbaProject"}}
+	f.retainedParts = map[string]string{"xl/vbaProject.bin": "VBA"}
+	f.contentTypes = &xlsxTypes{
+		Overrides: []xlsxOverride{{PartName: "/xl/workbook.xml", ContentType: macroEnabled}},
+		Defaults:  []xlsxDefault{{Extension: "bin", ContentType: "application/vnd.ms-office.vbaProject"}}}
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/vbaProject.bin"], Equals, "VBA")
+	c.Assert(strings.Contains(parts["xl/workbook.xml"], `relationships:id="rId2"`), Equals, true)
+	c.Assert(parts["xl/_rels/workbook.xml.rels"], Equals, `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId2" Target="worksheets/sheet1.xml" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"></Relationship><Relationship Id="rId3" Target="sharedStrings.xml" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"></Relationship><Relationship Id="rId4" Target="theme/theme1.xml" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"></Relationship><Relationship Id="rId5" Target="styles.xml" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"></Relationship><Relationship Id="rId1" Target="vbaProject.bin" Type="http://schemas.microsoft.com/office/2006/relationships/vbaProject"></Relationship></Relationships>`)
+	types := parts["[Content_Types].xml"]
+	c.Assert(strings.Contains(types, `<Override PartName="/xl/workbook.xml" ContentType="`+macroEnabled+`">`), Equals, true)
+	c.Assert(strings.Contains(types, `<Default Extension="bin" ContentType="application/vnd.ms-office.vbaProject">`), Equals, true)
+}
+
+// The retained docProps/app.xml only keeps its list of the sheets
+// while the sheets are the ones that were read.
+func (l *FileSuite) TestMarshalDropsStaleSheetTitles(c *C) {
+	app := `<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes">` +
+		`<Application>Microsoft Excel</Application>` +
+		`<HeadingPairs><vt:vector size="2" baseType="variant"><vt:variant><vt:lpstr>Worksheets</vt:lpstr></vt:variant><vt:variant><vt:i4>1</vt:i4></vt:variant></vt:vector></HeadingPairs>` +
+		`<TitlesOfParts><vt:vector size="1" baseType="lpstr"><vt:lpstr>Sheet1</vt:lpstr></vt:vector></TitlesOfParts>` +
+		`<Company>Acme</Company></Properties>`
+	f := NewFile()
+	f.AddSheet("Sheet1")
+	f.readSheetNames = []string{"Sheet1"}
+	f.retainedParts = map[string]string{"docProps/app.xml": app}
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["docProps/app.xml"], Equals, app)
+
+	f.AddSheet("Sheet2")
+	parts, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["docProps/app.xml"], Equals, `<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes">`+
+		`<Application>Microsoft Excel</Application><Company>Acme</Company></Properties>`)
+}
+
+// Files that are read and written back out keep their date system,
+// the state of their sheets and their default row heights and column
+// widths.
+func (l *FileSuite) TestWriteRoundTripsWorkbookAndSheetProperties(c *C) {
+	for _, name := range []string{"testcelltypes.xlsx", "macExcelTest.xlsx", "hiddenSheets.xlsx"} {
+		f, err := OpenFile("./testdocs/" + name)
+		c.Assert(err, IsNil)
+		var buf bytes.Buffer
+		c.Assert(f.Write(&buf), IsNil)
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		c.Assert(err, IsNil)
+		f2, err := ReadZipReader(zr)
+		c.Assert(err, IsNil)
+
+		c.Assert(f2.Date1904, Equals, f.Date1904)
+		output, err := f.ToSlice()
+		c.Assert(err, IsNil)
+		output2, err := f2.ToSlice()
+		c.Assert(err, IsNil)
+		c.Assert(output2, DeepEquals, output)
+		c.Assert(len(f2.Sheets), Equals, len(f.Sheets))
+		for i, sheet := range f.Sheets {
+			c.Assert(f2.Sheets[i].state(), Equals, sheet.state())
+			c.Assert(f2.Sheets[i].unmodelled.SheetFormatPr, DeepEquals, sheet.unmodelled.SheetFormatPr)
+		}
+	}
+
+	f, err := OpenFile("./testdocs/testcelltypes.xlsx")
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[0].Cell(4, 0).String(), Equals, "01-01-15")
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/workbook.xml"], `date1904="true"`), Equals, true)
+
+	f, err = OpenFile("./testdocs/macExcelTest.xlsx")
+	c.Assert(err, IsNil)
+	parts, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<sheetFormatPr baseColWidth="10" defaultColWidth="15.83203125" defaultRowHeight="25" customHeight="true" x14ac:dyDescent="0"></sheetFormatPr>`), Equals, true)
+
+	f, err = OpenFile("./testdocs/hiddenSheets.xlsx")
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[1].Hidden, Equals, true)
+	c.Assert(f.Sheets[2].Hidden, Equals, true)
+	parts, err = f.MarshallParts()
+	c.Assert(err, IsNil)
+	workbook := parts["xl/workbook.xml"]
+	c.Assert(strings.Contains(workbook, `relationships:id="rId1" state="visible"`), Equals, true)
+	c.Assert(strings.Contains(workbook, `relationships:id="rId2" state="hidden"`), Equals, true)
+	c.Assert(strings.Contains(workbook, `relationships:id="rId3" state="veryHidden"`), Equals, true)
+}
@@ -0,0 +1,403 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HeaderFooter holds the headers and footers printed on the pages of
+// a Sheet.  The odd page header and footer are used for every page
+// unless DifferentOddEven or DifferentFirst are set, in which case the
+// even page and first page ones are used for those pages.
+type HeaderFooter struct {
+	DifferentOddEven bool
+	DifferentFirst   bool
+	OddHeader        HeaderFooterText
+	OddFooter        HeaderFooterText
+	EvenHeader       HeaderFooterText
+	EvenFooter       HeaderFooterText
+	FirstHeader      HeaderFooterText
+	FirstFooter      HeaderFooterText
+}
+
+// NewHeaderFooter returns the HeaderFooter that a Sheet is written
+// with unless it is given another: the name of the sheet at the top of
+// each page and the page number at the bottom.
+func NewHeaderFooter() *HeaderFooter {
+	return &HeaderFooter{
+		OddHeader: HeaderFooterText{
+			Center: NewHeaderFooterBuilder().Font("Times New Roman", "Regular").FontSize(12).SheetName().String()},
+		OddFooter: HeaderFooterText{
+			Center: NewHeaderFooterBuilder().Font("Times New Roman", "Regular").FontSize(12).Text("Page ").PageNumber().String()},
+	}
+}
+
+// HeaderFooterText is a single header or footer, made up of left,
+// centre and right aligned sections.  Each section is text that can
+// include control codes, such as "&P" for the page number, which are
+// most easily put together with a HeaderFooterBuilder and taken apart
+// with Items.
+type HeaderFooterText struct {
+	Left   string
+	Center string
+	Right  string
+}
+
+// String returns the header or footer as it is stored in a worksheet.
+func (t HeaderFooterText) String() string {
+	result := ""
+	if t.Left != "" {
+		result += "&L" + t.Left
+	}
+	if t.Center != "" {
+		result += "&C" + t.Center
+	}
+	if t.Right != "" {
+		result += "&R" + t.Right
+	}
+	return result
+}
+
+// Items returns the literal text and control codes of each section of
+// the header or footer, as ParseHeaderFooterSection gives them.
+func (t HeaderFooterText) Items() (left, center, right []HeaderFooterItem) {
+	return ParseHeaderFooterSection(t.Left), ParseHeaderFooterSection(t.Center), ParseHeaderFooterSection(t.Right)
+}
+
+// parseHeaderFooterText splits a header or footer, as it is stored in
+// a worksheet, into its sections.  Anything before the first section
+// code is centred, as it is by Excel.
+func parseHeaderFooterText(s string) HeaderFooterText {
+	t := HeaderFooterText{}
+	section := &t.Center
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '&' || i+1 == len(s) {
+			continue
+		}
+		var next *string
+		switch s[i+1] {
+		case 'L':
+			next = &t.Left
+		case 'C':
+			next = &t.Center
+		case 'R':
+			next = &t.Right
+		default:
+			// Another code, or an escaped ampersand; either
+			// way it stays part of the section.
+			i++
+			continue
+		}
+		*section += s[start:i]
+		section = next
+		start = i + 2
+		i++
+	}
+	*section += s[start:]
+	return t
+}
+
+// HeaderFooterBuilder puts together the text and control codes of a
+// section of a header or footer.  For example:
+//
+//	NewHeaderFooterBuilder().Text("Page ").PageNumber().Text(" of ").PageCount().String()
+//
+// gives "Page &P of &N".
+type HeaderFooterBuilder struct {
+	codes    string
+	fontSize bool // The last code added was a font size.
+}
+
+// NewHeaderFooterBuilder returns an empty HeaderFooterBuilder.
+func NewHeaderFooterBuilder() *HeaderFooterBuilder {
+	return &HeaderFooterBuilder{}
+}
+
+// String returns the section that has been built.
+func (b *HeaderFooterBuilder) String() string {
+	return b.codes
+}
+
+// Text adds literal text, escaping any ampersands in it.  Text that
+// starts with a digit straight after a font size is separated from it
+// by a space, as the digits would otherwise be read as part of the
+// size.
+func (b *HeaderFooterBuilder) Text(text string) *HeaderFooterBuilder {
+	if b.fontSize && text != "" && text[0] >= '0' && text[0] <= '9' {
+		b.codes += " "
+	}
+	return b.add(strings.Replace(text, "&", "&&", -1))
+}
+
+// add adds codes that aren't a font size.
+func (b *HeaderFooterBuilder) add(codes string) *HeaderFooterBuilder {
+	b.codes += codes
+	b.fontSize = false
+	return b
+}
+
+// PageNumber adds the number of the page.
+func (b *HeaderFooterBuilder) PageNumber() *HeaderFooterBuilder {
+	return b.add("&P")
+}
+
+// PageCount adds the total number of pages.
+func (b *HeaderFooterBuilder) PageCount() *HeaderFooterBuilder {
+	return b.add("&N")
+}
+
+// Date adds the date the sheet is printed on.
+func (b *HeaderFooterBuilder) Date() *HeaderFooterBuilder {
+	return b.add("&D")
+}
+
+// Time adds the time the sheet is printed at.
+func (b *HeaderFooterBuilder) Time() *HeaderFooterBuilder {
+	return b.add("&T")
+}
+
+// FileName adds the name of the workbook file.
+func (b *HeaderFooterBuilder) FileName() *HeaderFooterBuilder {
+	return b.add("&F")
+}
+
+// FilePath adds the path of the workbook file.
+func (b *HeaderFooterBuilder) FilePath() *HeaderFooterBuilder {
+	return b.add("&Z")
+}
+
+// SheetName adds the name of the sheet.
+func (b *HeaderFooterBuilder) SheetName() *HeaderFooterBuilder {
+	return b.add("&A")
+}
+
+// Font switches the text that follows to the named font, in the given
+// style, such as "Regular", "Bold" or "Bold Italic".
+func (b *HeaderFooterBuilder) Font(name, style string) *HeaderFooterBuilder {
+	return b.add(fmt.Sprintf(`&"%s,%s"`, name, style))
+}
+
+// FontSize switches the text that follows to the given size, in
+// points.
+func (b *HeaderFooterBuilder) FontSize(size int) *HeaderFooterBuilder {
+	b.codes += fmt.Sprintf("&%d", size)
+	b.fontSize = true
+	return b
+}
+
+// Bold toggles bold text on or off.
+func (b *HeaderFooterBuilder) Bold() *HeaderFooterBuilder {
+	return b.add("&B")
+}
+
+// Italic toggles italic text on or off.
+func (b *HeaderFooterBuilder) Italic() *HeaderFooterBuilder {
+	return b.add("&I")
+}
+
+// Underline toggles underlined text on or off.
+func (b *HeaderFooterBuilder) Underline() *HeaderFooterBuilder {
+	return b.add("&U")
+}
+
+// HeaderFooterItemKind says what a HeaderFooterItem is.
+type HeaderFooterItemKind int
+
+const (
+	// HeaderFooterLiteral is literal text, which is in Text.
+	HeaderFooterLiteral HeaderFooterItemKind = iota
+	HeaderFooterPageNumber
+	HeaderFooterPageCount
+	HeaderFooterDate
+	HeaderFooterTime
+	HeaderFooterFileName
+	HeaderFooterFilePath
+	HeaderFooterSheetName
+	// HeaderFooterFont switches to the font in FontName and
+	// FontStyle.
+	HeaderFooterFont
+	// HeaderFooterFontSize switches to the size in FontSize.
+	HeaderFooterFontSize
+	HeaderFooterBold
+	HeaderFooterItalic
+	HeaderFooterUnderline
+	// HeaderFooterCode is any other control code, such as "&S" for
+	// strikethrough or "&KFF0000" for a colour, which is in Text as
+	// it is written.
+	HeaderFooterCode
+)
+
+// headerFooterCodes maps the control codes that are a single letter
+// to the kind of item they are.
+var headerFooterCodes = map[byte]HeaderFooterItemKind{
+	'P': HeaderFooterPageNumber,
+	'N': HeaderFooterPageCount,
+	'D': HeaderFooterDate,
+	'T': HeaderFooterTime,
+	'F': HeaderFooterFileName,
+	'Z': HeaderFooterFilePath,
+	'A': HeaderFooterSheetName,
+	'B': HeaderFooterBold,
+	'I': HeaderFooterItalic,
+	'U': HeaderFooterUnderline,
+}
+
+// HeaderFooterItem is a piece of a section of a header or footer:
+// literal text, or one of its control codes.
+type HeaderFooterItem struct {
+	Kind      HeaderFooterItemKind
+	Text      string
+	FontName  string
+	FontStyle string
+	FontSize  int
+}
+
+// Item adds a HeaderFooterItem, such as one that
+// ParseHeaderFooterSection returned.
+func (b *HeaderFooterBuilder) Item(item HeaderFooterItem) *HeaderFooterBuilder {
+	switch item.Kind {
+	case HeaderFooterLiteral:
+		return b.Text(item.Text)
+	case HeaderFooterFont:
+		return b.Font(item.FontName, item.FontStyle)
+	case HeaderFooterFontSize:
+		return b.FontSize(item.FontSize)
+	case HeaderFooterCode:
+		return b.add(item.Text)
+	}
+	for code, kind := range headerFooterCodes {
+		if kind == item.Kind {
+			return b.add("&" + string(code))
+		}
+	}
+	return b
+}
+
+// ParseHeaderFooterSection splits a section of a header or footer,
+// such as the Left, Center or Right of a HeaderFooterText, into its
+// literal text and control codes.  A HeaderFooterBuilder given the
+// items with Item builds the section again.
+func ParseHeaderFooterSection(section string) []HeaderFooterItem {
+	var items []HeaderFooterItem
+	literal := ""
+	add := func(item HeaderFooterItem) {
+		if literal != "" {
+			items = append(items, HeaderFooterItem{Kind: HeaderFooterLiteral, Text: literal})
+			literal = ""
+		}
+		items = append(items, item)
+	}
+	for i := 0; i < len(section); i++ {
+		if section[i] != '&' || i+1 == len(section) {
+			literal += section[i : i+1]
+			continue
+		}
+		c := section[i+1]
+		switch {
+		case c == '&':
+			literal += "&"
+			i++
+		case c == '"':
+			end := strings.IndexByte(section[i+2:], '"')
+			if end < 0 {
+				end = len(section) - i - 2
+			}
+			font := section[i+2 : i+2+end]
+			item := HeaderFooterItem{Kind: HeaderFooterFont, FontName: font}
+			if comma := strings.IndexByte(font, ','); comma >= 0 {
+				item.FontName, item.FontStyle = font[:comma], font[comma+1:]
+			}
+			add(item)
+			i += end + 2
+		case c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(section) && section[end] >= '0' && section[end] <= '9' {
+				end++
+			}
+			size, _ := strconv.Atoi(section[i+1 : end])
+			add(HeaderFooterItem{Kind: HeaderFooterFontSize, FontSize: size})
+			// The space that separates a size from digits
+			// that follow it isn't part of the text.
+			if end+1 < len(section) && section[end] == ' ' && section[end+1] >= '0' && section[end+1] <= '9' {
+				end++
+			}
+			i = end - 1
+		case c == 'K':
+			end := i + 8
+			if end > len(section) {
+				end = len(section)
+			}
+			add(HeaderFooterItem{Kind: HeaderFooterCode, Text: section[i:end]})
+			i = end - 1
+		default:
+			if kind, ok := headerFooterCodes[c]; ok {
+				add(HeaderFooterItem{Kind: kind})
+			} else {
+				add(HeaderFooterItem{Kind: HeaderFooterCode, Text: section[i : i+2]})
+			}
+			i++
+		}
+	}
+	if literal != "" {
+		items = append(items, HeaderFooterItem{Kind: HeaderFooterLiteral, Text: literal})
+	}
+	return items
+}
+
+// readHeaderFooter returns the HeaderFooter of a worksheet that has
+// been read.
+func readHeaderFooter(xHeaderFooter xlsxHeaderFooter) *HeaderFooter {
+	headerFooter := &HeaderFooter{
+		DifferentOddEven: xHeaderFooter.DifferentOddEven,
+		DifferentFirst:   xHeaderFooter.DifferentFirst,
+	}
+	for _, header := range xHeaderFooter.OddHeader {
+		headerFooter.OddHeader = parseHeaderFooterText(header.Content)
+	}
+	for _, footer := range xHeaderFooter.OddFooter {
+		headerFooter.OddFooter = parseHeaderFooterText(footer.Content)
+	}
+	for _, header := range xHeaderFooter.EvenHeader {
+		headerFooter.EvenHeader = parseHeaderFooterText(header.Content)
+	}
+	for _, footer := range xHeaderFooter.EvenFooter {
+		headerFooter.EvenFooter = parseHeaderFooterText(footer.Content)
+	}
+	for _, header := range xHeaderFooter.FirstHeader {
+		headerFooter.FirstHeader = parseHeaderFooterText(header.Content)
+	}
+	for _, footer := range xHeaderFooter.FirstFooter {
+		headerFooter.FirstFooter = parseHeaderFooterText(footer.Content)
+	}
+	return headerFooter
+}
+
+// makeXLSXHeaderFooter returns the xlsxHeaderFooter representation of
+// the HeaderFooter, leaving out any header or footer that is empty.
+func (hf *HeaderFooter) makeXLSXHeaderFooter() xlsxHeaderFooter {
+	xHeaderFooter := xlsxHeaderFooter{
+		DifferentOddEven: hf.DifferentOddEven,
+		DifferentFirst:   hf.DifferentFirst,
+	}
+	headers := func(t HeaderFooterText) []xlsxOddHeader {
+		if s := t.String(); s != "" {
+			return []xlsxOddHeader{{Content: s}}
+		}
+		return nil
+	}
+	footers := func(t HeaderFooterText) []xlsxOddFooter {
+		if s := t.String(); s != "" {
+			return []xlsxOddFooter{{Content: s}}
+		}
+		return nil
+	}
+	xHeaderFooter.OddHeader = headers(hf.OddHeader)
+	xHeaderFooter.OddFooter = footers(hf.OddFooter)
+	xHeaderFooter.EvenHeader = headers(hf.EvenHeader)
+	xHeaderFooter.EvenFooter = footers(hf.EvenFooter)
+	xHeaderFooter.FirstHeader = headers(hf.FirstHeader)
+	xHeaderFooter.FirstFooter = footers(hf.FirstFooter)
+	return xHeaderFooter
+}
@@ -0,0 +1,107 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type HeaderFooterSuite struct{}
+
+var _ = Suite(&HeaderFooterSuite{})
+
+func (s *HeaderFooterSuite) TestHeaderFooterBuilder(c *C) {
+	b := NewHeaderFooterBuilder().Font("Arial", "Bold").FontSize(10).Text("Page ").PageNumber().Text(" of ").PageCount()
+	c.Assert(b.String(), Equals, `&"Arial,Bold"&10Page &P of &N`)
+	b = NewHeaderFooterBuilder().Bold().Text("Tom & Jerry").Bold().Date().Time().FileName().FilePath().SheetName().Italic().Underline()
+	c.Assert(b.String(), Equals, `&BTom && Jerry&B&D&T&F&Z&A&I&U`)
+}
+
+func (s *HeaderFooterSuite) TestParseHeaderFooterText(c *C) {
+	text := HeaderFooterText{Left: "&D", Center: `&"Arial,Bold"Sales && Costs`, Right: "Page &P of &N"}
+	c.Assert(text.String(), Equals, `&L&D&C&"Arial,Bold"Sales && Costs&RPage &P of &N`)
+	c.Assert(parseHeaderFooterText(text.String()), DeepEquals, text)
+
+	// Text before any section code is centred.
+	c.Assert(parseHeaderFooterText("Report&R&A"), DeepEquals, HeaderFooterText{Center: "Report", Right: "&A"})
+	c.Assert(parseHeaderFooterText(""), DeepEquals, HeaderFooterText{})
+}
+
+func (s *HeaderFooterSuite) TestMakeXLSXHeaderFooter(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	sheet.HeaderFooter = &HeaderFooter{
+		DifferentFirst: true,
+		OddHeader:      HeaderFooterText{Right: "&P"},
+		FirstFooter:    HeaderFooterText{Center: "Confidential"},
+	}
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	output, err := xml.Marshal(xSheet)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), `<headerFooter differentFirst="true" differentOddEven="false"><oddHeader>&amp;R&amp;P</oddHeader><firstFooter>&amp;CConfidential</firstFooter></headerFooter>`), Equals, true)
+
+	// The default header and footer are those of NewHeaderFooter.
+	c.Assert(NewHeaderFooter().makeXLSXHeaderFooter(), DeepEquals, newXlsxWorksheet().HeaderFooter)
+}
+
+func (s *HeaderFooterSuite) TestHeaderFooterRoundTrip(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	sheet.HeaderFooter = &HeaderFooter{
+		DifferentOddEven: true,
+		OddHeader:        HeaderFooterText{Left: "&A", Right: "&D"},
+		OddFooter:        HeaderFooterText{Center: "Page &P"},
+		EvenHeader:       HeaderFooterText{Left: "&D", Right: "&A"},
+		EvenFooter:       HeaderFooterText{Center: "Page &P of &N"},
+	}
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	c.Assert(*f2.Sheet["Sheet1"].HeaderFooter, DeepEquals, *sheet.HeaderFooter)
+}
+
+// Text that starts with a digit isn't run into a font size before it.
+func (s *HeaderFooterSuite) TestHeaderFooterBuilderFontSizeDigits(c *C) {
+	c.Assert(NewHeaderFooterBuilder().FontSize(10).Text("12 items").String(), Equals, "&10 12 items")
+	c.Assert(NewHeaderFooterBuilder().FontSize(10).Text("Items").String(), Equals, "&10Items")
+	c.Assert(NewHeaderFooterBuilder().FontSize(10).Bold().Text("12").String(), Equals, "&10&B12")
+	c.Assert(ParseHeaderFooterSection("&10 12 items"), DeepEquals, []HeaderFooterItem{
+		{Kind: HeaderFooterFontSize, FontSize: 10},
+		{Kind: HeaderFooterLiteral, Text: "12 items"},
+	})
+}
+
+func (s *HeaderFooterSuite) TestParseHeaderFooterSection(c *C) {
+	section := `&"Arial,Bold Italic"&14Page &P of &N && more&S&KFF0000&X`
+	items := ParseHeaderFooterSection(section)
+	c.Assert(items, DeepEquals, []HeaderFooterItem{
+		{Kind: HeaderFooterFont, FontName: "Arial", FontStyle: "Bold Italic"},
+		{Kind: HeaderFooterFontSize, FontSize: 14},
+		{Kind: HeaderFooterLiteral, Text: "Page "},
+		{Kind: HeaderFooterPageNumber},
+		{Kind: HeaderFooterLiteral, Text: " of "},
+		{Kind: HeaderFooterPageCount},
+		{Kind: HeaderFooterLiteral, Text: " & more"},
+		{Kind: HeaderFooterCode, Text: "&S"},
+		{Kind: HeaderFooterCode, Text: "&KFF0000"},
+		{Kind: HeaderFooterCode, Text: "&X"},
+	})
+	b := NewHeaderFooterBuilder()
+	for _, item := range items {
+		b.Item(item)
+	}
+	c.Assert(b.String(), Equals, section)
+
+	left, center, right := HeaderFooterText{Left: "&D&T", Right: "&B&A&F&Z&I&U"}.Items()
+	c.Assert(left, DeepEquals, []HeaderFooterItem{{Kind: HeaderFooterDate}, {Kind: HeaderFooterTime}})
+	c.Assert(center, HasLen, 0)
+	c.Assert(right, DeepEquals, []HeaderFooterItem{{Kind: HeaderFooterBold}, {Kind: HeaderFooterSheetName},
+		{Kind: HeaderFooterFileName}, {Kind: HeaderFooterFilePath}, {Kind: HeaderFooterItalic}, {Kind: HeaderFooterUnderline}})
+}
@@ -0,0 +1,281 @@
+package xlsx
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// HTMLOptions control how WriteHTML renders a Sheet as an HTML table.
+type HTMLOptions struct {
+	// Range, such as "B2:D10", is the range of cells that is
+	// rendered.  It defaults to all of the Sheet.
+	Range string
+	// IncludeHidden renders the hidden rows and columns, with the
+	// style display:none, rather than leaving them out.
+	IncludeHidden bool
+}
+
+// WriteHTML writes the Sheet to w as an HTML table, with the cells'
+// formatted values and, as inline CSS, their fonts, fills, borders and
+// alignment, and the widths of the columns and the heights of the
+// rows.  Merged cells span the rows and columns of their range.  opts
+// may be nil.
+func (s *Sheet) WriteHTML(w io.Writer, opts *HTMLOptions) error {
+	options := HTMLOptions{}
+	if opts != nil {
+		options = *opts
+	}
+	bounds, err := s.rangeBounds(options.Range)
+	if err != nil {
+		return err
+	}
+	hiddenCols := make(map[int]bool)
+	widths := make(map[int]float64)
+	for _, col := range s.Cols {
+		for x := col.Min - 1; x < col.Max; x++ {
+			hiddenCols[x] = col.Hidden
+			widths[x] = col.Width
+		}
+	}
+	hiddenRow := func(y int) bool {
+		return y >= 0 && y < len(s.Rows) && s.Rows[y] != nil && s.Rows[y].Hidden
+	}
+	hidden := func(x, y int) bool {
+		return !options.IncludeHidden && (hiddenCols[x] || hiddenRow(y))
+	}
+
+	// The first rendered cell of each merged range, which is its
+	// top left cell unless the range or hidden rows and columns cut
+	// that off, shows the top left cell and spans the rows and
+	// columns of the range that are rendered.  The other cells of
+	// the range aren't rendered.
+	spans := make(map[[2]int][2]int)
+	origins := make(map[[2]int][2]int)
+	covered := make(map[[2]int]bool)
+	for _, r := range s.mergedRanges() {
+		first := [2]int{-1, -1}
+		colspan, rowspan := 0, 0
+		for x := r.x1; x <= r.x2; x++ {
+			if x >= bounds.x1 && x <= bounds.x2 && !hidden(x, -1) {
+				if colspan == 0 {
+					first[0] = x
+				}
+				colspan++
+			}
+		}
+		for y := r.y1; y <= r.y2; y++ {
+			if y >= bounds.y1 && y <= bounds.y2 && !hidden(-1, y) {
+				if rowspan == 0 {
+					first[1] = y
+				}
+				rowspan++
+			}
+		}
+		for y := r.y1; y <= r.y2; y++ {
+			for x := r.x1; x <= r.x2; x++ {
+				covered[[2]int{x, y}] = true
+			}
+		}
+		if colspan == 0 || rowspan == 0 {
+			continue
+		}
+		delete(covered, first)
+		spans[first] = [2]int{colspan, rowspan}
+		origins[first] = [2]int{r.x1, r.y1}
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`<table style="border-collapse:collapse">` + "\n<colgroup>")
+	for x := bounds.x1; x <= bounds.x2; x++ {
+		if hidden(x, -1) {
+			continue
+		}
+		var css []string
+		if widths[x] > 0 {
+			css = append(css, fmt.Sprintf("width:%dpx", s.colWidthPixels(x)))
+		}
+		bw.WriteString("<col" + styleAttr(css) + ">")
+	}
+	bw.WriteString("</colgroup>\n")
+	for y := bounds.y1; y <= bounds.y2; y++ {
+		if hidden(-1, y) {
+			continue
+		}
+		var row *Row
+		if y < len(s.Rows) {
+			row = s.Rows[y]
+		}
+		var css []string
+		if row != nil && row.Height > 0 {
+			css = append(css, "height:"+strconv.FormatFloat(row.Height, 'f', -1, 64)+"pt")
+		}
+		if row != nil && row.Hidden {
+			css = append(css, "display:none")
+		}
+		bw.WriteString("<tr" + styleAttr(css) + ">")
+		for x := bounds.x1; x <= bounds.x2; x++ {
+			if hidden(x, -1) || covered[[2]int{x, y}] {
+				continue
+			}
+			cell := s.cellAt(x, y)
+			if origin, ok := origins[[2]int{x, y}]; ok {
+				cell = s.cellAt(origin[0], origin[1])
+			}
+			bw.WriteString("<td")
+			if span, ok := spans[[2]int{x, y}]; ok {
+				if span[0] > 1 {
+					bw.WriteString(` colspan="` + strconv.Itoa(span[0]) + `"`)
+				}
+				if span[1] > 1 {
+					bw.WriteString(` rowspan="` + strconv.Itoa(span[1]) + `"`)
+				}
+			}
+			css := cellCSS(cell)
+			if hiddenCols[x] {
+				css = append(css, "display:none")
+			}
+			bw.WriteString(styleAttr(css) + ">")
+			bw.WriteString(strings.Replace(html.EscapeString(htmlText(cell)), "\n", "<br>", -1))
+			bw.WriteString("</td>")
+		}
+		bw.WriteString("</tr>\n")
+	}
+	bw.WriteString("</table>\n")
+	return bw.Flush()
+}
+
+// cellAt returns the cell at the zero-based coordinates, or nil if
+// there isn't one.
+func (s *Sheet) cellAt(x, y int) *Cell {
+	if y < 0 || y >= len(s.Rows) || s.Rows[y] == nil || x < 0 || x >= len(s.Rows[y].Cells) {
+		return nil
+	}
+	return s.Rows[y].Cells[x]
+}
+
+// styleAttr returns a style attribute with the CSS declarations, or
+// nothing if there aren't any.
+func styleAttr(css []string) string {
+	if len(css) == 0 {
+		return ""
+	}
+	return ` style="` + html.EscapeString(strings.Join(css, ";")) + `"`
+}
+
+// htmlText returns the text that a cell, which may be nil, shows.
+func htmlText(cell *Cell) string {
+	switch {
+	case cell == nil:
+		return ""
+	case cell.Type() == CellTypeBool:
+		return strings.ToUpper(strconv.FormatBool(cell.Bool()))
+	}
+	return cell.FormattedValue()
+}
+
+// cellCSS returns the CSS declarations for the style of a cell, which
+// may be nil.  As in Excel, the font, fill and border are only used
+// when the style applies them, and numbers are aligned to the right
+// unless the style says otherwise.
+func cellCSS(cell *Cell) []string {
+	if cell == nil {
+		return nil
+	}
+	var css []string
+	align := ""
+	if cell.Type() == CellTypeNumeric && cell.Value != "" {
+		align = "right"
+	}
+	if style := cell.GetStyle(); style != nil {
+		font := style.Font
+		if !style.ApplyFont {
+			font = Font{}
+		}
+		if font.Name != "" {
+			css = append(css, "font-family:'"+strings.Replace(font.Name, "'", "", -1)+"'")
+		}
+		if font.Size > 0 {
+			css = append(css, "font-size:"+strconv.Itoa(font.Size)+"pt")
+		}
+		if color := htmlColor(font.Color); color != "" {
+			css = append(css, "color:"+color)
+		}
+		if font.Bold {
+			css = append(css, "font-weight:bold")
+		}
+		if font.Italic {
+			css = append(css, "font-style:italic")
+		}
+		if font.Underline {
+			css = append(css, "text-decoration:underline")
+		}
+		if style.ApplyFill && style.Fill.PatternType != "" && style.Fill.PatternType != "none" {
+			if color := htmlColor(style.Fill.FgColor); color != "" {
+				css = append(css, "background-color:"+color)
+			}
+		}
+		for _, side := range []struct{ name, style string }{
+			{"top", style.Border.Top},
+			{"right", style.Border.Right},
+			{"bottom", style.Border.Bottom},
+			{"left", style.Border.Left},
+		} {
+			if border := htmlBorder(side.style); style.ApplyBorder && border != "" {
+				css = append(css, "border-"+side.name+":"+border)
+			}
+		}
+		switch style.Alignment.Horizontal {
+		case "left", "center", "right", "justify":
+			align = style.Alignment.Horizontal
+		case "centerContinuous":
+			align = "center"
+		case "distributed":
+			align = "justify"
+		}
+	}
+	if align != "" {
+		css = append(css, "text-align:"+align)
+	}
+	return css
+}
+
+// htmlColor returns the CSS colour of a colour given as ARGB, such as
+// "FFFF0000", or RGB, or nothing if it isn't either.
+func htmlColor(color string) string {
+	if len(color) == 8 {
+		color = color[2:]
+	}
+	if len(color) != 6 {
+		return ""
+	}
+	if _, err := strconv.ParseUint(color, 16, 32); err != nil {
+		return ""
+	}
+	return "#" + strings.ToLower(color)
+}
+
+// htmlBorder returns the CSS border that looks most like a border
+// style, such as "thin" or "mediumDashed", or nothing for no border.
+func htmlBorder(style string) string {
+	switch style {
+	case "", "none":
+		return ""
+	case "medium":
+		return "2px solid #000"
+	case "thick":
+		return "3px solid #000"
+	case "double":
+		return "3px double #000"
+	case "dashed", "dashDot", "dashDotDot":
+		return "1px dashed #000"
+	case "mediumDashed", "mediumDashDot", "mediumDashDotDot", "slantDashDot":
+		return "2px dashed #000"
+	case "dotted", "hair":
+		return "1px dotted #000"
+	}
+	return "1px solid #000"
+}
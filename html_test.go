@@ -0,0 +1,112 @@
+package xlsx
+
+import (
+	"bytes"
+
+	. "gopkg.in/check.v1"
+)
+
+type HTMLSuite struct{}
+
+var _ = Suite(&HTMLSuite{})
+
+func (s *HTMLSuite) TestWriteHTML(c *C) {
+	sheet := makeExportSheet()
+	style := NewStyle()
+	style.Font = Font{Size: 10, Name: "Arial", Color: "FFFF0000", Bold: true, Italic: true}
+	style.Fill = *NewFill("solid", "FFFFFF00", "")
+	style.Border = *NewBorder("thin", "", "", "double")
+	style.Alignment.Horizontal = "center"
+	style.ApplyFont, style.ApplyFill, style.ApplyBorder = true, true, true
+	sheet.Rows[0].Cells[0].SetStyle(style)
+	sheet.Rows[0].Height = 20.5
+	sheet.Rows[1].Cells[0].SetString("<Ann>\nSmith")
+	sheet.SetColWidth(0, 0, 10)
+
+	var buf bytes.Buffer
+	c.Assert(sheet.WriteHTML(&buf, nil), IsNil)
+	c.Assert(buf.String(), Equals, `<table style="border-collapse:collapse">`+"\n"+
+		`<colgroup><col style="width:70px"><col style="width:66px"><col style="width:66px"><col style="width:66px"></colgroup>`+"\n"+
+		`<tr style="height:20.5pt"><td style="font-family:&#39;Arial&#39;;font-size:10pt;color:#ff0000;font-weight:bold;font-style:italic;background-color:#ffff00;border-bottom:3px double #000;border-left:1px solid #000;text-align:center">Name</td><td>Joined</td><td>Total</td><td>Member</td></tr>`+"\n"+
+		`<tr><td>&lt;Ann&gt;<br>Smith</td><td style="text-align:right">2-Jan-15</td><td style="text-align:right">1234.50</td><td>TRUE</td></tr>`+"\n"+
+		`<tr><td colspan="2">Merged</td><td></td><td></td></tr>`+"\n"+
+		`</table>`+"\n")
+
+	// Styles that don't apply their fonts, fills and borders leave
+	// them out.
+	style.ApplyFont, style.ApplyFill, style.ApplyBorder = false, false, false
+	c.Assert(cellCSS(sheet.Rows[0].Cells[0]), DeepEquals, []string{"text-align:center"})
+}
+
+func (s *HTMLSuite) TestWriteHTMLHidden(c *C) {
+	sheet := makeExportSheet()
+
+	var buf bytes.Buffer
+	c.Assert(sheet.WriteHTML(&buf, &HTMLOptions{Range: "A2:C4"}), IsNil)
+	c.Assert(buf.String(), Equals, `<table style="border-collapse:collapse">`+"\n"+
+		`<colgroup><col style="width:66px"><col style="width:66px"></colgroup>`+"\n"+
+		`<tr><td>Ann &#34;A&#34;, Smith</td><td style="text-align:right">2-Jan-15</td></tr>`+"\n"+
+		`<tr><td colspan="2">Merged</td></tr>`+"\n"+
+		`</table>`+"\n")
+
+	buf.Reset()
+	c.Assert(sheet.WriteHTML(&buf, &HTMLOptions{Range: "A3:B3", IncludeHidden: true}), IsNil)
+	c.Assert(buf.String(), Equals, `<table style="border-collapse:collapse">`+"\n"+
+		`<colgroup><col style="width:66px"><col style="width:66px"></colgroup>`+"\n"+
+		`<tr style="display:none"><td>hidden</td><td style="display:none"></td></tr>`+"\n"+
+		`</table>`+"\n")
+
+	// A merged range whose top left cell is cut off is shown by its
+	// first rendered cell.
+	buf.Reset()
+	c.Assert(sheet.WriteHTML(&buf, &HTMLOptions{Range: "C4:D4"}), IsNil)
+	c.Assert(buf.String(), Equals, `<table style="border-collapse:collapse">`+"\n"+
+		`<colgroup><col style="width:66px"><col style="width:66px"></colgroup>`+"\n"+
+		`<tr><td>Merged</td><td></td></tr>`+"\n"+
+		`</table>`+"\n")
+
+	buf.Reset()
+	c.Assert(sheet.WriteHTML(&buf, &HTMLOptions{Range: "B4:D4", IncludeHidden: true}), IsNil)
+	c.Assert(buf.String(), Equals, `<table style="border-collapse:collapse">`+"\n"+
+		`<colgroup><col style="width:66px"><col style="width:66px"><col style="width:66px"></colgroup>`+"\n"+
+		`<tr><td colspan="2" style="display:none">Merged</td><td></td></tr>`+"\n"+
+		`</table>`+"\n")
+
+	c.Assert(sheet.WriteHTML(&buf, &HTMLOptions{Range: "A0"}), ErrorMatches, `invalid range "A0"`)
+}
+
+func (s *HTMLSuite) TestWriteHTMLClippedMerge(c *C) {
+	sheet := NewFile().AddSheet("Clipped")
+	for y := 0; y < 3; y++ {
+		row := sheet.AddRow()
+		for x := 0; x < 3; x++ {
+			row.AddCell()
+		}
+	}
+	sheet.Rows[0].Cells[0].SetString("Merged")
+	sheet.Rows[2].Cells[2].SetString("C3")
+	sheet.unmodelled = &xlsxWorksheet{MergeCells: &xlsxRawXML{InnerXML: `<mergeCell ref="A1:B2"/>`}}
+
+	var buf bytes.Buffer
+	c.Assert(sheet.WriteHTML(&buf, &HTMLOptions{Range: "B2:C3"}), IsNil)
+	c.Assert(buf.String(), Equals, `<table style="border-collapse:collapse">`+"\n"+
+		`<colgroup><col style="width:66px"><col style="width:66px"></colgroup>`+"\n"+
+		`<tr><td>Merged</td><td></td></tr>`+"\n"+
+		`<tr><td></td><td>C3</td></tr>`+"\n"+
+		`</table>`+"\n")
+
+	buf.Reset()
+	c.Assert(sheet.WriteHTML(&buf, &HTMLOptions{Range: "A2:C3"}), IsNil)
+	c.Assert(buf.String(), Equals, `<table style="border-collapse:collapse">`+"\n"+
+		`<colgroup><col style="width:66px"><col style="width:66px"><col style="width:66px"></colgroup>`+"\n"+
+		`<tr><td colspan="2">Merged</td><td></td></tr>`+"\n"+
+		`<tr><td></td><td></td><td>C3</td></tr>`+"\n"+
+		`</table>`+"\n")
+}
+
+func (s *HTMLSuite) TestHTMLColor(c *C) {
+	c.Assert(htmlColor("FF00FF00"), Equals, "#00ff00")
+	c.Assert(htmlColor("123456"), Equals, "#123456")
+	c.Assert(htmlColor(""), Equals, "")
+	c.Assert(htmlColor("FFXX0000"), Equals, "")
+}
@@ -0,0 +1,310 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// JSONOptions control what WriteJSON writes of the cells of a File,
+// besides their values.
+type JSONOptions struct {
+	// Formats adds the number formats of the cells.  Dates have
+	// their formats whether or not it is set, as it is what makes
+	// them dates.
+	Formats bool
+	// Styles adds the fonts, fills, borders and alignment of the
+	// cells whose styles apply them.
+	Styles bool
+	// Formulas adds the formulas of the cells.  Without it, the
+	// values that the formulas last gave are written.
+	Formulas bool
+}
+
+// jsonFile, jsonSheet, jsonCell and jsonStyle are the JSON forms of a
+// File and what it holds.  A cell is written as its value alone, as a
+// string, number, boolean or null, unless it has a format, formula or
+// style, when it is written as a jsonCell.  Empty cells are null, and
+// numbers without a format are in the "general" one.  A date is a
+// jsonCell of the "date" type, whose value is a string in the form of
+// time.RFC3339Nano.
+type jsonFile struct {
+	Date1904 bool        `json:"date1904,omitempty"`
+	Sheets   []jsonSheet `json:"sheets"`
+}
+
+// jsonTypeDate is the type of a jsonCell that holds a date.
+const jsonTypeDate = "date"
+
+type jsonSheet struct {
+	Name   string              `json:"name"`
+	Hidden bool                `json:"hidden,omitempty"`
+	Rows   [][]json.RawMessage `json:"rows"`
+}
+
+type jsonCell struct {
+	Value   json.RawMessage `json:"value"`
+	Type    string          `json:"type,omitempty"`
+	Format  string          `json:"format,omitempty"`
+	Formula string          `json:"formula,omitempty"`
+	Style   *jsonStyle      `json:"style,omitempty"`
+}
+
+type jsonStyle struct {
+	Font       *jsonFont   `json:"font,omitempty"`
+	Fill       *jsonFill   `json:"fill,omitempty"`
+	Border     *jsonBorder `json:"border,omitempty"`
+	Horizontal string      `json:"horizontal,omitempty"`
+}
+
+type jsonFont struct {
+	Name      string `json:"name,omitempty"`
+	Size      int    `json:"size,omitempty"`
+	Color     string `json:"color,omitempty"`
+	Bold      bool   `json:"bold,omitempty"`
+	Italic    bool   `json:"italic,omitempty"`
+	Underline bool   `json:"underline,omitempty"`
+}
+
+type jsonFill struct {
+	Pattern string `json:"pattern,omitempty"`
+	FgColor string `json:"fgColor,omitempty"`
+	BgColor string `json:"bgColor,omitempty"`
+}
+
+type jsonBorder struct {
+	Left   string `json:"left,omitempty"`
+	Right  string `json:"right,omitempty"`
+	Top    string `json:"top,omitempty"`
+	Bottom string `json:"bottom,omitempty"`
+}
+
+// MarshalJSON returns the File as JSON, as WriteJSON writes it with
+// all of the JSONOptions set.
+func (f *File) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.WriteJSON(&buf, &JSONOptions{Formats: true, Styles: true, Formulas: true}); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// UnmarshalJSON replaces the File with the one that data, in the form
+// that WriteJSON writes, describes.
+func (f *File) UnmarshalJSON(data []byte) error {
+	file, err := ReadJSON(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	*f = *file
+	for _, sheet := range f.Sheets {
+		sheet.File = f
+	}
+	return nil
+}
+
+// WriteJSON writes the sheets of the File to w as JSON, in the form
+//
+//    {"sheets": [{"name": "Sheet1", "rows": [["Name", 42, true, null]]}]}
+//
+// with each row a list of the values of its cells, and "date1904":
+// true first if the dates of the File count from 1904.  opts, which
+// may be nil, say what else is written.  ReadJSON reads it back.
+func (f *File) WriteJSON(w io.Writer, opts *JSONOptions) error {
+	options := JSONOptions{}
+	if opts != nil {
+		options = *opts
+	}
+	file := jsonFile{Date1904: f.Date1904, Sheets: []jsonSheet{}}
+	for _, sheet := range f.Sheets {
+		jSheet := jsonSheet{Name: sheet.Name, Hidden: sheet.Hidden, Rows: [][]json.RawMessage{}}
+		for _, row := range sheet.Rows {
+			jRow := []json.RawMessage{}
+			if row != nil {
+				for _, cell := range row.Cells {
+					jCell, err := options.cellJSON(cell)
+					if err != nil {
+						return err
+					}
+					jRow = append(jRow, jCell)
+				}
+			}
+			jSheet.Rows = append(jSheet.Rows, jRow)
+		}
+		file.Sheets = append(file.Sheets, jSheet)
+	}
+	return json.NewEncoder(w).Encode(file)
+}
+
+// cellJSON returns the JSON form of a cell.
+func (o *JSONOptions) cellJSON(cell *Cell) (json.RawMessage, error) {
+	var value interface{}
+	if cell != nil && (cell.Type() != CellTypeString || cell.Value != "") {
+		value = cell.TypedValue()
+	}
+	jCell := jsonCell{}
+	switch v := value.(type) {
+	case time.Time:
+		// Dates are kept to the millisecond, as they are by
+		// Excel, which rounds off the error of their serial
+		// numbers.
+		value = v.Round(time.Millisecond).Format(time.RFC3339Nano)
+		jCell.Type = jsonTypeDate
+		jCell.Format = cell.numFmt
+	case ErrorValue:
+		value = string(v)
+	}
+	var err error
+	if jCell.Value, err = json.Marshal(value); err != nil {
+		return nil, err
+	}
+	if cell == nil {
+		return jCell.Value, nil
+	}
+	if o.Formats && value != nil && cell.numFmt != "" && cell.numFmt != "general" {
+		jCell.Format = cell.numFmt
+	}
+	if o.Formulas {
+		jCell.Formula = cell.formula
+	}
+	if o.Styles {
+		jCell.Style = makeJSONStyle(cell.GetStyle())
+	}
+	if jCell.Type == "" && jCell.Format == "" && jCell.Formula == "" && jCell.Style == nil {
+		return jCell.Value, nil
+	}
+	return json.Marshal(jCell)
+}
+
+// makeJSONStyle returns the JSON form of the parts of style that it
+// applies, or nil if it doesn't apply any.
+func makeJSONStyle(style *Style) *jsonStyle {
+	if style == nil {
+		return nil
+	}
+	jStyle := &jsonStyle{Horizontal: style.Alignment.Horizontal}
+	if style.ApplyFont {
+		font := style.Font
+		jStyle.Font = &jsonFont{font.Name, font.Size, font.Color, font.Bold, font.Italic, font.Underline}
+	}
+	if style.ApplyFill {
+		jStyle.Fill = &jsonFill{style.Fill.PatternType, style.Fill.FgColor, style.Fill.BgColor}
+	}
+	if style.ApplyBorder {
+		border := style.Border
+		jStyle.Border = &jsonBorder{border.Left, border.Right, border.Top, border.Bottom}
+	}
+	if jStyle.Font == nil && jStyle.Fill == nil && jStyle.Border == nil && jStyle.Horizontal == "" {
+		return nil
+	}
+	return jStyle
+}
+
+// style returns the Style that the JSON form of a style describes.
+func (jStyle *jsonStyle) style() *Style {
+	style := NewStyle()
+	if font := jStyle.Font; font != nil {
+		style.Font = Font{Name: font.Name, Size: font.Size, Color: font.Color,
+			Bold: font.Bold, Italic: font.Italic, Underline: font.Underline}
+		style.ApplyFont = true
+	}
+	if fill := jStyle.Fill; fill != nil {
+		style.Fill = Fill{PatternType: fill.Pattern, FgColor: fill.FgColor, BgColor: fill.BgColor}
+		style.ApplyFill = true
+	}
+	if border := jStyle.Border; border != nil {
+		style.Border = Border{Left: border.Left, Right: border.Right, Top: border.Top, Bottom: border.Bottom}
+		style.ApplyBorder = true
+	}
+	style.Alignment.Horizontal = jStyle.Horizontal
+	return style
+}
+
+// ReadJSON reads a File, in the form that WriteJSON writes, from r.
+func ReadJSON(r io.Reader) (*File, error) {
+	var jFile jsonFile
+	if err := json.NewDecoder(r).Decode(&jFile); err != nil {
+		return nil, err
+	}
+	f := NewFile()
+	f.Date1904 = jFile.Date1904
+	for _, jSheet := range jFile.Sheets {
+		if jSheet.Name == "" {
+			return nil, fmt.Errorf("a sheet has no name")
+		}
+		if _, exists := f.Sheet[jSheet.Name]; exists {
+			return nil, fmt.Errorf("there are two sheets called %q", jSheet.Name)
+		}
+		sheet := f.AddSheet(jSheet.Name)
+		sheet.Hidden = jSheet.Hidden
+		for y, jRow := range jSheet.Rows {
+			row := sheet.AddRow()
+			for x, jCell := range jRow {
+				if err := setJSONCell(row.AddCell(), jCell); err != nil {
+					return nil, fmt.Errorf("sheet %q, cell %s: %s", sheet.Name, getCellIDStringFromCoords(x, y), err)
+				}
+			}
+		}
+	}
+	return f, nil
+}
+
+// setJSONCell sets cell to what the JSON form of a cell describes.
+func setJSONCell(cell *Cell, data json.RawMessage) error {
+	jCell := jsonCell{Value: data}
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '{' {
+		jCell = jsonCell{}
+		if err := json.Unmarshal(data, &jCell); err != nil {
+			return err
+		}
+	}
+	var value interface{}
+	if len(jCell.Value) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(jCell.Value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+	}
+	if _, ok := value.(string); jCell.Type != "" && (jCell.Type != jsonTypeDate || !ok) {
+		return fmt.Errorf("%s isn't a value of the %q type", jCell.Value, jCell.Type)
+	}
+	switch v := value.(type) {
+	case nil:
+	case bool:
+		cell.SetBool(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			cell.SetInt64(n)
+			cell.numFmt = "general"
+		} else if n, err := v.Float64(); err == nil {
+			cell.setGeneralFloat(n)
+		} else {
+			return err
+		}
+	case string:
+		if jCell.Type == jsonTypeDate {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return fmt.Errorf("the date %q isn't in the form %s", v, time.RFC3339Nano)
+			}
+			cell.SetDateTime(t)
+		} else {
+			cell.SetString(v)
+		}
+	default:
+		return fmt.Errorf("%s isn't the value of a cell", jCell.Value)
+	}
+	if jCell.Format != "" {
+		cell.numFmt = jCell.Format
+	}
+	if jCell.Formula != "" {
+		cell.SetFormula(jCell.Formula)
+	}
+	if jCell.Style != nil {
+		cell.SetStyle(jCell.Style.style())
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type JSONSuite struct{}
+
+var _ = Suite(&JSONSuite{})
+
+// makeJSONFile returns a File with cells of each type, and one with a
+// formula and one with a style.
+func makeJSONFile() *File {
+	f := NewFile()
+	sheet := f.AddSheet("Data")
+	row := sheet.AddRow()
+	row.AddCell().SetString("Name")
+	row.AddCell().SetInt(42)
+	row.AddCell().SetFloatWithFormat(1.25, "0.00")
+	row.AddCell().SetBool(true)
+	row.AddCell()
+	row.AddCell().SetDateTimeWithFormat(time.Date(2015, 1, 2, 12, 30, 0, 0, time.UTC), "yyyy-mm-dd hh:mm:ss")
+	sheet.AddRow()
+	row = sheet.AddRow()
+	cell := row.AddCell()
+	cell.SetFormula("B1*2")
+	cell.Value = "84"
+	style := NewStyle()
+	style.Font = Font{Name: "Arial", Size: 10, Bold: true}
+	style.ApplyFont = true
+	style.Alignment.Horizontal = "center"
+	cell = row.AddCell()
+	cell.SetString("styled")
+	cell.SetStyle(style)
+	f.AddSheet("Hidden").Hidden = true
+	return f
+}
+
+func (s *JSONSuite) TestWriteJSON(c *C) {
+	f := makeJSONFile()
+	var buf bytes.Buffer
+	c.Assert(f.WriteJSON(&buf, nil), IsNil)
+	c.Assert(buf.String(), Equals, `{"sheets":[{"name":"Data","rows":[`+
+		`["Name",42,1.25,true,null,{"value":"2015-01-02T12:30:00Z","type":"date","format":"yyyy-mm-dd hh:mm:ss"}],`+
+		`[],`+
+		`[84,"styled"]]},`+
+		`{"name":"Hidden","hidden":true,"rows":[]}]}`+"\n")
+
+	buf.Reset()
+	c.Assert(f.WriteJSON(&buf, &JSONOptions{Formats: true}), IsNil)
+	c.Assert(strings.Contains(buf.String(), `["Name",{"value":42,"format":"0"},{"value":1.25,"format":"0.00"},true,null,`), Equals, true)
+
+	data, err := json.Marshal(f)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(data), `[{"value":84,"formula":"B1*2"},`+
+		`{"value":"styled","style":{"font":{"name":"Arial","size":10,"bold":true},"horizontal":"center"}}]`), Equals, true)
+}
+
+// What WriteJSON writes, ReadJSON reads back.
+func (s *JSONSuite) TestReadJSON(c *C) {
+	var buf bytes.Buffer
+	c.Assert(makeJSONFile().WriteJSON(&buf, &JSONOptions{Formats: true, Styles: true, Formulas: true}), IsNil)
+	f, err := ReadJSON(&buf)
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets, HasLen, 2)
+	c.Assert(f.Sheets[1].Name, Equals, "Hidden")
+	c.Assert(f.Sheets[1].Hidden, Equals, true)
+
+	sheet := f.Sheet["Data"]
+	c.Assert(sheet.Rows, HasLen, 3)
+	var values []interface{}
+	for _, cell := range sheet.Rows[0].Cells {
+		values = append(values, cell.TypedValue())
+	}
+	c.Assert(values[:5], DeepEquals, []interface{}{"Name", int64(42), 1.25, true, ""})
+	c.Assert(values[5].(time.Time).Round(time.Millisecond), Equals, time.Date(2015, 1, 2, 12, 30, 0, 0, time.UTC))
+	c.Assert(sheet.Rows[0].Cells[2].FormattedValue(), Equals, "1.25")
+	c.Assert(sheet.Rows[0].Cells[5].FormattedValue(), Equals, "2015-01-02 12:30:00")
+	c.Assert(sheet.Rows[1].Cells, HasLen, 0)
+	c.Assert(sheet.Rows[2].Cells[0].Formula(), Equals, "B1*2")
+	c.Assert(sheet.Rows[2].Cells[0].Value, Equals, "84")
+	style := sheet.Rows[2].Cells[1].GetStyle()
+	c.Assert(style.ApplyFont, Equals, true)
+	c.Assert(style.Font.Name, Equals, "Arial")
+	c.Assert(style.Font.Bold, Equals, true)
+	c.Assert(style.Alignment.Horizontal, Equals, "center")
+	c.Assert(style.ApplyFill, Equals, false)
+
+	// Numbers that aren't whole are kept as they are.
+	f, err = ReadJSON(strings.NewReader(`{"sheets":[{"name":"S","rows":[[0.1, 1e300]]}]}`))
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[0].Rows[0].Cells[0].Value, Equals, "0.1")
+	c.Assert(f.Sheets[0].Rows[0].Cells[1].TypedValue(), Equals, 1e300)
+}
+
+// Text in a cell with a date format is read back as text, and hidden
+// sheets stay hidden when the File is written as xlsx.
+func (s *JSONSuite) TestReadJSONTextWithDateFormat(c *C) {
+	f := NewFile()
+	cell := f.AddSheet("S").AddRow().AddCell()
+	cell.SetString("N/A")
+	cell.numFmt = "yyyy-mm-dd"
+	f.AddSheet("Hidden").Hidden = true
+	var buf bytes.Buffer
+	c.Assert(f.WriteJSON(&buf, &JSONOptions{Formats: true}), IsNil)
+	c.Assert(buf.String(), Equals, `{"sheets":[{"name":"S","rows":[[{"value":"N/A","format":"yyyy-mm-dd"}]]},`+
+		`{"name":"Hidden","hidden":true,"rows":[]}]}`+"\n")
+
+	f, err := ReadJSON(&buf)
+	c.Assert(err, IsNil)
+	cell = f.Sheets[0].Cell(0, 0)
+	c.Assert(cell.Type(), Equals, CellTypeString)
+	c.Assert(cell.Value, Equals, "N/A")
+	c.Assert(cell.GetNumberFormat(), Equals, "yyyy-mm-dd")
+
+	buf.Reset()
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f, err = ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	c.Assert(f.Sheets[0].Hidden, Equals, false)
+	c.Assert(f.Sheets[1].Hidden, Equals, true)
+	c.Assert(f.Sheets[0].Cell(0, 0).Value, Equals, "N/A")
+}
+
+// A File whose dates count from 1904 keeps its date system, and its
+// dates, through JSON.
+func (s *JSONSuite) TestJSONDate1904(c *C) {
+	f := NewFile()
+	f.Date1904 = true
+	when := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.AddSheet("S").AddRow().AddCell().SetDateTimeWithFormat(when, "yyyy-mm-dd")
+	var buf bytes.Buffer
+	c.Assert(f.WriteJSON(&buf, nil), IsNil)
+	c.Assert(buf.String(), Equals, `{"date1904":true,"sheets":[{"name":"S","rows":[[{"value":"2020-01-01T00:00:00Z","type":"date","format":"yyyy-mm-dd"}]]}]}`+"\n")
+
+	f, err := ReadJSON(&buf)
+	c.Assert(err, IsNil)
+	c.Assert(f.Date1904, Equals, true)
+	cell := f.Sheets[0].Cell(0, 0)
+	c.Assert(cell.Value, Equals, "42369")
+	c.Assert(cell.FormattedValue(), Equals, "2020-01-01")
+
+	data, err := json.Marshal(f)
+	c.Assert(err, IsNil)
+	var f2 File
+	c.Assert(json.Unmarshal(data, &f2), IsNil)
+	c.Assert(f2.Date1904, Equals, true)
+	c.Assert(f2.Sheets[0].Cell(0, 0).Value, Equals, "42369")
+}
+
+func (s *JSONSuite) TestUnmarshalJSON(c *C) {
+	data, err := json.Marshal(makeJSONFile())
+	c.Assert(err, IsNil)
+	var f File
+	c.Assert(json.Unmarshal(data, &f), IsNil)
+	c.Assert(f.Sheet["Data"].File, Equals, &f)
+	again, err := json.Marshal(&f)
+	c.Assert(err, IsNil)
+	c.Assert(string(again), Equals, string(data))
+}
+
+func (s *JSONSuite) TestReadJSONErrors(c *C) {
+	for _, test := range []struct {
+		json, err string
+	}{
+		{`{"sheets":[{"rows":[]}]}`, `a sheet has no name`},
+		{`{"sheets":[{"name":"S"},{"name":"S"}]}`, `there are two sheets called "S"`},
+		{`{"sheets":[{"name":"S","rows":[[1,[2]]]}]}`, `sheet "S", cell B1: \[2\] isn't the value of a cell`},
+		{`{"sheets":[{"name":"S","rows":[[],[{"value":"soon","type":"date","format":"d-mmm-yy"}]]}]}`, `sheet "S", cell A2: the date "soon" isn't in the form .*`},
+		{`{"sheets":[{"name":"S","rows":[[{"value":42,"type":"date"}]]}]}`, `sheet "S", cell A1: 42 isn't a value of the "date" type`},
+		{`{"sheets":[{"name":"S","rows":[[{"value":"x","type":"time"}]]}]}`, `sheet "S", cell A1: "x" isn't a value of the "time" type`},
+		{`{"sheets":`, `unexpected EOF`},
+	} {
+		_, err := ReadJSON(strings.NewReader(test.json))
+		c.Assert(err, ErrorMatches, test.err)
+	}
+}
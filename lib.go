@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -89,12 +91,12 @@ func formatColumnName(colId []int) string {
 			// range 0-25, all other numbers are 1-26,
 			// hence we use a differente offset for the
 			// last part.
-			result += string(part + 65)
+			result += string(rune(part + 65))
 		} else {
 			// Don't output leading 0s, as there is no
 			// representation of 0 in this format.
 			if part > 0 {
-				result += string(part + 64)
+				result += string(rune(part + 64))
 			}
 		}
 	}
@@ -378,6 +380,16 @@ func formulaForCell(rawcell xlsxC, sharedFormulas map[int]sharedFormula) string
 // general enough - we should support retaining tabs and newlines.
 func fillCellData(rawcell xlsxC, reftable *RefTable, sharedFormulas map[int]sharedFormula, cell *Cell) {
 	var data string = rawcell.V
+	if rawcell.T == "inlineStr" {
+		// Inline strings keep their value in the is
+		// element, rather than v.
+		if rawcell.Is != nil {
+			cell.Value = rawcell.Is.String()
+			cell.richText = makeRichText(rawcell.Is)
+		}
+		cell.cellType = CellTypeInline
+		return
+	}
 	if len(data) > 0 {
 		vval := strings.Trim(data, " \t\n\r")
 		switch rawcell.T {
@@ -387,6 +399,7 @@ func fillCellData(rawcell xlsxC, reftable *RefTable, sharedFormulas map[int]shar
 				panic(error)
 			}
 			cell.Value = reftable.ResolveSharedString(ref)
+			cell.richText = reftable.ResolveSharedRichText(ref)
 			cell.cellType = CellTypeString
 		case "b": // Boolean
 			cell.Value = vval
@@ -485,6 +498,8 @@ func readRowsFromSheet(Worksheet *xlsxWorksheet, file *File) ([]*Row, []*Col, in
 		}
 
 		row.Hidden = rawrow.Hidden
+		row.Height = rawrow.Ht
+		row.CustomHeight = rawrow.CustomHeight
 
 		insertColIndex = minCol
 		for _, rawcell := range rawrow.C {
@@ -560,7 +575,32 @@ func readSheetFromFile(sc chan *indexedSheet, index int, rsheet xlsxSheet, fi *F
 	sheet.File = fi
 	sheet.Rows, sheet.Cols, sheet.MaxCol, sheet.MaxRow = readRowsFromSheet(worksheet, fi)
 	sheet.Hidden = rsheet.State == sheetStateHidden || rsheet.State == sheetStateVeryHidden
+	sheet.veryHidden = rsheet.State == sheetStateVeryHidden
 	sheet.SheetViews = readSheetViews(worksheet.SheetViews)
+	sheet.PageSetup = readPageSetup(worksheet)
+	sheet.PageMargins = readPageMargins(worksheet)
+	sheet.HeaderFooter = readHeaderFooter(worksheet.HeaderFooter)
+	sheet.rowBreaks, sheet.otherRowBreaks = readPageBreaks(worksheet.RowBreaks)
+	sheet.colBreaks, sheet.otherColBreaks = readPageBreaks(worksheet.ColBreaks)
+	sheet.protection = worksheet.SheetProtection
+	worksheet.flattenRawAttrs()
+	sheet.unmodelled = new(xlsxWorksheet)
+	sheet.unmodelled.copyUnmodelled(worksheet)
+	sheet.sparklineGroups, error = readSparklineGroups(worksheet.ExtLst, fi.styles)
+	if error != nil {
+		result.Error = error
+		sc <- result
+		return
+	}
+	sheet.retainedSparklineGroups = len(sheet.sparklineGroups)
+	if rels, ok := fi.worksheetRels[worksheetNameFromSheet(rsheet, sheetXMLMap)]; ok {
+		sheet.rels, error = readPart(rels)
+		if error != nil {
+			result.Error = error
+			sc <- result
+			return
+		}
+	}
 	result.Sheet = sheet
 	sc <- result
 }
@@ -585,6 +625,10 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 		return nil, nil, err
 	}
 	file.Date1904 = workbook.WorkbookPr.Date1904
+	file.workbookProtection = workbook.WorkbookProtection
+	workbook.flattenRawAttrs()
+	file.unmodelled = new(xlsxWorkbook)
+	file.unmodelled.copyUnmodelled(workbook)
 	sheetCount = len(workbook.Sheets.Sheet)
 	sheetsByName := make(map[string]*Sheet, sheetCount)
 	sheets := make([]*Sheet, sheetCount)
@@ -615,6 +659,7 @@ func readSheetsFromZipFile(f *zip.File, file *File, sheetXMLMap map[string]strin
 		sheet.Sheet.Name = sheetName
 		sheets[sheet.Index] = sheet.Sheet
 	}
+	file.readDefinedNames(workbook.DefinedNames.DefinedName, sheets)
 	return sheetsByName, sheets, nil
 }
 
@@ -667,13 +712,14 @@ func readStylesFromZipFile(f *zip.File, theme *theme) (*xlsxStyleSheet, error) {
 		return nil, error
 	}
 	buildNumFmtRefTable(style)
+	style.keepOriginal()
 	return style, nil
 }
 
 func buildNumFmtRefTable(style *xlsxStyleSheet) {
+	style.numFmtRefTable = make(map[int]xlsxNumFmt, len(style.NumFmts.NumFmt))
 	for _, numFmt := range style.NumFmts.NumFmt {
-		// We do this for the side effect of populating the NumFmtRefTable.
-		style.addNumFmt(numFmt)
+		style.numFmtRefTable[numFmt.NumFmtId] = numFmt
 	}
 }
 
@@ -695,64 +741,107 @@ func readThemeFromZipFile(f *zip.File) (*theme, error) {
 type WorkBookRels map[string]string
 
 func (w *WorkBookRels) MakeXLSXWorkbookRels() xlsxWorkbookRels {
-	relCount := len(*w)
-	xWorkbookRels := xlsxWorkbookRels{}
-	xWorkbookRels.Relationships = make([]xlsxWorkbookRelation, relCount+3)
-	for k, v := range *w {
+	return w.makeXLSXWorkbookRels(nil)
+}
+
+// makeXLSXWorkbookRels does the work of MakeXLSXWorkbookRels, making
+// sure that the relationships it adds for the shared strings, theme
+// and styles don't take an id amongst reserved.
+func (w *WorkBookRels) makeXLSXWorkbookRels(reserved map[string]bool) xlsxWorkbookRels {
+	used := make(map[string]bool, len(reserved)+len(*w))
+	for k := range reserved {
+		used[k] = true
+	}
+	indices := make([]int, 0, len(*w))
+	for k := range *w {
 		index, err := strconv.Atoi(k[3:])
 		if err != nil {
 			panic(err.Error())
 		}
-		xWorkbookRels.Relationships[index-1] = xlsxWorkbookRelation{
-			Id:     k,
-			Target: v,
-			Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"}
+		indices = append(indices, index)
+		used[k] = true
 	}
+	sort.Ints(indices)
 
-	relCount++
-	sheetId := fmt.Sprintf("rId%d", relCount)
-	xWorkbookRels.Relationships[relCount-1] = xlsxWorkbookRelation{
-		Id:     sheetId,
+	xWorkbookRels := xlsxWorkbookRels{}
+	xWorkbookRels.Relationships = make([]xlsxWorkbookRelation, 0, len(indices)+3)
+	for _, index := range indices {
+		id := fmt.Sprintf("rId%d", index)
+		xWorkbookRels.Relationships = append(xWorkbookRels.Relationships, xlsxWorkbookRelation{
+			Id:     id,
+			Target: (*w)[id],
+			Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet"})
+	}
+
+	relCount := 0
+	xWorkbookRels.Relationships = append(xWorkbookRels.Relationships, xlsxWorkbookRelation{
+		Id:     nextFreeRelId(&relCount, used),
 		Target: "sharedStrings.xml",
-		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"}
+		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings"})
 
-	relCount++
-	sheetId = fmt.Sprintf("rId%d", relCount)
-	xWorkbookRels.Relationships[relCount-1] = xlsxWorkbookRelation{
-		Id:     sheetId,
+	xWorkbookRels.Relationships = append(xWorkbookRels.Relationships, xlsxWorkbookRelation{
+		Id:     nextFreeRelId(&relCount, used),
 		Target: "theme/theme1.xml",
-		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"}
+		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"})
 
-	relCount++
-	sheetId = fmt.Sprintf("rId%d", relCount)
-	xWorkbookRels.Relationships[relCount-1] = xlsxWorkbookRelation{
-		Id:     sheetId,
+	xWorkbookRels.Relationships = append(xWorkbookRels.Relationships, xlsxWorkbookRelation{
+		Id:     nextFreeRelId(&relCount, used),
 		Target: "styles.xml",
-		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"}
+		Type:   "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"})
 
 	return xWorkbookRels
 }
 
+// nextFreeRelId returns the first relationship id of the form rIdN,
+// counting up from just past *n, that isn't already used.  The id is
+// marked as used and *n is left at its N.
+func nextFreeRelId(n *int, used map[string]bool) string {
+	for {
+		*n++
+		id := fmt.Sprintf("rId%d", *n)
+		if !used[id] {
+			used[id] = true
+			return id
+		}
+	}
+}
+
+// regeneratedRelTypes are the types of workbook relationship that we
+// write afresh, rather than retain, when a File that has been read is
+// written back out.  The calculation chain is dropped altogether, as
+// it would otherwise go stale when cells are edited.
+var regeneratedRelTypes = map[string]bool{
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet":     true,
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships/sharedStrings": true,
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles":        true,
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme":         true,
+	"http://schemas.openxmlformats.org/officeDocument/2006/relationships/calcChain":     true,
+}
+
 // readWorkbookRelationsFromZipFile is an internal helper function to
 // extract a map of relationship ID strings to the name of the
 // worksheet.xml file they refer to.  The resulting map can be used to
-// reliably derefence the worksheets in the XLSX file.
-func readWorkbookRelationsFromZipFile(workbookRels *zip.File) (WorkBookRels, error) {
+// reliably derefence the worksheets in the XLSX file.  The
+// relationships to parts that we don't regenerate are returned too,
+// so that they can be written back out.
+func readWorkbookRelationsFromZipFile(workbookRels *zip.File) (WorkBookRels, []xlsxWorkbookRelation, error) {
 	var sheetXMLMap WorkBookRels
 	var wbRelationships *xlsxWorkbookRels
 	var rc io.ReadCloser
 	var decoder *xml.Decoder
 	var err error
 
+	var retained []xlsxWorkbookRelation
+
 	rc, err = workbookRels.Open()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	decoder = xml.NewDecoder(rc)
 	wbRelationships = new(xlsxWorkbookRels)
 	err = decoder.Decode(wbRelationships)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	sheetXMLMap = make(WorkBookRels)
 	for _, rel := range wbRelationships.Relationships {
@@ -760,8 +849,42 @@ func readWorkbookRelationsFromZipFile(workbookRels *zip.File) (WorkBookRels, err
 			_, filename := path.Split(rel.Target)
 			sheetXMLMap[rel.Id] = strings.Replace(filename, ".xml", "", 1)
 		}
+		if !regeneratedRelTypes[rel.Type] {
+			retained = append(retained, rel)
+		}
+	}
+	return sheetXMLMap, retained, nil
+}
+
+// readContentTypesFromZipFile is an internal helper function to
+// extract the content types declared by the XLSX file.
+func readContentTypesFromZipFile(f *zip.File) (*xlsxTypes, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
 	}
-	return sheetXMLMap, nil
+	defer rc.Close()
+	types := new(xlsxTypes)
+	err = xml.NewDecoder(rc).Decode(types)
+	if err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+// readPart is an internal helper function that returns the contents
+// of a part of the XLSX file unchanged.
+func readPart(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	content, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
 }
 
 // ReadZip() takes a pointer to a zip.ReadCloser and returns a
@@ -789,10 +912,15 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 	var workbook *zip.File
 	var workbookRels *zip.File
 	var worksheets map[string]*zip.File
+	var worksheetRels map[string]*zip.File
+	var contentTypes *zip.File
+	var retainedParts map[string]string
 
 	file = NewFile()
 	// file.numFmtRefTable = make(map[int]xlsxNumFmt, 1)
 	worksheets = make(map[string]*zip.File, len(r.File))
+	worksheetRels = make(map[string]*zip.File, len(r.File))
+	retainedParts = make(map[string]string)
 	for _, v = range r.File {
 		switch v.Name {
 		case "xl/sharedStrings.xml":
@@ -805,19 +933,40 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 			styles = v
 		case "xl/theme/theme1.xml":
 			themeFile = v
+		case "[Content_Types].xml":
+			contentTypes = v
+		case "xl/calcChain.xml":
+			// Dropped, see regeneratedRelTypes.
 		default:
-			if len(v.Name) > 14 {
-				if v.Name[0:13] == "xl/worksheets" {
-					worksheets[v.Name[14:len(v.Name)-4]] = v
+			switch {
+			case strings.HasSuffix(v.Name, "/"):
+				// A directory entry.
+			case strings.HasPrefix(v.Name, "xl/worksheets/_rels/") && strings.HasSuffix(v.Name, ".xml.rels"):
+				worksheetRels[v.Name[20:len(v.Name)-9]] = v
+			case len(v.Name) > 14 && v.Name[0:13] == "xl/worksheets":
+				worksheets[v.Name[14:len(v.Name)-4]] = v
+			default:
+				// Anything else we don't understand is kept,
+				// as is, to be written back out.
+				retainedParts[v.Name], err = readPart(v)
+				if err != nil {
+					return nil, err
 				}
 			}
 		}
 	}
-	sheetXMLMap, err = readWorkbookRelationsFromZipFile(workbookRels)
+	sheetXMLMap, file.workbookRels, err = readWorkbookRelationsFromZipFile(workbookRels)
 	if err != nil {
 		return nil, err
 	}
 	file.worksheets = worksheets
+	file.worksheetRels = worksheetRels
+	if contentTypes != nil {
+		file.contentTypes, err = readContentTypesFromZipFile(contentTypes)
+		if err != nil {
+			return nil, err
+		}
+	}
 	reftable, err = readSharedStringsFromZipFile(sharedStrings)
 	if err != nil {
 		return nil, err
@@ -830,6 +979,11 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 		}
 
 		file.theme = theme
+		// The theme is also written back out as it was read.
+		retainedParts[themeFile.Name], err = readPart(themeFile)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if styles != nil {
 		style, err = readStylesFromZipFile(styles, file.theme)
@@ -850,5 +1004,19 @@ func ReadZipReader(r *zip.Reader) (*File, error) {
 	}
 	file.Sheet = sheetsByName
 	file.Sheets = sheets
+	file.retainedParts = retainedParts
+	for _, sheet := range sheets {
+		file.readSheetNames = append(file.readSheetNames, sheet.Name)
+	}
+	for _, sheet := range sheets {
+		err = sheet.readDrawing(retainedParts)
+		if err != nil {
+			return nil, err
+		}
+		err = sheet.readPivotTables(retainedParts)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return file, nil
 }
@@ -321,6 +321,35 @@ func (l *LibSuite) TestReadRowsFromSheet(c *C) {
 	c.Assert(pane.YSplit, Equals, 1)
 }
 
+// Inline strings, whether plain or made of rich text runs, are read
+// from the is element of the cell.
+func (l *LibSuite) TestReadRowsFromSheetWithInlineStrings(c *C) {
+	var sheetxml = bytes.NewBufferString(`
+<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <dimension ref="A1:B1"/>
+  <sheetData>
+    <row r="1">
+      <c r="A1" t="inlineStr"><is><t>Foo</t></is></c>
+      <c r="B1" t="inlineStr"><is><r><rPr><b/></rPr><t>Bar</t></r><r><t xml:space="preserve"> Baz</t></r></is></c>
+    </row>
+  </sheetData>
+</worksheet>`)
+	worksheet := new(xlsxWorksheet)
+	err := xml.NewDecoder(sheetxml).Decode(worksheet)
+	c.Assert(err, IsNil)
+	file := new(File)
+	rows, _, maxCols, maxRows := readRowsFromSheet(worksheet, file)
+	c.Assert(maxRows, Equals, 1)
+	c.Assert(maxCols, Equals, 2)
+	cell1 := rows[0].Cells[0]
+	c.Assert(cell1.Value, Equals, "Foo")
+	c.Assert(cell1.Type(), Equals, CellTypeInline)
+	cell2 := rows[0].Cells[1]
+	c.Assert(cell2.Value, Equals, "Bar Baz")
+	c.Assert(cell2.Type(), Equals, CellTypeInline)
+}
+
 func (l *LibSuite) TestReadRowsFromSheetWithLeadingEmptyRows(c *C) {
 	var sharedstringsXML = bytes.NewBufferString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
 <sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="2" uniqueCount="2"><si><t>ABC</t></si><si><t>DEF</t></si></sst>`)
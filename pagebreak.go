@@ -0,0 +1,136 @@
+package xlsx
+
+import (
+	"fmt"
+	"sort"
+)
+
+// InsertRowBreak adds a manual page break before the row with the
+// given index, so that it starts a new printed page.  Adding a break
+// that is already there does nothing.
+func (s *Sheet) InsertRowBreak(row int) error {
+	if row <= 0 {
+		return fmt.Errorf("can't insert a page break before row %d", row)
+	}
+	s.rowBreaks = insertPageBreak(s.rowBreaks, row)
+	return nil
+}
+
+// RemoveRowBreak removes the manual page break before the row with the
+// given index.  An error is returned if there is no such break.
+func (s *Sheet) RemoveRowBreak(row int) error {
+	breaks, ok := removePageBreak(s.rowBreaks, row)
+	if !ok {
+		return fmt.Errorf("there is no page break before row %d", row)
+	}
+	s.rowBreaks = breaks
+	return nil
+}
+
+// RowBreaks returns the indexes, in ascending order, of the rows that
+// have a manual page break before them.
+func (s *Sheet) RowBreaks() []int {
+	return append([]int{}, s.rowBreaks...)
+}
+
+// InsertColBreak adds a manual page break before the column with the
+// given index, so that it starts a new printed page.  Adding a break
+// that is already there does nothing.
+func (s *Sheet) InsertColBreak(col int) error {
+	if col <= 0 {
+		return fmt.Errorf("can't insert a page break before column %d", col)
+	}
+	s.colBreaks = insertPageBreak(s.colBreaks, col)
+	return nil
+}
+
+// RemoveColBreak removes the manual page break before the column with
+// the given index.  An error is returned if there is no such break.
+func (s *Sheet) RemoveColBreak(col int) error {
+	breaks, ok := removePageBreak(s.colBreaks, col)
+	if !ok {
+		return fmt.Errorf("there is no page break before column %d", col)
+	}
+	s.colBreaks = breaks
+	return nil
+}
+
+// ColBreaks returns the indexes, in ascending order, of the columns
+// that have a manual page break before them.
+func (s *Sheet) ColBreaks() []int {
+	return append([]int{}, s.colBreaks...)
+}
+
+// insertPageBreak adds index to the sorted breaks, unless it's already
+// there.
+func insertPageBreak(breaks []int, index int) []int {
+	i := sort.SearchInts(breaks, index)
+	if i < len(breaks) && breaks[i] == index {
+		return breaks
+	}
+	breaks = append(breaks, 0)
+	copy(breaks[i+1:], breaks[i:])
+	breaks[i] = index
+	return breaks
+}
+
+// removePageBreak removes index from the sorted breaks, reporting
+// whether it was there.
+func removePageBreak(breaks []int, index int) ([]int, bool) {
+	i := sort.SearchInts(breaks, index)
+	if i == len(breaks) || breaks[i] != index {
+		return breaks, false
+	}
+	return append(breaks[:i], breaks[i+1:]...), true
+}
+
+// readPageBreaks returns the indexes of the rows or columns that the
+// manual page breaks of a worksheet that has been read come before,
+// and the other breaks, such as those put in by a pivot table, as they
+// are.  A break is recorded against the last row or column of the page
+// it ends, which, as they're numbered from 1, is the index of the
+// next.
+func readPageBreaks(xBreaks *xlsxPageBreaks) (breaks []int, others []xlsxBreak) {
+	if xBreaks == nil {
+		return nil, nil
+	}
+	for _, brk := range xBreaks.Brk {
+		if brk.Man && !brk.Pt && brk.Id > 0 {
+			breaks = insertPageBreak(breaks, brk.Id)
+		} else {
+			others = append(others, brk)
+		}
+	}
+	return breaks, others
+}
+
+// makeXLSXPageBreaks returns the xlsxPageBreaks representation of
+// manual page breaks, each of which runs across the sheet as far as
+// max, together with the other breaks that were read, or nil if there
+// are none.  A manual break takes the place of another one before the
+// same row or column.
+func makeXLSXPageBreaks(breaks []int, others []xlsxBreak, max int) *xlsxPageBreaks {
+	if len(breaks) == 0 && len(others) == 0 {
+		return nil
+	}
+	xBreaks := &xlsxPageBreaks{ManualBreakCount: len(breaks)}
+	for _, index := range breaks {
+		xBreaks.Brk = append(xBreaks.Brk, xlsxBreak{Id: index, Max: max, Man: true})
+	}
+	for _, brk := range others {
+		if i := sort.SearchInts(breaks, brk.Id); i == len(breaks) || breaks[i] != brk.Id {
+			xBreaks.Brk = append(xBreaks.Brk, brk)
+		}
+	}
+	sort.Stable(xlsxBreaksByID(xBreaks.Brk))
+	xBreaks.Count = len(xBreaks.Brk)
+	return xBreaks
+}
+
+// xlsxBreaksByID orders page breaks by the row or column they come
+// before.
+type xlsxBreaksByID []xlsxBreak
+
+func (b xlsxBreaksByID) Len() int           { return len(b) }
+func (b xlsxBreaksByID) Less(i, j int) bool { return b[i].Id < b[j].Id }
+func (b xlsxBreaksByID) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
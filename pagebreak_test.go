@@ -0,0 +1,94 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type PageBreakSuite struct{}
+
+var _ = Suite(&PageBreakSuite{})
+
+func (s *PageBreakSuite) TestInsertAndRemovePageBreaks(c *C) {
+	sheet := &Sheet{Name: "Sheet1"}
+	c.Assert(sheet.InsertRowBreak(20), IsNil)
+	c.Assert(sheet.InsertRowBreak(10), IsNil)
+	c.Assert(sheet.InsertRowBreak(10), IsNil)
+	c.Assert(sheet.InsertRowBreak(0), ErrorMatches, "can't insert a page break before row 0")
+	c.Assert(sheet.RowBreaks(), DeepEquals, []int{10, 20})
+	c.Assert(sheet.RemoveRowBreak(10), IsNil)
+	c.Assert(sheet.RemoveRowBreak(10), ErrorMatches, "there is no page break before row 10")
+	c.Assert(sheet.RowBreaks(), DeepEquals, []int{20})
+
+	c.Assert(sheet.InsertColBreak(3), IsNil)
+	c.Assert(sheet.InsertColBreak(-1), ErrorMatches, "can't insert a page break before column -1")
+	c.Assert(sheet.ColBreaks(), DeepEquals, []int{3})
+	c.Assert(sheet.RemoveColBreak(3), IsNil)
+	c.Assert(sheet.RemoveColBreak(4), ErrorMatches, "there is no page break before column 4")
+	c.Assert(sheet.ColBreaks(), HasLen, 0)
+}
+
+func (s *PageBreakSuite) TestMakeXLSXPageBreaks(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	c.Assert(sheet.InsertRowBreak(10), IsNil)
+	c.Assert(sheet.InsertColBreak(2), IsNil)
+
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	output, err := xml.Marshal(xSheet)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), `</headerFooter><rowBreaks count="1" manualBreakCount="1"><brk id="10" max="16383" man="true"></brk></rowBreaks><colBreaks count="1" manualBreakCount="1"><brk id="2" max="1048575" man="true"></brk></colBreaks>`), Equals, true)
+
+	// Without breaks neither element is written.
+	sheet = f.AddSheet("Sheet2")
+	output, err = xml.Marshal(sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil)))
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), "Breaks"), Equals, false)
+}
+
+func (s *PageBreakSuite) TestPageBreaksRoundTrip(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	c.Assert(sheet.InsertRowBreak(5), IsNil)
+	c.Assert(sheet.InsertRowBreak(12), IsNil)
+	c.Assert(sheet.InsertColBreak(4), IsNil)
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	sheet2 := f2.Sheet["Sheet1"]
+	c.Assert(sheet2.RowBreaks(), DeepEquals, []int{5, 12})
+	c.Assert(sheet2.ColBreaks(), DeepEquals, []int{4})
+}
+
+// Only manual page breaks are read as such; the others, such as those
+// of a pivot table, are written back out as they were read.
+func (s *PageBreakSuite) TestReadPageBreaksKeepsOthers(c *C) {
+	xBreaks := &xlsxPageBreaks{Count: 4, ManualBreakCount: 2, Brk: []xlsxBreak{
+		{Id: 3, Max: 16383, Man: true},
+		{Id: 7, Max: 16383},
+		{Id: 9, Max: 16383, Man: true, Pt: true},
+		{Id: 12, Min: 2, Max: 10, Man: true},
+	}}
+	breaks, others := readPageBreaks(xBreaks)
+	c.Assert(breaks, DeepEquals, []int{3, 12})
+	c.Assert(others, DeepEquals, []xlsxBreak{{Id: 7, Max: 16383}, {Id: 9, Max: 16383, Man: true, Pt: true}})
+
+	breaks = insertPageBreak(breaks, 9)
+	breaks = insertPageBreak(breaks, 5)
+	c.Assert(makeXLSXPageBreaks(breaks, others, 16383), DeepEquals, &xlsxPageBreaks{Count: 5, ManualBreakCount: 4, Brk: []xlsxBreak{
+		{Id: 3, Max: 16383, Man: true},
+		{Id: 5, Max: 16383, Man: true},
+		{Id: 7, Max: 16383},
+		{Id: 9, Max: 16383, Man: true},
+		{Id: 12, Max: 16383, Man: true},
+	}})
+	c.Assert(makeXLSXPageBreaks(nil, others, 16383).ManualBreakCount, Equals, 0)
+}
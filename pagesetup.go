@@ -0,0 +1,253 @@
+package xlsx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	printAreaName   = "_xlnm.Print_Area"
+	printTitlesName = "_xlnm.Print_Titles"
+)
+
+// PageSetup controls how a Sheet is laid out on the page when it is
+// printed.
+type PageSetup struct {
+	PaperSize   int    // For example 1 for Letter, or 9 for A4.
+	Orientation string // "portrait" or "landscape".
+	Scale       int    // As a percentage, unless FitToPage is set.
+	// When FitToPage is set the Sheet is scaled to fit FitToWidth
+	// pages across and FitToHeight pages down, where 0 means as
+	// many as are needed.
+	FitToPage   bool
+	FitToWidth  int
+	FitToHeight int
+	PageOrder   string // "downThenOver" or "overThenDown".
+}
+
+// NewPageSetup returns the PageSetup that a Sheet is written with
+// unless it is given another.
+func NewPageSetup() *PageSetup {
+	return &PageSetup{
+		PaperSize:   9,
+		Orientation: "portrait",
+		Scale:       100,
+		FitToWidth:  1,
+		FitToHeight: 1,
+		PageOrder:   "downThenOver",
+	}
+}
+
+// PageMargins are the margins of a printed Sheet, in inches.  Header
+// and Footer are the distances of the header and footer from the edge
+// of the page.
+type PageMargins struct {
+	Left   float64
+	Right  float64
+	Top    float64
+	Bottom float64
+	Header float64
+	Footer float64
+}
+
+// NewPageMargins returns the PageMargins that a Sheet is written with
+// unless it is given others.
+func NewPageMargins() *PageMargins {
+	return &PageMargins{
+		Left:   0.7875,
+		Right:  0.7875,
+		Top:    1.05277777777778,
+		Bottom: 1.05277777777778,
+		Header: 0.7875,
+		Footer: 0.7875,
+	}
+}
+
+// readPageSetup returns the PageSetup of a worksheet that has been
+// read.
+func readPageSetup(worksheet *xlsxWorksheet) *PageSetup {
+	xPageSetUp := worksheet.PageSetUp
+	pageSetup := &PageSetup{
+		Orientation: xPageSetUp.Orientation,
+		Scale:       xPageSetUp.Scale,
+		FitToWidth:  xPageSetUp.FitToWidth,
+		FitToHeight: xPageSetUp.FitToHeight,
+		PageOrder:   xPageSetUp.PageOrder,
+	}
+	pageSetup.PaperSize, _ = strconv.Atoi(xPageSetUp.PaperSize)
+	for _, pr := range worksheet.SheetPr.PageSetUpPr {
+		pageSetup.FitToPage = pageSetup.FitToPage || pr.FitToPage
+	}
+	return pageSetup
+}
+
+// readPageMargins returns the PageMargins of a worksheet that has been
+// read.
+func readPageMargins(worksheet *xlsxWorksheet) *PageMargins {
+	margins := PageMargins(worksheet.PageMargins)
+	return &margins
+}
+
+// makeXLSXPageSetup applies the page setup and margins of the Sheet,
+// where it has them, to the worksheet.
+func (s *Sheet) makeXLSXPageSetup(worksheet *xlsxWorksheet) {
+	if s.PageSetup != nil {
+		worksheet.PageSetUp.PaperSize = strconv.Itoa(s.PageSetup.PaperSize)
+		worksheet.PageSetUp.Orientation = s.PageSetup.Orientation
+		worksheet.PageSetUp.Scale = s.PageSetup.Scale
+		worksheet.PageSetUp.FitToWidth = s.PageSetup.FitToWidth
+		worksheet.PageSetUp.FitToHeight = s.PageSetup.FitToHeight
+		worksheet.PageSetUp.PageOrder = s.PageSetup.PageOrder
+		worksheet.SheetPr.PageSetUpPr = []xlsxPageSetUpPr{{FitToPage: s.PageSetup.FitToPage}}
+	}
+	if s.PageMargins != nil {
+		worksheet.PageMargins = xlsxPageMargins(*s.PageMargins)
+	}
+}
+
+// SetPrintArea limits the printing of the Sheet to a range of cells,
+// such as "A1:D20".  An empty ref clears the print area.  The Sheet
+// has to be part of a File, as the print area is kept as one of its
+// defined names.
+func (s *Sheet) SetPrintArea(ref string) error {
+	if ref == "" {
+		return s.setPrintName(printAreaName, "")
+	}
+	formula, err := s.absoluteSheetRef(ref)
+	if err != nil {
+		return err
+	}
+	return s.setPrintName(printAreaName, formula)
+}
+
+// PrintArea returns the range of cells that printing the Sheet is
+// limited to, such as "A1:D20", or "" if it has no print area.
+func (s *Sheet) PrintArea() string {
+	formula := s.printName(printAreaName)
+	if formula == "" {
+		return ""
+	}
+	_, ref, err := splitSheetRef(formula)
+	if err != nil {
+		return ""
+	}
+	return strings.Replace(ref, "$", "", -1)
+}
+
+// SetPrintTitles sets the rows, such as "1:2", and columns, such as
+// "A:A", that are repeated on every printed page of the Sheet.  Either
+// can be empty, and both being empty clears the print titles.  As with
+// SetPrintArea, the Sheet has to be part of a File.
+func (s *Sheet) SetPrintTitles(rows, cols string) error {
+	areas := []string{}
+	for _, ref := range []string{cols, rows} {
+		if ref == "" {
+			continue
+		}
+		area, err := s.absoluteSheetRef(ref)
+		if err != nil {
+			return err
+		}
+		areas = append(areas, area)
+	}
+	return s.setPrintName(printTitlesName, strings.Join(areas, ","))
+}
+
+// PrintTitles returns the rows, such as "1:2", and columns, such as
+// "A:A", that are repeated on every printed page of the Sheet.  Either
+// is empty if there are none.
+func (s *Sheet) PrintTitles() (rows, cols string) {
+	formula := s.printName(printTitlesName)
+	if formula == "" {
+		return "", ""
+	}
+	for _, area := range splitAreas(formula) {
+		_, ref, err := splitSheetRef(area)
+		if err != nil {
+			continue
+		}
+		ref = strings.Replace(ref, "$", "", -1)
+		if strings.IndexAny(ref, "0123456789") == 0 {
+			rows = ref
+		} else {
+			cols = ref
+		}
+	}
+	return rows, cols
+}
+
+// printName returns the formula of the named print setting of the
+// Sheet, or "" if it doesn't have one.
+func (s *Sheet) printName(name string) string {
+	if s.File == nil {
+		return ""
+	}
+	i := s.File.findDefinedName(name, s)
+	if i < 0 {
+		return ""
+	}
+	return s.File.definedNames[i].Formula
+}
+
+// setPrintName replaces the named print setting of the Sheet with
+// formula, or removes it if formula is empty.
+func (s *Sheet) setPrintName(name, formula string) error {
+	if s.File == nil {
+		return fmt.Errorf("sheet %q isn't part of a file", s.Name)
+	}
+	if s.File.findDefinedName(name, s) >= 0 {
+		err := s.File.RemoveDefinedName(name, s)
+		if err != nil {
+			return err
+		}
+	}
+	if formula == "" {
+		return nil
+	}
+	return s.File.AddDefinedName(&DefinedName{Name: name, Formula: formula, Sheet: s})
+}
+
+// absoluteSheetRef returns a reference to a range of cells, rows or
+// columns of the Sheet, as it is written in a defined name; "A1:D20"
+// becomes "Sheet1!$A$1:$D$20".
+func (s *Sheet) absoluteSheetRef(ref string) (string, error) {
+	parts := strings.Split(strings.Replace(ref, "$", "", -1), ":")
+	if len(parts) > 2 {
+		return "", fmt.Errorf("%q isn't a range of cells", ref)
+	}
+	for i, part := range parts {
+		letters := strings.Map(letterOnlyMapF, part)
+		digits := strings.Map(intOnlyMapF, part)
+		if part == "" || letters+digits != strings.ToUpper(part) {
+			return "", fmt.Errorf("%q isn't a range of cells", ref)
+		}
+		parts[i] = ""
+		if letters != "" {
+			parts[i] += "$" + letters
+		}
+		if digits != "" {
+			parts[i] += "$" + digits
+		}
+	}
+	return quoteSheetName(s.Name) + "!" + strings.Join(parts, ":"), nil
+}
+
+// splitAreas splits a formula made up of a number of comma separated
+// references into the individual references, allowing for commas in
+// quoted sheet names.
+func splitAreas(formula string) []string {
+	areas := []string{}
+	quoted := false
+	start := 0
+	for i, r := range formula {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			areas = append(areas, formula[start:i])
+			start = i + 1
+		}
+	}
+	return append(areas, formula[start:])
+}
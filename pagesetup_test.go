@@ -0,0 +1,120 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type PageSetupSuite struct{}
+
+var _ = Suite(&PageSetupSuite{})
+
+func (s *PageSetupSuite) TestMakeXLSXPageSetup(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	pageSetup := NewPageSetup()
+	pageSetup.PaperSize = 1
+	pageSetup.Orientation = "landscape"
+	pageSetup.FitToPage = true
+	pageSetup.FitToHeight = 0
+	sheet.PageSetup = pageSetup
+	sheet.PageMargins = &PageMargins{Left: 0.5, Right: 0.5, Top: 1, Bottom: 1, Header: 0.25, Footer: 0}
+
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	output, err := xml.Marshal(xSheet)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), `<sheetPr filterMode="false"><pageSetUpPr fitToPage="true"></pageSetUpPr></sheetPr>`), Equals, true)
+	c.Assert(strings.Contains(string(output), `<pageMargins left="0.5" right="0.5" top="1" bottom="1" header="0.25" footer="0"></pageMargins><pageSetup paperSize="1" scale="100" firstPageNumber="1" fitToWidth="1" fitToHeight="0" pageOrder="downThenOver" orientation="landscape"`), Equals, true)
+}
+
+// The page setup and margins of a worksheet are read, with anything
+// left out taking its default value.
+func (s *PageSetupSuite) TestReadPageSetup(c *C) {
+	sheetxml := bytes.NewBufferString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetPr><pageSetUpPr fitToPage="1"/></sheetPr>
+  <sheetData/>
+  <pageMargins left="0.25" right="0.25" top="0.75" bottom="0.75" header="0.3" footer="0.3"/>
+  <pageSetup paperSize="9" orientation="landscape" fitToHeight="0"/>
+</worksheet>`)
+	worksheet := new(xlsxWorksheet)
+	worksheet.setSchemaDefaults()
+	err := xml.NewDecoder(sheetxml).Decode(worksheet)
+	c.Assert(err, IsNil)
+	c.Assert(*readPageSetup(worksheet), DeepEquals, PageSetup{
+		PaperSize:   9,
+		Orientation: "landscape",
+		Scale:       100,
+		FitToPage:   true,
+		FitToWidth:  1,
+		FitToHeight: 0,
+		PageOrder:   "downThenOver",
+	})
+	c.Assert(*readPageMargins(worksheet), DeepEquals, PageMargins{Left: 0.25, Right: 0.25, Top: 0.75, Bottom: 0.75, Header: 0.3, Footer: 0.3})
+
+	// A worksheet without them gets the defaults.
+	worksheet = new(xlsxWorksheet)
+	worksheet.setSchemaDefaults()
+	err = xml.NewDecoder(strings.NewReader(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`)).Decode(worksheet)
+	c.Assert(err, IsNil)
+	c.Assert(readPageSetup(worksheet).PaperSize, Equals, 1)
+	c.Assert(readPageMargins(worksheet).Left, Equals, 0.7)
+}
+
+func (s *PageSetupSuite) TestPrintAreaAndTitles(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("My Sheet")
+	c.Assert(sheet.PrintArea(), Equals, "")
+	c.Assert(sheet.SetPrintArea("a1:D20"), IsNil)
+	c.Assert(sheet.PrintArea(), Equals, "A1:D20")
+	c.Assert(sheet.SetPrintArea("B2:C3"), IsNil)
+	c.Assert(sheet.PrintArea(), Equals, "B2:C3")
+	c.Assert(sheet.SetPrintArea("B2:C3:D4"), ErrorMatches, `"B2:C3:D4" isn't a range of cells`)
+
+	c.Assert(sheet.SetPrintTitles("1:2", "A:A"), IsNil)
+	rows, cols := sheet.PrintTitles()
+	c.Assert(rows, Equals, "1:2")
+	c.Assert(cols, Equals, "A:A")
+
+	names := f.DefinedNames()
+	c.Assert(names, HasLen, 2)
+	c.Assert(*names[0], DeepEquals, DefinedName{Name: "_xlnm.Print_Area", Formula: "'My Sheet'!$B$2:$C$3", Sheet: sheet})
+	c.Assert(*names[1], DeepEquals, DefinedName{Name: "_xlnm.Print_Titles", Formula: "'My Sheet'!$A:$A,'My Sheet'!$1:$2", Sheet: sheet})
+
+	c.Assert(sheet.SetPrintTitles("", ""), IsNil)
+	c.Assert(sheet.SetPrintArea(""), IsNil)
+	c.Assert(f.DefinedNames(), HasLen, 0)
+
+	orphan := &Sheet{Name: "Orphan"}
+	c.Assert(orphan.SetPrintArea("A1:B2"), ErrorMatches, `sheet "Orphan" isn't part of a file`)
+}
+
+// Print settings survive being written to, and read from, a file.
+func (s *PageSetupSuite) TestPrintSetupRoundTrip(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	sheet.PageSetup = NewPageSetup()
+	sheet.PageSetup.Orientation = "landscape"
+	sheet.PageMargins = NewPageMargins()
+	sheet.PageMargins.Left = 0.25
+	c.Assert(sheet.SetPrintArea("A1:F50"), IsNil)
+	c.Assert(sheet.SetPrintTitles("1:1", ""), IsNil)
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	sheet2 := f2.Sheet["Sheet1"]
+	c.Assert(*sheet2.PageSetup, DeepEquals, *sheet.PageSetup)
+	c.Assert(*sheet2.PageMargins, DeepEquals, *sheet.PageMargins)
+	c.Assert(sheet2.PrintArea(), Equals, "A1:F50")
+	rows, cols := sheet2.PrintTitles()
+	c.Assert(rows, Equals, "1:1")
+	c.Assert(cols, Equals, "")
+}
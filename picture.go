@@ -0,0 +1,153 @@
+package xlsx
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"strings"
+)
+
+// pictureContentTypes are the content types of the image formats that
+// pictures can be added in, keyed by format.
+var pictureContentTypes = map[string]string{
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+}
+
+// Picture is an image drawn on a Sheet.
+type Picture struct {
+	Cell    string // The cell, such as "B2", that the picture is placed at.
+	Format  string // "png", "jpeg" or "gif" for pictures that are added.
+	Data    []byte // The image, encoded in Format.
+	Options PictureOptions
+	// width and height are the size, in pixels, of the image
+	// before it is scaled.
+	width, height int
+}
+
+// PictureOptions control where a Picture is placed, and how big it
+// is.
+type PictureOptions struct {
+	OffsetX int     // Pixels right of the left edge of the Cell.
+	OffsetY int     // Pixels down from the top edge of the Cell.
+	ScaleX  float64 // The width is multiplied by ScaleX; 0 leaves it as it is.
+	ScaleY  float64 // The height is multiplied by ScaleY; 0 leaves it as it is.
+	// Anchor is "oneCell" (the default) for a picture that moves
+	// with the cells it is placed at, "twoCell" for one that moves
+	// and is resized with the cells it covers, or "absolute" for
+	// one that does neither.
+	Anchor      string
+	Description string // Alternative text for the picture.
+}
+
+// AddPicture draws an image, encoded in format ("png", "jpeg" or
+// "gif"), on the Sheet with its top left corner at the cell cellRef,
+// such as "B2".  options may be nil, to place the picture at the
+// cell at its natural size.
+func (s *Sheet) AddPicture(cellRef string, data []byte, format string, options *PictureOptions) error {
+	format = strings.ToLower(format)
+	if format == "jpg" {
+		format = "jpeg"
+	}
+	if _, ok := pictureContentTypes[format]; !ok {
+		return fmt.Errorf("unsupported picture format %q", format)
+	}
+	if _, _, err := getCoordsFromCellIDString(cellRef); err != nil {
+		return fmt.Errorf("invalid cell reference %q: %s", cellRef, err)
+	}
+	config, decodedFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("can't read %s picture: %s", format, err)
+	}
+	if decodedFormat != format {
+		return fmt.Errorf("picture is %s, not %s", decodedFormat, format)
+	}
+	picture := &Picture{
+		Cell:   cellRef,
+		Format: format,
+		Data:   data,
+		width:  config.Width,
+		height: config.Height}
+	if options != nil {
+		picture.Options = *options
+	}
+	switch picture.Options.Anchor {
+	case "":
+		picture.Options.Anchor = "oneCell"
+	case "oneCell", "twoCell", "absolute":
+	default:
+		return fmt.Errorf("unknown picture anchor %q", picture.Options.Anchor)
+	}
+	s.pictures = append(s.pictures, picture)
+	return nil
+}
+
+// Pictures returns the pictures drawn on the Sheet, both those read
+// from a file and those that have been added.
+func (s *Sheet) Pictures() []*Picture {
+	return append([]*Picture{}, s.pictures...)
+}
+
+// makeDrawingAnchor returns where the picture is drawn on s.
+func (p *Picture) makeDrawingAnchor(s *Sheet) (drawingAnchor, error) {
+	col, row, err := getCoordsFromCellIDString(p.Cell)
+	if err != nil {
+		return drawingAnchor{}, err
+	}
+	scaled := func(size int, scale float64) int {
+		if scale == 0 {
+			return size
+		}
+		return int(math.Round(float64(size) * scale))
+	}
+	return s.makeDrawingAnchor(p.Options.Anchor, col, row,
+		p.Options.OffsetX, p.Options.OffsetY,
+		scaled(p.width, p.Options.ScaleX), scaled(p.height, p.Options.ScaleY)), nil
+}
+
+// marshal returns the picture as an xdr:pic element, with the given
+// drawing object id, whose image is the drawing's relationship rId.
+func (p *Picture) marshal(id int, rId string, anchor drawingAnchor) string {
+	return fmt.Sprintf(`<xdr:pic><xdr:nvPicPr><xdr:cNvPr id="%d" name="Picture %d" descr="%s"/><xdr:cNvPicPr><a:picLocks noChangeAspect="1"/></xdr:cNvPicPr></xdr:nvPicPr>`+
+		`<xdr:blipFill><a:blip r:embed="%s"/><a:stretch><a:fillRect/></a:stretch></xdr:blipFill>`+
+		`<xdr:spPr><a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></xdr:spPr></xdr:pic>`,
+		id, id-1, escapeAttr(p.Options.Description), rId,
+		anchor.x*emuPerPixel, anchor.y*emuPerPixel, anchor.width*emuPerPixel, anchor.height*emuPerPixel)
+}
+
+// readPicture returns the Picture placed by a drawing anchor, whose
+// image is data, read from a part with the extension ext.
+func readPicture(anchor xlsxDrawingAnchor, data []byte, ext string) *Picture {
+	picture := &Picture{Data: data, Format: strings.ToLower(strings.TrimPrefix(ext, "."))}
+	if picture.Format == "jpg" {
+		picture.Format = "jpeg"
+	}
+	picture.Options.Description = anchor.Pic.CNvPr.Descr
+	picture.Options.Anchor = strings.TrimSuffix(anchor.XMLName.Local, "Anchor")
+	picture.Cell = "A1"
+	if anchor.From != nil {
+		picture.Cell = getCellIDStringFromCoords(anchor.From.Col, anchor.From.Row)
+		picture.Options.OffsetX = int(anchor.From.ColOff / emuPerPixel)
+		picture.Options.OffsetY = int(anchor.From.RowOff / emuPerPixel)
+	} else if anchor.Pos != nil {
+		picture.Options.OffsetX = int(anchor.Pos.X / emuPerPixel)
+		picture.Options.OffsetY = int(anchor.Pos.Y / emuPerPixel)
+	}
+	size := anchor.Ext
+	if anchor.Pic.Xfrm.Ext != nil {
+		size = anchor.Pic.Xfrm.Ext
+	}
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		picture.width, picture.height = config.Width, config.Height
+		if size != nil && config.Width > 0 && config.Height > 0 {
+			picture.Options.ScaleX = float64(size.Cx) / float64(config.Width*emuPerPixel)
+			picture.Options.ScaleY = float64(size.Cy) / float64(config.Height*emuPerPixel)
+		}
+	}
+	return picture
+}
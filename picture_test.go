@@ -0,0 +1,178 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type PictureSuite struct{}
+
+var _ = Suite(&PictureSuite{})
+
+// testPNG returns a blank PNG image of the given size.
+func testPNG(c *C, width, height int) []byte {
+	var buf bytes.Buffer
+	c.Assert(png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))), IsNil)
+	return buf.Bytes()
+}
+
+func (s *PictureSuite) TestAddPicture(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	logo := testPNG(c, 20, 10)
+	c.Assert(sheet.AddPicture("B2", logo, "PNG", &PictureOptions{
+		OffsetX:     5,
+		ScaleX:      2,
+		Description: "Our <logo>"}), IsNil)
+	c.Assert(sheet.Pictures(), HasLen, 1)
+	c.Assert(sheet.Pictures()[0].Format, Equals, "png")
+	c.Assert(sheet.Pictures()[0].Options.Anchor, Equals, "oneCell")
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/media/image1.png"], Equals, string(logo))
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<drawing xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="rId1"></drawing>`), Equals, true)
+	c.Assert(parts["xl/worksheets/_rels/sheet1.xml.rels"], Equals, `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Target="../drawings/drawing1.xml" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/drawing"></Relationship></Relationships>`)
+	c.Assert(parts["xl/drawings/_rels/drawing1.xml.rels"], Equals, `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Target="../media/image1.png" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"></Relationship></Relationships>`)
+	c.Assert(strings.Contains(parts["xl/drawings/drawing1.xml"], `<xdr:oneCellAnchor><xdr:from><xdr:col>1</xdr:col><xdr:colOff>47625</xdr:colOff><xdr:row>1</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from><xdr:ext cx="381000" cy="95250"/><xdr:pic><xdr:nvPicPr><xdr:cNvPr id="2" name="Picture 1" descr="Our &lt;logo&gt;"/>`), Equals, true)
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], `<Override PartName="/xl/drawings/drawing1.xml" ContentType="application/vnd.openxmlformats-officedocument.drawing+xml"></Override>`), Equals, true)
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], `<Default Extension="png" ContentType="image/png"></Default>`), Equals, true)
+}
+
+func (s *PictureSuite) TestAddPictureErrors(c *C) {
+	sheet := NewFile().AddSheet("Sheet1")
+	logo := testPNG(c, 1, 1)
+	c.Assert(sheet.AddPicture("A1", logo, "bmp", nil), ErrorMatches, `unsupported picture format "bmp"`)
+	c.Assert(sheet.AddPicture("A1", logo, "jpg", nil), ErrorMatches, "picture is png, not jpeg")
+	c.Assert(sheet.AddPicture("A1", []byte("not an image"), "png", nil), ErrorMatches, "can't read png picture: .*")
+	c.Assert(sheet.AddPicture("A1", logo, "png", &PictureOptions{Anchor: "floating"}), ErrorMatches, `unknown picture anchor "floating"`)
+	c.Assert(sheet.Pictures(), HasLen, 0)
+}
+
+// Pictures written to a file are read back from it, and the drawing
+// they are in is written back out as it was read.
+func (s *PictureSuite) TestPicturesRoundTrip(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	c.Assert(sheet.AddPicture("C3", testPNG(c, 4, 2), "png", &PictureOptions{
+		OffsetX: 3,
+		OffsetY: 4,
+		ScaleX:  1.5,
+		ScaleY:  2,
+		Anchor:  "twoCell"}), IsNil)
+	c.Assert(sheet.AddPicture("A1", testPNG(c, 8, 8), "png", &PictureOptions{
+		OffsetX: 100,
+		OffsetY: 30,
+		Anchor:  "absolute"}), IsNil)
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	pictures := f2.Sheet["Sheet1"].Pictures()
+	c.Assert(pictures, HasLen, 2)
+	c.Assert(*pictures[0], DeepEquals, *sheet.Pictures()[0])
+	// The scale that was left as it was is read as it is.
+	expected := *sheet.Pictures()[1]
+	expected.Options.ScaleX, expected.Options.ScaleY = 1, 1
+	c.Assert(*pictures[1], DeepEquals, expected)
+
+	parts, err := f2.MarshallParts()
+	c.Assert(err, IsNil)
+	original, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	for _, name := range []string{"xl/drawings/drawing1.xml", "xl/drawings/_rels/drawing1.xml.rels", "xl/media/image1.png", "xl/media/image2.png"} {
+		c.Assert(parts[name], Equals, original[name])
+	}
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], `<Override PartName="/xl/drawings/drawing1.xml"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<drawing xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="rId1"></drawing>`), Equals, true)
+}
+
+// Pictures added to a Sheet read with a drawing are added to that
+// drawing, which otherwise stays as it was read.
+func (s *PictureSuite) TestAddPictureToRetainedDrawing(c *C) {
+	f := NewFile()
+	c.Assert(f.AddSheet("Sheet1").AddPicture("B2", testPNG(c, 4, 4), "png", nil), IsNil)
+	original, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+
+	sheet := f2.Sheet["Sheet1"]
+	logo := testPNG(c, 6, 3)
+	c.Assert(sheet.AddPicture("D4", logo, "png", &PictureOptions{Description: "Added"}), IsNil)
+	parts, err := f2.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/media/image1.png"], Equals, original["xl/media/image1.png"])
+	c.Assert(parts["xl/media/image2.png"], Equals, string(logo))
+	drawing := parts["xl/drawings/drawing1.xml"]
+	c.Assert(strings.HasPrefix(drawing, strings.TrimSuffix(original["xl/drawings/drawing1.xml"], "</xdr:wsDr>")), Equals, true)
+	c.Assert(strings.Contains(drawing, `<xdr:oneCellAnchor xmlns:xdr=`), Equals, true)
+	c.Assert(strings.Contains(drawing, `<xdr:cNvPr id="3" name="Picture 2" descr="Added"/>`), Equals, true)
+	c.Assert(parts["xl/drawings/_rels/drawing1.xml.rels"], Equals, `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Target="../media/image1.png" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"></Relationship><Relationship Id="rId2" Target="../media/image2.png" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"></Relationship></Relationships>`)
+	c.Assert(parts["xl/worksheets/_rels/sheet1.xml.rels"], Equals, original["xl/worksheets/_rels/sheet1.xml.rels"])
+
+	buf.Reset()
+	c.Assert(f2.Write(&buf), IsNil)
+	zr, err = zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f3, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	pictures := f3.Sheet["Sheet1"].Pictures()
+	c.Assert(pictures, HasLen, 2)
+	c.Assert(pictures[1].Cell, Equals, "D4")
+	c.Assert(pictures[1].Data, DeepEquals, logo)
+}
+
+// Pictures that are linked, or whose relationships can't be followed,
+// don't stop the file from being read, and are left in the drawing.
+func (s *PictureSuite) TestReadLinkedPictures(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	for _, cell := range []string{"A1", "B2", "C3", "D4"} {
+		c.Assert(sheet.AddPicture(cell, testPNG(c, 4, 4), "png", nil), IsNil)
+	}
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	drawing := parts["xl/drawings/drawing1.xml"]
+	drawing = strings.Replace(drawing, `r:embed="rId1"`, `r:link="rId1"`, 1)
+	drawing = strings.Replace(drawing, `r:embed="rId2"`, `r:embed="rId9"`, 1)
+	parts["xl/drawings/drawing1.xml"] = drawing
+	parts["xl/drawings/_rels/drawing1.xml.rels"] = strings.Replace(parts["xl/drawings/_rels/drawing1.xml.rels"],
+		`Target="../media/image3.png"`, `Target="http://example.com/image3.png" TargetMode="External"`, 1)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, part := range parts {
+		w, err := zw.Create(name)
+		c.Assert(err, IsNil)
+		_, err = w.Write([]byte(part))
+		c.Assert(err, IsNil)
+	}
+	c.Assert(zw.Close(), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	pictures := f2.Sheet["Sheet1"].Pictures()
+	c.Assert(pictures, HasLen, 1)
+	c.Assert(pictures[0].Cell, Equals, "D4")
+
+	parts2, err := f2.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts2["xl/drawings/drawing1.xml"], Equals, drawing)
+}
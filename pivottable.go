@@ -0,0 +1,651 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	pivotTableRelType           = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotTable"
+	pivotCacheDefinitionRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheDefinition"
+	pivotCacheRecordsRelType    = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/pivotCacheRecords"
+
+	pivotTableContentType           = "application/vnd.openxmlformats-officedocument.spreadsheetml.pivotTable+xml"
+	pivotCacheDefinitionContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.pivotCacheDefinition+xml"
+	pivotCacheRecordsContentType    = "application/vnd.openxmlformats-officedocument.spreadsheetml.pivotCacheRecords+xml"
+)
+
+// pivotFunctionTitles are the functions that the values of a pivot
+// table can be summarised with, and the titles that Excel gives them.
+var pivotFunctionTitles = map[string]string{
+	"sum":       "Sum",
+	"count":     "Count",
+	"average":   "Average",
+	"max":       "Max",
+	"min":       "Min",
+	"product":   "Product",
+	"countNums": "Count",
+	"stdDev":    "StdDev",
+	"stdDevp":   "StdDevp",
+	"var":       "Var",
+	"varp":      "Varp",
+}
+
+// PivotTable is a pivot table on a Sheet, which summarises the data in
+// a range of cells whose first row holds the names of its fields.
+type PivotTable struct {
+	Name        string
+	SourceRange string // Such as "Data!$A$1:$D$100".
+	Location    string // The cell, such as "A3", at the top left of the table.
+	Rows        []string
+	Columns     []string
+	Values      []PivotValue
+	Filters     []string
+	// Cache holds the data that a pivot table read from a file
+	// was last refreshed with.  It is nil for those that have been
+	// added, whose data is read from the SourceRange when they are
+	// written.
+	Cache *PivotCache
+	// retained is set for pivot tables read from a file, which are
+	// written back out as they were read.
+	retained bool
+}
+
+// PivotValue is a field whose values are summarised by a PivotTable.
+type PivotValue struct {
+	Field    string
+	Function string // Such as "sum", "count" or "average".
+	Name     string // The caption, such as "Sum of Sales".
+}
+
+// PivotCache is the data that a PivotTable summarises.  The values of
+// the fields are given as strings, and are empty when they are blank.
+type PivotCache struct {
+	Fields  []string
+	Records [][]string
+}
+
+// AddPivotTable adds a PivotTable to the Sheet, at the cell
+// targetCell, that summarises the data in sourceRange, such as
+// "Data!$A$1:$D$100".  The first row of sourceRange holds the names of
+// the fields that rows, columns, values and filters refer to, and the
+// rest is read when the table is written.  Filters are put in the
+// rows above targetCell, so there must be room for them, and a blank
+// row, there.
+//
+// The pivot table is refreshed when the file is opened in Excel.
+func (s *Sheet) AddPivotTable(sourceRange, targetCell string, rows, columns []string, values []PivotValue, filters []string) (*PivotTable, error) {
+	fields, err := s.File.pivotSourceFields(sourceRange)
+	if err != nil {
+		return nil, err
+	}
+	_, row, err := getCoordsFromCellIDString(targetCell)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cell reference %q: %s", targetCell, err)
+	}
+	if len(filters) > 0 && row < len(filters)+1 {
+		return nil, fmt.Errorf("there isn't room above %s for %d filters", targetCell, len(filters))
+	}
+	used := make(map[string]bool)
+	for _, axis := range [][]string{rows, columns, filters} {
+		for _, field := range axis {
+			if pivotFieldIndex(fields, field) < 0 {
+				return nil, fmt.Errorf("pivot table source %q has no field %q", sourceRange, field)
+			}
+			if used[field] {
+				return nil, fmt.Errorf("pivot table field %q can only be used once amongst the rows, columns and filters", field)
+			}
+			used[field] = true
+		}
+	}
+	table := &PivotTable{
+		Name:        fmt.Sprintf("PivotTable%d", len(s.pivotTables)+1),
+		SourceRange: sourceRange,
+		Location:    targetCell,
+		Rows:        rows,
+		Columns:     columns,
+		Filters:     filters,
+	}
+	for _, value := range values {
+		if pivotFieldIndex(fields, value.Field) < 0 {
+			return nil, fmt.Errorf("pivot table source %q has no field %q", sourceRange, value.Field)
+		}
+		if value.Function == "" {
+			value.Function = "sum"
+		}
+		title, ok := pivotFunctionTitles[value.Function]
+		if !ok {
+			return nil, fmt.Errorf("unknown pivot table function %q", value.Function)
+		}
+		if value.Name == "" {
+			value.Name = title + " of " + value.Field
+		}
+		table.Values = append(table.Values, value)
+	}
+	s.pivotTables = append(s.pivotTables, table)
+	return table, nil
+}
+
+// PivotTables returns the pivot tables on the Sheet, both those read
+// from a file and those that have been added.
+func (s *Sheet) PivotTables() []*PivotTable {
+	return append([]*PivotTable{}, s.pivotTables...)
+}
+
+// pivotSourceFields returns the names of the fields of the source of
+// a pivot table, from the first row of the range sourceRange.
+func (f *File) pivotSourceFields(sourceRange string) ([]string, error) {
+	sheetName, x1, y1, x2, _, ok := parseRangeRef(sourceRange)
+	if !ok {
+		return nil, fmt.Errorf("invalid pivot table source %q", sourceRange)
+	}
+	if _, ok := f.Sheet[sheetName]; !ok {
+		return nil, fmt.Errorf("pivot table source %q is on sheet %q, which doesn't exist", sourceRange, sheetName)
+	}
+	header, _ := f.rangeCells(quoteSheetName(sheetName) + "!" +
+		getCellIDStringFromCoords(x1, y1) + ":" + getCellIDStringFromCoords(x2, y1))
+	fields := make([]string, len(header))
+	for i, cell := range header {
+		if cell != nil {
+			fields[i] = cell.String()
+		}
+		if fields[i] == "" {
+			return nil, fmt.Errorf("pivot table source %q has a field with no name", sourceRange)
+		}
+	}
+	return fields, nil
+}
+
+// pivotFieldIndex returns the index of the field with the given name,
+// or -1 if there isn't one.
+func pivotFieldIndex(fields []string, name string) int {
+	for i, field := range fields {
+		if field == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// pivotCacheValue returns the value of a cell as it is held in a pivot
+// cache: a number, a string, or missing.
+func pivotCacheValue(cell *Cell) xlsxPivotCacheValue {
+	if cell == nil || cell.Value == "" {
+		return xlsxPivotCacheValue{XMLName: xml.Name{Local: "m"}}
+	}
+	switch cell.Type() {
+	case CellTypeNumeric, CellTypeFormula:
+		if n, err := strconv.ParseFloat(cell.Value, 64); err == nil {
+			return xlsxPivotCacheValue{XMLName: xml.Name{Local: "n"}, V: strconv.FormatFloat(n, 'g', -1, 64)}
+		}
+	}
+	return xlsxPivotCacheValue{XMLName: xml.Name{Local: "s"}, V: cell.String()}
+}
+
+// makeXLSXPivotCache returns the cache definition and records for the
+// PivotTable, read from the cells of f.  The shared items of the fields
+// on the table's axes, which the records refer to by index, are
+// listed; the values of the other fields are held in the records.
+func (t *PivotTable) makeXLSXPivotCache(f *File) (*xlsxPivotCacheDefinition, *xlsxPivotCacheRecords, error) {
+	fields, err := f.pivotSourceFields(t.SourceRange)
+	if err != nil {
+		return nil, nil, err
+	}
+	sheetName, x1, y1, x2, y2, _ := parseRangeRef(t.SourceRange)
+	cells, _ := f.rangeCells(t.SourceRange)
+	width := x2 - x1 + 1
+
+	axes := make(map[string]bool)
+	for _, axis := range [][]string{t.Rows, t.Columns, t.Filters} {
+		for _, field := range axis {
+			axes[field] = true
+		}
+	}
+
+	definition := &xlsxPivotCacheDefinition{
+		RId:                   "rId1",
+		RefreshOnLoad:         true,
+		RefreshedBy:           "Go XLSX",
+		CreatedVersion:        6,
+		RefreshedVersion:      6,
+		MinRefreshableVersion: 3,
+		RecordCount:           y2 - y1,
+		CacheSource: xlsxPivotCacheSource{
+			Type: "worksheet",
+			WorksheetSource: &xlsxPivotWorksheetSource{
+				Ref:   getCellIDStringFromCoords(x1, y1) + ":" + getCellIDStringFromCoords(x2, y2),
+				Sheet: sheetName}},
+		CacheFields: xlsxPivotCacheFields{Count: len(fields)},
+	}
+	records := &xlsxPivotCacheRecords{Count: y2 - y1, R: make([]xlsxPivotCacheRecord, y2-y1)}
+	for j, field := range fields {
+		cacheField := xlsxPivotCacheField{Name: field}
+		items := &cacheField.SharedItems
+		indexes := make(map[xlsxPivotCacheValue]int)
+		var hasString, hasNumber, hasBlank bool
+		var min, max float64
+		for i := range records.R {
+			value := pivotCacheValue(cells[(i+1)*width+j])
+			switch value.XMLName.Local {
+			case "s":
+				hasString = true
+			case "m":
+				hasBlank = true
+			case "n":
+				n, _ := strconv.ParseFloat(value.V, 64)
+				if !hasNumber || n < min {
+					min = n
+				}
+				if !hasNumber || n > max {
+					max = n
+				}
+				hasNumber = true
+			}
+			if axes[field] {
+				index, ok := indexes[value]
+				if !ok {
+					index = len(items.Items)
+					indexes[value] = index
+					items.Items = append(items.Items, value)
+				}
+				value = xlsxPivotCacheValue{XMLName: xml.Name{Local: "x"}, V: strconv.Itoa(index)}
+			}
+			records.R[i].Values = append(records.R[i].Values, value)
+		}
+		items.Count = len(items.Items)
+		if !hasString {
+			items.ContainsString = "0"
+			if !hasBlank {
+				items.ContainsSemiMixedTypes = "0"
+			}
+		}
+		if hasBlank {
+			items.ContainsBlank = "1"
+		}
+		if hasNumber {
+			items.ContainsNumber = "1"
+			items.MinValue = strconv.FormatFloat(min, 'g', -1, 64)
+			items.MaxValue = strconv.FormatFloat(max, 'g', -1, 64)
+			if hasString {
+				items.ContainsMixedTypes = "1"
+			}
+		}
+		definition.CacheFields.CacheField = append(definition.CacheFields.CacheField, cacheField)
+	}
+	return definition, records, nil
+}
+
+// makeXLSXPivotTableDefinition returns the definition of the
+// PivotTable, whose cache, with the given id, is cache.
+func (t *PivotTable) makeXLSXPivotTableDefinition(cacheId int, cache *xlsxPivotCacheDefinition) *xlsxPivotTableDefinition {
+	fields := make([]string, len(cache.CacheFields.CacheField))
+	for i, field := range cache.CacheFields.CacheField {
+		fields[i] = field.Name
+	}
+	definition := &xlsxPivotTableDefinition{
+		Name:                  t.Name,
+		CacheId:               cacheId,
+		DataCaption:           "Values",
+		UpdatedVersion:        6,
+		MinRefreshableVersion: 3,
+		CreatedVersion:        6,
+		UseAutoFormatting:     true,
+		ItemPrintTitles:       true,
+		Outline:               true,
+		OutlineData:           true,
+		PivotFields:           xlsxPivotFields{Count: len(fields)},
+		PivotTableStyleInfo: &xlsxPivotTableStyleInfo{
+			Name:           "PivotStyleLight16",
+			ShowRowHeaders: true,
+			ShowColHeaders: true,
+			ShowLastColumn: true},
+	}
+	for i, field := range fields {
+		pivotField := xlsxPivotField{}
+		for _, axis := range []struct {
+			name   string
+			fields []string
+		}{{"axisRow", t.Rows}, {"axisCol", t.Columns}, {"axisPage", t.Filters}} {
+			if pivotFieldIndex(axis.fields, field) >= 0 {
+				pivotField.Axis = axis.name
+			}
+		}
+		for _, value := range t.Values {
+			if value.Field == field {
+				pivotField.DataField = true
+			}
+		}
+		if pivotField.Axis != "" {
+			count := cache.CacheFields.CacheField[i].SharedItems.Count
+			pivotField.Items = &xlsxPivotItems{Count: count + 1}
+			for x := 0; x < count; x++ {
+				x := x
+				pivotField.Items.Item = append(pivotField.Items.Item, xlsxPivotItem{X: &x})
+			}
+			pivotField.Items.Item = append(pivotField.Items.Item, xlsxPivotItem{T: "default"})
+		}
+		definition.PivotFields.PivotField = append(definition.PivotFields.PivotField, pivotField)
+	}
+
+	fieldRefs := func(names []string, values bool) *xlsxPivotFieldRefs {
+		refs := &xlsxPivotFieldRefs{}
+		for _, name := range names {
+			refs.Field = append(refs.Field, xlsxPivotFieldRef{X: pivotFieldIndex(fields, name)})
+		}
+		if values {
+			refs.Field = append(refs.Field, xlsxPivotFieldRef{X: -2})
+		}
+		refs.Count = len(refs.Field)
+		if refs.Count == 0 {
+			return nil
+		}
+		return refs
+	}
+	definition.RowFields = fieldRefs(t.Rows, false)
+	definition.ColFields = fieldRefs(t.Columns, len(t.Values) > 1)
+	if len(t.Filters) > 0 {
+		definition.PageFields = &xlsxPivotPageFields{Count: len(t.Filters)}
+		for _, name := range t.Filters {
+			definition.PageFields.PageField = append(definition.PageFields.PageField,
+				xlsxPivotPageField{Fld: pivotFieldIndex(fields, name), Hier: -1})
+		}
+	}
+	if len(t.Values) > 0 {
+		definition.DataFields = &xlsxPivotDataFields{Count: len(t.Values)}
+		for _, value := range t.Values {
+			definition.DataFields.DataField = append(definition.DataFields.DataField, xlsxPivotDataField{
+				Name:     value.Name,
+				Fld:      pivotFieldIndex(fields, value.Field),
+				Subtotal: value.Function})
+		}
+	}
+
+	// The table is laid out afresh when it is refreshed, so its
+	// extent here need only be roughly right.
+	col, row, _ := getCoordsFromCellIDString(t.Location)
+	width, height := len(t.Values), 2
+	if width == 0 {
+		width = 1
+	}
+	definition.Location.FirstHeaderRow = 1
+	definition.Location.FirstDataRow = 1
+	if len(t.Rows) > 0 {
+		width++
+		definition.Location.FirstDataCol = 1
+		height += cache.CacheFields.CacheField[pivotFieldIndex(fields, t.Rows[0])].SharedItems.Count
+	}
+	if definition.ColFields != nil {
+		height++
+		definition.Location.FirstDataRow = 2
+	}
+	if len(t.Filters) > 0 {
+		definition.Location.RowPageCount = len(t.Filters)
+		definition.Location.ColPageCount = 1
+	}
+	definition.Location.Ref = t.Location + ":" + getCellIDStringFromCoords(col+width-1, row+height-1)
+	return definition
+}
+
+// pivotCacheList collects the pivot caches of a workbook as it is
+// written: those retained from the file it was read from, and those
+// of the pivot tables that have been added.
+type pivotCacheList struct {
+	retained *xlsxRawXML
+	nextId   int
+	ids      []int
+	parts    []string
+}
+
+// newPivotCacheList returns a pivotCacheList holding the pivotCaches
+// element, which may be nil, retained from a workbook.
+func newPivotCacheList(retained *xlsxRawXML) *pivotCacheList {
+	list := &pivotCacheList{retained: retained, nextId: 1}
+	if retained == nil {
+		return list
+	}
+	decoder := xml.NewDecoder(strings.NewReader(retained.InnerXML))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		if element, ok := token.(xml.StartElement); ok {
+			for _, attr := range element.Attr {
+				if id, err := strconv.Atoi(attr.Value); err == nil && attr.Name.Local == "cacheId" && id >= list.nextId {
+					list.nextId = id + 1
+				}
+			}
+		}
+	}
+	return list
+}
+
+// add adds the pivot cache definition with the given part name to the
+// list, and returns the id it is given.
+func (l *pivotCacheList) add(part string) int {
+	id := l.nextId
+	l.nextId++
+	l.ids = append(l.ids, id)
+	l.parts = append(l.parts, part)
+	return id
+}
+
+// makeXLSXPivotCaches returns the pivotCaches element of the
+// workbook, and the workbook relationships to the pivot caches that
+// have been added, whose ids are allocated with nextFreeRelId.
+func (l *pivotCacheList) makeXLSXPivotCaches(relCount *int, used map[string]bool) (*xlsxRawXML, []xlsxWorkbookRelation) {
+	if len(l.ids) == 0 {
+		return l.retained, nil
+	}
+	pivotCaches := &xlsxRawXML{XMLName: xml.Name{Local: "pivotCaches"}}
+	if l.retained != nil {
+		pivotCaches.Attrs = l.retained.Attrs
+		pivotCaches.InnerXML = l.retained.InnerXML
+	}
+	var rels []xlsxWorkbookRelation
+	for i, id := range l.ids {
+		rId := nextFreeRelId(relCount, used)
+		rels = append(rels, xlsxWorkbookRelation{
+			Id:     rId,
+			Target: relTarget("xl/workbook.xml", l.parts[i]),
+			Type:   pivotCacheDefinitionRelType})
+		pivotCaches.InnerXML += fmt.Sprintf(`<pivotCache cacheId="%d" xmlns:r="%s" r:id="%s"/>`, id, relationshipsNS, rId)
+	}
+	return pivotCaches, rels
+}
+
+// makePivotTableParts adds the parts for the pivot tables that have
+// been added to the Sheet, and their caches, to parts.  It returns
+// rels, the relationships of the worksheet, with the pivot tables
+// added to them.
+func (s *Sheet) makePivotTableParts(rels string, names *partNamer, parts map[string]string, types *xlsxTypes, caches *pivotCacheList) (string, error) {
+	marshal := func(part string, thing interface{}) error {
+		body, err := xml.Marshal(thing)
+		if err != nil {
+			return err
+		}
+		parts[part] = xml.Header + string(body)
+		return nil
+	}
+	for _, table := range s.pivotTables {
+		if table.retained {
+			continue
+		}
+		definitionPart := names.next("xl/pivotCache/pivotCacheDefinition%d.xml")
+		recordsPart := names.next("xl/pivotCache/pivotCacheRecords%d.xml")
+		tablePart := names.next("xl/pivotTables/pivotTable%d.xml")
+
+		cache, records, err := table.makeXLSXPivotCache(s.File)
+		if err != nil {
+			return "", err
+		}
+		cacheId := caches.add(definitionPart)
+		for _, part := range []struct {
+			name  string
+			thing interface{}
+		}{
+			{definitionPart, cache},
+			{recordsPart, records},
+			{tablePart, table.makeXLSXPivotTableDefinition(cacheId, cache)},
+			{relsPartName(definitionPart), xlsxWorkbookRels{Relationships: []xlsxWorkbookRelation{{
+				Id:     cache.RId,
+				Target: relTarget(definitionPart, recordsPart),
+				Type:   pivotCacheRecordsRelType}}}},
+			{relsPartName(tablePart), xlsxWorkbookRels{Relationships: []xlsxWorkbookRelation{{
+				Id:     "rId1",
+				Target: relTarget(tablePart, definitionPart),
+				Type:   pivotCacheDefinitionRelType}}}},
+		} {
+			if err := marshal(part.name, part.thing); err != nil {
+				return "", err
+			}
+		}
+		types.Overrides = append(types.Overrides,
+			xlsxOverride{PartName: "/" + definitionPart, ContentType: pivotCacheDefinitionContentType},
+			xlsxOverride{PartName: "/" + recordsPart, ContentType: pivotCacheRecordsContentType},
+			xlsxOverride{PartName: "/" + tablePart, ContentType: pivotTableContentType})
+		rels, _, err = addRel(rels, pivotTableRelType, relTarget("xl/worksheets/sheet.xml", tablePart))
+		if err != nil {
+			return "", err
+		}
+	}
+	return rels, nil
+}
+
+// readPivotTables reads the pivot tables of a Sheet that has been read
+// from a file, from the parts retained from that file.
+func (s *Sheet) readPivotTables(retainedParts map[string]string) error {
+	if s.rels == "" {
+		return nil
+	}
+	tableParts, err := findRelTargets(s.rels, "xl/worksheets/sheet.xml", pivotTableRelType)
+	if err != nil {
+		return err
+	}
+	for _, tablePart := range tableParts {
+		definition := new(xlsxPivotTableDefinition)
+		cache := new(xlsxPivotCacheDefinition)
+		records := new(xlsxPivotCacheRecords)
+		if err := unmarshalRelatedPart(retainedParts, tablePart, definition); err != nil {
+			return err
+		}
+		cachePart, err := findRelatedPart(retainedParts, tablePart, pivotCacheDefinitionRelType)
+		if err != nil {
+			return err
+		}
+		if err := unmarshalRelatedPart(retainedParts, cachePart, cache); err != nil {
+			return err
+		}
+		recordsPart, err := findRelatedPart(retainedParts, cachePart, pivotCacheRecordsRelType)
+		if err != nil {
+			return err
+		}
+		if err := unmarshalRelatedPart(retainedParts, recordsPart, records); err != nil {
+			return err
+		}
+		table := readPivotTable(definition, cache, records)
+		table.retained = true
+		s.pivotTables = append(s.pivotTables, table)
+	}
+	return nil
+}
+
+// findRelatedPart returns the name of the first part that the part
+// source has a relationship, of the given type, to.  The name is empty
+// if there isn't one.
+func findRelatedPart(retainedParts map[string]string, source, relType string) (string, error) {
+	rels, ok := retainedParts[relsPartName(source)]
+	if !ok {
+		return "", nil
+	}
+	targets, err := findRelTargets(rels, source, relType)
+	if err != nil || len(targets) == 0 {
+		return "", err
+	}
+	return targets[0], nil
+}
+
+// unmarshalRelatedPart decodes the retained part with the given name
+// into v.  Nothing is done if there is no such part.
+func unmarshalRelatedPart(retainedParts map[string]string, part string, v interface{}) error {
+	content, ok := retainedParts[part]
+	if !ok {
+		return nil
+	}
+	return xml.Unmarshal([]byte(content), v)
+}
+
+// readPivotTable returns the PivotTable with the given definition,
+// cache definition and records.
+func readPivotTable(definition *xlsxPivotTableDefinition, cache *xlsxPivotCacheDefinition, records *xlsxPivotCacheRecords) *PivotTable {
+	cacheFields := cache.CacheFields.CacheField
+	table := &PivotTable{
+		Name:     definition.Name,
+		Location: strings.Split(definition.Location.Ref, ":")[0],
+		Cache:    &PivotCache{},
+	}
+	field := func(i int) string {
+		if i >= 0 && i < len(cacheFields) {
+			return cacheFields[i].Name
+		}
+		return ""
+	}
+	if source := cache.CacheSource.WorksheetSource; source != nil {
+		if source.Name != "" {
+			table.SourceRange = source.Name
+		} else {
+			table.SourceRange = quoteSheetName(source.Sheet) + "!" + source.Ref
+		}
+	}
+	fieldNames := func(refs *xlsxPivotFieldRefs) []string {
+		var names []string
+		if refs != nil {
+			for _, ref := range refs.Field {
+				if ref.X >= 0 {
+					names = append(names, field(ref.X))
+				}
+			}
+		}
+		return names
+	}
+	table.Rows = fieldNames(definition.RowFields)
+	table.Columns = fieldNames(definition.ColFields)
+	if definition.PageFields != nil {
+		for _, pageField := range definition.PageFields.PageField {
+			table.Filters = append(table.Filters, field(pageField.Fld))
+		}
+	}
+	if definition.DataFields != nil {
+		for _, dataField := range definition.DataFields.DataField {
+			value := PivotValue{Field: field(dataField.Fld), Function: dataField.Subtotal, Name: dataField.Name}
+			if value.Function == "" {
+				value.Function = "sum"
+			}
+			table.Values = append(table.Values, value)
+		}
+	}
+
+	for _, cacheField := range cacheFields {
+		table.Cache.Fields = append(table.Cache.Fields, cacheField.Name)
+	}
+	for _, record := range records.R {
+		values := make([]string, len(record.Values))
+		for j, value := range record.Values {
+			if value.XMLName.Local == "x" && j < len(cacheFields) {
+				index, err := strconv.Atoi(value.V)
+				items := cacheFields[j].SharedItems.Items
+				if err != nil || index < 0 || index >= len(items) {
+					continue
+				}
+				value = items[index]
+			}
+			if value.XMLName.Local != "m" {
+				values[j] = value.V
+			}
+		}
+		table.Cache.Records = append(table.Cache.Records, values)
+	}
+	return table
+}
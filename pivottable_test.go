@@ -0,0 +1,146 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type PivotTableSuite struct{}
+
+var _ = Suite(&PivotTableSuite{})
+
+// makePivotSource returns a File with a sheet, "Data", of sales by
+// region and product, and an empty sheet, "Report".
+func makePivotSource() *File {
+	f := NewFile()
+	data := f.AddSheet("Data")
+	for _, values := range [][]interface{}{
+		{"Region", "Product", "Sales"},
+		{"North", "Apples", 10},
+		{"South", "Apples", 20},
+		{"North", "Pears", 5},
+		{"South", "Pears", ""},
+	} {
+		row := data.AddRow()
+		for _, value := range values {
+			cell := row.AddCell()
+			switch value := value.(type) {
+			case int:
+				cell.SetInt(value)
+			case string:
+				cell.SetString(value)
+			}
+		}
+	}
+	f.AddSheet("Report")
+	return f
+}
+
+func (s *PivotTableSuite) TestAddPivotTable(c *C) {
+	f := makePivotSource()
+	report := f.Sheet["Report"]
+	table, err := report.AddPivotTable("Data!$A$1:$C$5", "A3",
+		[]string{"Region"}, nil, []PivotValue{{Field: "Sales"}}, []string{"Product"})
+	c.Assert(err, IsNil)
+	c.Assert(table.Name, Equals, "PivotTable1")
+	c.Assert(table.Values, DeepEquals, []PivotValue{{Field: "Sales", Function: "sum", Name: "Sum of Sales"}})
+	c.Assert(report.PivotTables(), DeepEquals, []*PivotTable{table})
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/pivotCache/pivotCacheDefinition1.xml"], Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<pivotCacheDefinition xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:relationships="http://schemas.openxmlformats.org/officeDocument/2006/relationships" relationships:id="rId1" refreshOnLoad="true" refreshedBy="Go XLSX" createdVersion="6" refreshedVersion="6" minRefreshableVersion="3" recordCount="4">`+
+		`<cacheSource type="worksheet"><worksheetSource ref="A1:C5" sheet="Data"></worksheetSource></cacheSource><cacheFields count="3">`+
+		`<cacheField name="Region" numFmtId="0"><sharedItems count="2"><s v="North"></s><s v="South"></s></sharedItems></cacheField>`+
+		`<cacheField name="Product" numFmtId="0"><sharedItems count="2"><s v="Apples"></s><s v="Pears"></s></sharedItems></cacheField>`+
+		`<cacheField name="Sales" numFmtId="0"><sharedItems containsString="0" containsBlank="1" containsNumber="1" minValue="5" maxValue="20"></sharedItems></cacheField>`+
+		`</cacheFields></pivotCacheDefinition>`)
+	c.Assert(parts["xl/pivotCache/pivotCacheRecords1.xml"], Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<pivotCacheRecords xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="4">`+
+		`<r><x v="0"></x><x v="0"></x><n v="10"></n></r><r><x v="1"></x><x v="0"></x><n v="20"></n></r>`+
+		`<r><x v="0"></x><x v="1"></x><n v="5"></n></r><r><x v="1"></x><x v="1"></x><m></m></r></pivotCacheRecords>`)
+
+	pivotTable := parts["xl/pivotTables/pivotTable1.xml"]
+	c.Assert(strings.Contains(pivotTable, `name="PivotTable1" cacheId="1" dataCaption="Values"`), Equals, true)
+	c.Assert(strings.Contains(pivotTable, `<location ref="A3:B6" firstHeaderRow="1" firstDataRow="1" firstDataCol="1" rowPageCount="1" colPageCount="1">`), Equals, true)
+	c.Assert(strings.Contains(pivotTable, `<pivotField axis="axisRow" showAll="false"><items count="3"><item x="0"></item><item x="1"></item><item t="default"></item></items></pivotField>`), Equals, true)
+	c.Assert(strings.Contains(pivotTable, `<pivotField dataField="true" showAll="false"></pivotField>`), Equals, true)
+	c.Assert(strings.Contains(pivotTable, `<rowFields count="1"><field x="0"></field></rowFields>`), Equals, true)
+	c.Assert(strings.Contains(pivotTable, `<pageFields count="1"><pageField fld="1" hier="-1"></pageField></pageFields>`), Equals, true)
+	c.Assert(strings.Contains(pivotTable, `<dataField name="Sum of Sales" fld="2" subtotal="sum" baseField="0" baseItem="0"></dataField>`), Equals, true)
+
+	c.Assert(strings.Contains(parts["xl/workbook.xml"], `<pivotCaches><pivotCache cacheId="1" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="rId3"/></pivotCaches>`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/_rels/workbook.xml.rels"], `<Relationship Id="rId3" Target="pivotCache/pivotCacheDefinition1.xml" Type="`+pivotCacheDefinitionRelType+`">`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/_rels/sheet2.xml.rels"], `Target="../pivotTables/pivotTable1.xml" Type="`+pivotTableRelType+`"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/pivotTables/_rels/pivotTable1.xml.rels"], `Target="../pivotCache/pivotCacheDefinition1.xml"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/pivotCache/_rels/pivotCacheDefinition1.xml.rels"], `Target="pivotCacheRecords1.xml"`), Equals, true)
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], `PartName="/xl/pivotTables/pivotTable1.xml" ContentType="`+pivotTableContentType+`"`), Equals, true)
+}
+
+func (s *PivotTableSuite) TestAddPivotTableErrors(c *C) {
+	f := makePivotSource()
+	report := f.Sheet["Report"]
+	values := []PivotValue{{Field: "Sales"}}
+	_, err := report.AddPivotTable("Data!A1:C5", "A3", []string{"Region"}, nil, values, nil)
+	c.Assert(err, IsNil)
+	_, err = report.AddPivotTable("Data!A1", "A3", []string{"Region"}, nil, values, nil)
+	c.Assert(err, ErrorMatches, `pivot table source "Data!A1" has no field "Sales"`)
+	_, err = report.AddPivotTable("Nowhere!A1:C5", "A3", []string{"Region"}, nil, values, nil)
+	c.Assert(err, ErrorMatches, `pivot table source "Nowhere!A1:C5" is on sheet "Nowhere", which doesn't exist`)
+	_, err = report.AddPivotTable("Data!A1:D5", "A3", []string{"Region"}, nil, values, nil)
+	c.Assert(err, ErrorMatches, `pivot table source "Data!A1:D5" has a field with no name`)
+	_, err = report.AddPivotTable("Data!A1:C5", "A3", []string{"Colour"}, nil, values, nil)
+	c.Assert(err, ErrorMatches, `pivot table source "Data!A1:C5" has no field "Colour"`)
+	_, err = report.AddPivotTable("Data!A1:C5", "A3", []string{"Region"}, []string{"Region"}, values, nil)
+	c.Assert(err, ErrorMatches, `pivot table field "Region" can only be used once amongst the rows, columns and filters`)
+	_, err = report.AddPivotTable("Data!A1:C5", "A3", []string{"Region"}, nil, []PivotValue{{Field: "Sales", Function: "median"}}, nil)
+	c.Assert(err, ErrorMatches, `unknown pivot table function "median"`)
+	_, err = report.AddPivotTable("Data!A1:C5", "A2", []string{"Region"}, nil, values, []string{"Product"})
+	c.Assert(err, ErrorMatches, `there isn't room above A2 for 1 filters`)
+	c.Assert(report.PivotTables(), HasLen, 1)
+}
+
+func (s *PivotTableSuite) TestPivotTablesRoundTrip(c *C) {
+	f := makePivotSource()
+	_, err := f.Sheet["Report"].AddPivotTable("Data!$A$1:$C$5", "A1",
+		[]string{"Region"}, []string{"Product"}, []PivotValue{{Field: "Sales", Function: "average"}, {Field: "Sales", Function: "count", Name: "Sales"}}, nil)
+	c.Assert(err, IsNil)
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	tables := f2.Sheet["Report"].PivotTables()
+	c.Assert(tables, HasLen, 1)
+	c.Assert(*tables[0], DeepEquals, PivotTable{
+		Name:        "PivotTable1",
+		SourceRange: "Data!A1:C5",
+		Location:    "A1",
+		Rows:        []string{"Region"},
+		Columns:     []string{"Product"},
+		Values:      []PivotValue{{"Sales", "average", "Average of Sales"}, {"Sales", "count", "Sales"}},
+		Cache: &PivotCache{
+			Fields: []string{"Region", "Product", "Sales"},
+			Records: [][]string{
+				{"North", "Apples", "10"},
+				{"South", "Apples", "20"},
+				{"North", "Pears", "5"},
+				{"South", "Pears", ""}}},
+		retained: true})
+
+	// A table that has been read is retained as it was, and one that
+	// is added alongside it gets a cache of its own.
+	_, err = f2.Sheet["Data"].AddPivotTable("Data!A1:C5", "E3", []string{"Product"}, nil, nil, nil)
+	c.Assert(err, IsNil)
+	parts, err := f2.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/pivotTables/pivotTable1.xml"], Equals, f2.retainedParts["xl/pivotTables/pivotTable1.xml"])
+	c.Assert(strings.Contains(parts["xl/pivotTables/pivotTable2.xml"], `cacheId="2"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/workbook.xml"], `r:id="rId3"/><pivotCache cacheId="2" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="rId4"/>`), Equals, true)
+	c.Assert(strings.Count(parts["xl/_rels/workbook.xml.rels"], pivotCacheDefinitionRelType), Equals, 2)
+}
@@ -0,0 +1,215 @@
+package xlsx
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// passwordSpinCount is the number of times a password hash is
+// rehashed, which is what Excel itself uses.
+const passwordSpinCount = 100000
+
+// SheetProtectionOptions says what users may still do to a protected
+// Sheet.  Anything not allowed is prevented.
+type SheetProtectionOptions struct {
+	AllowSelectLockedCells   bool
+	AllowSelectUnlockedCells bool
+	AllowFormatCells         bool
+	AllowFormatColumns       bool
+	AllowFormatRows          bool
+	AllowInsertColumns       bool
+	AllowInsertRows          bool
+	AllowInsertHyperlinks    bool
+	AllowDeleteColumns       bool
+	AllowDeleteRows          bool
+	AllowSort                bool
+	AllowAutoFilter          bool
+	AllowPivotTables         bool
+	AllowEditObjects         bool
+	AllowEditScenarios       bool
+}
+
+// NewSheetProtectionOptions returns the options that a Sheet is
+// protected with by default, which, as in Excel, only allow cells to
+// be selected.
+func NewSheetProtectionOptions() *SheetProtectionOptions {
+	return &SheetProtectionOptions{
+		AllowSelectLockedCells:   true,
+		AllowSelectUnlockedCells: true,
+	}
+}
+
+// Protect protects the Sheet, so that its locked cells (see
+// Style.Protection) can't be changed, and nothing that options doesn't
+// allow can be done to it.  A nil options means
+// NewSheetProtectionOptions.  Unless password is empty it has to be
+// given to unprotect the Sheet again; it is stored as a salted SHA-512
+// hash, along with the legacy hash understood by older applications.
+func (s *Sheet) Protect(password string, options *SheetProtectionOptions) error {
+	if options == nil {
+		options = NewSheetProtectionOptions()
+	}
+	protection := &xlsxSheetProtection{
+		Sheet:               true,
+		Objects:             !options.AllowEditObjects,
+		Scenarios:           !options.AllowEditScenarios,
+		FormatCells:         !options.AllowFormatCells,
+		FormatColumns:       !options.AllowFormatColumns,
+		FormatRows:          !options.AllowFormatRows,
+		InsertColumns:       !options.AllowInsertColumns,
+		InsertRows:          !options.AllowInsertRows,
+		InsertHyperlinks:    !options.AllowInsertHyperlinks,
+		DeleteColumns:       !options.AllowDeleteColumns,
+		DeleteRows:          !options.AllowDeleteRows,
+		SelectLockedCells:   !options.AllowSelectLockedCells,
+		Sort:                !options.AllowSort,
+		AutoFilter:          !options.AllowAutoFilter,
+		PivotTables:         !options.AllowPivotTables,
+		SelectUnlockedCells: !options.AllowSelectUnlockedCells,
+	}
+	if password != "" {
+		salt, err := newPasswordSalt()
+		if err != nil {
+			return err
+		}
+		protection.Password = legacyPasswordHash(password)
+		protection.AlgorithmName = "SHA-512"
+		protection.SaltValue = base64.StdEncoding.EncodeToString(salt)
+		protection.SpinCount = passwordSpinCount
+		protection.HashValue = base64.StdEncoding.EncodeToString(passwordHash(password, salt, passwordSpinCount))
+	}
+	s.protection = protection
+	return nil
+}
+
+// Unprotect removes the protection from the Sheet.
+func (s *Sheet) Unprotect() {
+	s.protection = nil
+}
+
+// IsProtected reports whether the Sheet is protected.
+func (s *Sheet) IsProtected() bool {
+	return s.protection != nil && s.protection.Sheet
+}
+
+// ProtectionOptions returns what users may do to the Sheet while it is
+// protected, or nil if it isn't.
+func (s *Sheet) ProtectionOptions() *SheetProtectionOptions {
+	if !s.IsProtected() {
+		return nil
+	}
+	p := s.protection
+	return &SheetProtectionOptions{
+		AllowSelectLockedCells:   !p.SelectLockedCells,
+		AllowSelectUnlockedCells: !p.SelectUnlockedCells,
+		AllowFormatCells:         !p.FormatCells,
+		AllowFormatColumns:       !p.FormatColumns,
+		AllowFormatRows:          !p.FormatRows,
+		AllowInsertColumns:       !p.InsertColumns,
+		AllowInsertRows:          !p.InsertRows,
+		AllowInsertHyperlinks:    !p.InsertHyperlinks,
+		AllowDeleteColumns:       !p.DeleteColumns,
+		AllowDeleteRows:          !p.DeleteRows,
+		AllowSort:                !p.Sort,
+		AllowAutoFilter:          !p.AutoFilter,
+		AllowPivotTables:         !p.PivotTables,
+		AllowEditObjects:         !p.Objects,
+		AllowEditScenarios:       !p.Scenarios,
+	}
+}
+
+// newPasswordSalt returns 16 random bytes to salt a password hash with.
+func newPasswordSalt() ([]byte, error) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("can't generate a password salt: %s", err)
+	}
+	return salt, nil
+}
+
+// passwordHash returns the SHA-512 hash of the salted password, which
+// is rehashed, along with the number of the iteration, spinCount
+// times.
+func passwordHash(password string, salt []byte, spinCount int) []byte {
+	h := sha512.New()
+	h.Write(salt)
+	h.Write(utf16LE(password))
+	hash := h.Sum(nil)
+	iteration := make([]byte, 4)
+	for i := 0; i < spinCount; i++ {
+		binary.LittleEndian.PutUint32(iteration, uint32(i))
+		h.Reset()
+		h.Write(hash)
+		h.Write(iteration)
+		hash = h.Sum(hash[:0])
+	}
+	return hash
+}
+
+// utf16LE returns s encoded as little endian UTF-16, the form in which
+// passwords are hashed.
+func utf16LE(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(units))
+	for i, unit := range units {
+		binary.LittleEndian.PutUint16(b[2*i:], unit)
+	}
+	return b
+}
+
+// legacyPasswordHash returns the 16 bit hash of a password, as
+// hexadecimal, that was used for protection before the SHA-512 hash.
+func legacyPasswordHash(password string) string {
+	var hash uint16
+	b := []byte(password)
+	for i := len(b) - 1; i >= 0; i-- {
+		hash = (hash>>14)&0x01 | (hash<<1)&0x7fff
+		hash ^= uint16(b[i])
+	}
+	hash = (hash>>14)&0x01 | (hash<<1)&0x7fff
+	hash ^= uint16(len(b))
+	hash ^= 0xce4b
+	return fmt.Sprintf("%04X", hash)
+}
+
+// ProtectStructure protects the structure of the File, so that its
+// sheets can't be added, deleted, renamed, moved or hidden when
+// lockStructure is set, and its windows can't be moved, resized or
+// closed when lockWindows is set.  As with Sheet.Protect, unless
+// password is empty it has to be given to remove the protection.
+func (f *File) ProtectStructure(password string, lockStructure, lockWindows bool) error {
+	protection := xlsxWorkbookProtection{
+		LockStructure: lockStructure,
+		LockWindows:   lockWindows,
+	}
+	if password != "" {
+		salt, err := newPasswordSalt()
+		if err != nil {
+			return err
+		}
+		protection.WorkbookPassword = legacyPasswordHash(password)
+		protection.WorkbookAlgorithmName = "SHA-512"
+		protection.WorkbookSaltValue = base64.StdEncoding.EncodeToString(salt)
+		protection.WorkbookSpinCount = passwordSpinCount
+		protection.WorkbookHashValue = base64.StdEncoding.EncodeToString(passwordHash(password, salt, passwordSpinCount))
+	}
+	f.workbookProtection = protection
+	return nil
+}
+
+// UnprotectStructure removes the protection from the structure of the
+// File.
+func (f *File) UnprotectStructure() {
+	f.workbookProtection = xlsxWorkbookProtection{}
+}
+
+// StructureProtection reports whether the structure and the windows
+// of the File are protected.
+func (f *File) StructureProtection() (lockStructure, lockWindows bool) {
+	return f.workbookProtection.LockStructure, f.workbookProtection.LockWindows
+}
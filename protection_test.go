@@ -0,0 +1,130 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type ProtectionSuite struct{}
+
+var _ = Suite(&ProtectionSuite{})
+
+func (s *ProtectionSuite) TestPasswordHashes(c *C) {
+	c.Assert(legacyPasswordHash("test"), Equals, "CBEB")
+	c.Assert(legacyPasswordHash("password"), Equals, "83AF")
+
+	salt := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	hash := base64.StdEncoding.EncodeToString(passwordHash("test", salt, passwordSpinCount))
+	c.Assert(hash, Equals, "kdzmKvUf75HfA139bmknTx3jS5fWiutEA6RBGAszO6u8TWVHxbvj+mCzRnoOrXQhYGnJ5tcghBKAX1PBRLZdtg==")
+}
+
+func (s *ProtectionSuite) TestProtectSheet(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	c.Assert(sheet.IsProtected(), Equals, false)
+	c.Assert(sheet.ProtectionOptions(), IsNil)
+
+	options := NewSheetProtectionOptions()
+	options.AllowFormatCells = true
+	options.AllowSort = true
+	c.Assert(sheet.Protect("test", options), IsNil)
+	c.Assert(sheet.IsProtected(), Equals, true)
+	c.Assert(*sheet.ProtectionOptions(), DeepEquals, *options)
+
+	salt, err := base64.StdEncoding.DecodeString(sheet.protection.SaltValue)
+	c.Assert(err, IsNil)
+	c.Assert(salt, HasLen, 16)
+	c.Assert(sheet.protection.HashValue, Equals, base64.StdEncoding.EncodeToString(passwordHash("test", salt, passwordSpinCount)))
+
+	xSheet := sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	output, err := xml.Marshal(xSheet)
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), `</sheetData><sheetProtection password="CBEB" algorithmName="SHA-512" hashValue="`+sheet.protection.HashValue+`" saltValue="`+sheet.protection.SaltValue+`" spinCount="100000" sheet="true" objects="true" scenarios="true" formatCells="false" formatColumns="true" formatRows="true" insertColumns="true" insertRows="true" insertHyperlinks="true" deleteColumns="true" deleteRows="true" selectLockedCells="false" sort="false" autoFilter="true" pivotTables="true" selectUnlockedCells="false"></sheetProtection>`), Equals, true)
+
+	sheet.Unprotect()
+	c.Assert(sheet.IsProtected(), Equals, false)
+	output, err = xml.Marshal(sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil)))
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(string(output), "sheetProtection"), Equals, false)
+}
+
+// Flags left out of a sheetProtection element take their defaults,
+// which for most of them is to prevent the action.
+func (s *ProtectionSuite) TestReadSheetProtection(c *C) {
+	worksheet := new(xlsxWorksheet)
+	err := xml.NewDecoder(strings.NewReader(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/><sheetProtection password="CBEB" sheet="1" objects="1" scenarios="1" sort="0"/></worksheet>`)).Decode(worksheet)
+	c.Assert(err, IsNil)
+	sheet := &Sheet{protection: worksheet.SheetProtection}
+	c.Assert(sheet.IsProtected(), Equals, true)
+	c.Assert(*sheet.ProtectionOptions(), DeepEquals, SheetProtectionOptions{
+		AllowSelectLockedCells:   true,
+		AllowSelectUnlockedCells: true,
+		AllowSort:                true,
+	})
+}
+
+// Protection, and the cell styles that go with it, survive being
+// written to, and read from, a file.
+func (s *ProtectionSuite) TestProtectionRoundTrip(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	input := sheet.AddRow().AddCell()
+	input.SetString("Enter a value")
+	style := NewStyle()
+	style.ApplyProtection = true
+	style.Protection.Locked = false
+	input.SetStyle(style)
+	c.Assert(sheet.Protect("secret", nil), IsNil)
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	sheet2 := f2.Sheet["Sheet1"]
+	c.Assert(sheet2.IsProtected(), Equals, true)
+	c.Assert(*sheet2.protection, DeepEquals, *sheet.protection)
+	style2 := sheet2.Rows[0].Cells[0].GetStyle()
+	c.Assert(style2.ApplyProtection, Equals, true)
+	c.Assert(style2.Protection, Equals, Protection{Locked: false, Hidden: false})
+}
+
+func (s *ProtectionSuite) TestProtectStructure(c *C) {
+	f := NewFile()
+	f.AddSheet("Sheet1")
+	lockStructure, lockWindows := f.StructureProtection()
+	c.Assert(lockStructure, Equals, false)
+	c.Assert(lockWindows, Equals, false)
+
+	c.Assert(f.ProtectStructure("test", true, false), IsNil)
+	protection := f.workbookProtection
+	salt, err := base64.StdEncoding.DecodeString(protection.WorkbookSaltValue)
+	c.Assert(err, IsNil)
+	c.Assert(protection.WorkbookHashValue, Equals, base64.StdEncoding.EncodeToString(passwordHash("test", salt, passwordSpinCount)))
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/workbook.xml"], `<workbookProtection workbookPassword="CBEB" lockStructure="true" workbookAlgorithmName="SHA-512" workbookHashValue="`+protection.WorkbookHashValue+`" workbookSaltValue="`+protection.WorkbookSaltValue+`" workbookSpinCount="100000"></workbookProtection>`), Equals, true)
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	c.Assert(f2.workbookProtection, DeepEquals, protection)
+	lockStructure, lockWindows = f2.StructureProtection()
+	c.Assert(lockStructure, Equals, true)
+	c.Assert(lockWindows, Equals, false)
+
+	f2.UnprotectStructure()
+	parts, err = f2.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/workbook.xml"], `<workbookProtection></workbookProtection>`), Equals, true)
+}
@@ -0,0 +1,486 @@
+package xlsx
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CellError is the error returned when the value of a cell can't be
+// read into, or written from, the field of a struct.
+type CellError struct {
+	Sheet string
+	Cell  string // Such as "B2".
+	Field string
+	Err   error
+}
+
+// Error returns a description of the CellError that says where it
+// happened.
+func (e *CellError) Error() string {
+	return fmt.Sprintf("sheet %q, cell %s, field %s: %s", e.Sheet, e.Cell, e.Field, e.Err)
+}
+
+// CellUnmarshaler is implemented by types that read themselves from
+// a cell, for ReadStruct.
+type CellUnmarshaler interface {
+	UnmarshalCell(cell *Cell) error
+}
+
+// structField is a field of a struct that is read from, or written to,
+// a cell, as described by its xlsx tag.
+type structField struct {
+	index     []int   // The index sequence of the field, for FieldByIndex.
+	name      string  // The name of the field in the struct.
+	header    string  // The header of the column the field is in, if any.
+	format    string  // The number format of the cell, if any.
+	width     float64 // The width of the column, if any, for WriteTable.
+	omitEmpty bool    // Whether empty values are left out of the cell.
+}
+
+var (
+	cellMarshalerType   = reflect.TypeOf((*CellMarshaler)(nil)).Elem()
+	cellUnmarshalerType = reflect.TypeOf((*CellUnmarshaler)(nil)).Elem()
+)
+
+// structFields returns the fields of the struct type t that are read
+// from, or written to, cells, in the order of their columns.  Their
+// tags, such as `xlsx:"Name,format=0.00,width=12,omitempty"`, can name
+// the header of their column, which can also be given as header=Name,
+// give the number format of their cells and the width of their
+// column, and leave empty values out of them.  Fields that are
+// unexported, or tagged `xlsx:"-"`, are left out, and the fields of
+// embedded structs, without a name in their tag, take the place of
+// the struct.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("xlsx")
+		if tag == "-" {
+			continue
+		}
+		field := structField{index: []int{i}, name: f.Name}
+		inFormat := false
+		for j, option := range strings.Split(tag, ",") {
+			switch {
+			case strings.HasPrefix(option, "header="):
+				field.header = strings.TrimPrefix(option, "header=")
+			case strings.HasPrefix(option, "format="):
+				field.format = strings.TrimPrefix(option, "format=")
+				inFormat = true
+				continue
+			case strings.HasPrefix(option, "width="):
+				field.width, _ = strconv.ParseFloat(strings.TrimPrefix(option, "width="), 64)
+			case option == "omitempty":
+				field.omitEmpty = true
+			case j == 0:
+				field.header = option
+			case inFormat:
+				// Formats, such as "#,##0", can have commas
+				// in them.
+				field.format += "," + option
+				continue
+			}
+			inFormat = false
+		}
+		fieldType := f.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if f.Anonymous && field.header == "" && fieldType.Kind() == reflect.Struct && fieldType != timeType &&
+			!reflect.PtrTo(fieldType).Implements(cellMarshalerType) &&
+			!reflect.PtrTo(fieldType).Implements(cellUnmarshalerType) {
+			if f.PkgPath != "" && f.Type.Kind() == reflect.Ptr {
+				continue
+			}
+			for _, embedded := range structFields(fieldType) {
+				embedded.index = append([]int{i}, embedded.index...)
+				fields = append(fields, embedded)
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// fieldByIndex returns the field of the struct v with the given index
+// sequence.  Nil pointers to embedded structs, on the way to it, are
+// filled in if alloc is set; otherwise ok is false when there are any.
+func fieldByIndex(v reflect.Value, index []int, alloc bool) (field reflect.Value, ok bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// hasHeaders reports whether any of fields names the header of its
+// column.
+func hasHeaders(fields []structField) bool {
+	for _, field := range fields {
+		if field.header != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// headerColumns returns the indexes of the cells of a header row,
+// keyed by their values.  The first of any cells with the same value
+// is used.
+func headerColumns(header *Row) map[string]int {
+	columns := make(map[string]int)
+	if header == nil {
+		return columns
+	}
+	for i, cell := range header.Cells {
+		name := strings.TrimSpace(cell.String())
+		if _, ok := columns[name]; !ok && name != "" {
+			columns[name] = i
+		}
+	}
+	return columns
+}
+
+// ReadStruct reads the cells of the Row into the fields of the struct
+// pointed to by ptr; it is the inverse of WriteStruct.  The fields are
+// read from the cells in the same order, unless their tag, such as
+// `xlsx:"Name"`, names the header, in the first row of the Sheet, of
+// the column to read them from.  Fields tagged `xlsx:"-"` are left
+// alone.
+//
+// Strings, bools, all kinds of number, time.Time, types that implement
+// CellUnmarshaler, and pointers to them, can be read.  Empty cells
+// leave fields with their zero value, and pointers nil.  If a cell
+// can't be read into its field, a *CellError says which.
+func (r *Row) ReadStruct(ptr interface{}) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("ReadStruct needs a pointer to a struct, not %T", ptr)
+	}
+	fields := structFields(v.Elem().Type())
+	var header *Row
+	if hasHeaders(fields) && r.Sheet != nil && len(r.Sheet.Rows) > 0 {
+		header = r.Sheet.Rows[0]
+	}
+	return r.readStruct(v.Elem(), fields, headerColumns(header))
+}
+
+// ReadAll reads the rows of the Sheet into the slice pointed to by
+// ptr, whose elements are structs, or pointers to them, that are read
+// as by Row.ReadStruct.  If any of their fields name the header of
+// their column, the first row is taken to be the header row, and
+// isn't read.  The first cell that can't be read stops the reading,
+// and a *CellError says which it was.
+func (s *Sheet) ReadAll(ptr interface{}) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("ReadAll needs a pointer to a slice, not %T", ptr)
+	}
+	slice := v.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("ReadAll needs a pointer to a slice of structs, not %T", ptr)
+	}
+	fields := structFields(structType)
+	rows := s.Rows
+	columns := headerColumns(nil)
+	if hasHeaders(fields) && len(rows) > 0 {
+		columns = headerColumns(rows[0])
+		rows = rows[1:]
+	}
+	result := reflect.MakeSlice(slice.Type(), 0, len(rows))
+	for _, row := range rows {
+		if row == nil {
+			continue
+		}
+		elem := reflect.New(structType)
+		if err := row.readStruct(elem.Elem(), fields, columns); err != nil {
+			return err
+		}
+		if elemType.Kind() != reflect.Ptr {
+			elem = elem.Elem()
+		}
+		result = reflect.Append(result, elem)
+	}
+	slice.Set(result)
+	return nil
+}
+
+// readStruct reads the cells of the Row into the fields of the struct
+// v.  columns gives the index of the column of each header.
+func (r *Row) readStruct(v reflect.Value, fields []structField, columns map[string]int) error {
+	for i, field := range fields {
+		col := i
+		if field.header != "" {
+			var ok bool
+			if col, ok = columns[field.header]; !ok {
+				return r.cellError(-1, field, fmt.Errorf("there is no column with the header %q", field.header))
+			}
+		}
+		var cell *Cell
+		if col < len(r.Cells) {
+			cell = r.Cells[col]
+		}
+		fieldValue, _ := fieldByIndex(v, field.index, true)
+		if err := r.readCell(cell, fieldValue); err != nil {
+			return r.cellError(col, field, err)
+		}
+	}
+	return nil
+}
+
+// cellError returns a CellError for the cell, at the index col, of the
+// Row.  The whole row is referred to when col is -1.
+func (r *Row) cellError(col int, field structField, err error) *CellError {
+	e := &CellError{Field: field.name, Err: err}
+	if r.Sheet == nil {
+		return e
+	}
+	e.Sheet = r.Sheet.Name
+	for y, row := range r.Sheet.Rows {
+		if row == r {
+			if col < 0 {
+				e.Cell = fmt.Sprintf("%d:%d", y+1, y+1)
+			} else {
+				e.Cell = getCellIDStringFromCoords(col, y)
+			}
+		}
+	}
+	return e
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// readCell reads the value of cell, which may be nil, into v.
+func (r *Row) readCell(cell *Cell, v reflect.Value) error {
+	if v.CanAddr() && v.Addr().Type().Implements(cellUnmarshalerType) {
+		if cell == nil {
+			cell = NewCell(r)
+		}
+		return v.Addr().Interface().(CellUnmarshaler).UnmarshalCell(cell)
+	}
+	if cell == nil || cell.Value == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := r.readCell(cell, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if v.Type() == timeType {
+		n, err := strconv.ParseFloat(cell.Value, 64)
+		if err != nil {
+			return fmt.Errorf("%q isn't a date", cell.Value)
+		}
+		v.Set(reflect.ValueOf(TimeFromExcelTime(n, cell.isDate1904())))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(cell.String())
+	case reflect.Bool:
+		b, err := strconv.ParseBool(cell.Value)
+		if err != nil {
+			return fmt.Errorf("%q isn't a bool", cell.Value)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(cell.Value, 10, 64)
+		if isRangeError(err) {
+			return fmt.Errorf("%s is out of range for %s", cell.Value, v.Type())
+		}
+		if err != nil {
+			f, err := parseWholeNumber(cell.Value)
+			if err != nil {
+				return err
+			}
+			if f < -(1<<63) || f >= 1<<63 {
+				return fmt.Errorf("%s is out of range for %s", cell.Value, v.Type())
+			}
+			n = int64(f)
+		}
+		if v.OverflowInt(n) {
+			return fmt.Errorf("%s is out of range for %s", cell.Value, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(cell.Value, 10, 64)
+		if isRangeError(err) {
+			return fmt.Errorf("%s is out of range for %s", cell.Value, v.Type())
+		}
+		if err != nil {
+			f, err := parseWholeNumber(cell.Value)
+			if err != nil {
+				return err
+			}
+			if f < 0 || f >= 1<<64 {
+				return fmt.Errorf("%s is out of range for %s", cell.Value, v.Type())
+			}
+			n = uint64(f)
+		}
+		if v.OverflowUint(n) {
+			return fmt.Errorf("%s is out of range for %s", cell.Value, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(cell.Value, 64)
+		if err != nil {
+			return fmt.Errorf("%q isn't a number", cell.Value)
+		}
+		if v.OverflowFloat(n) {
+			return fmt.Errorf("%s is out of range for %s", cell.Value, v.Type())
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("can't read a cell into a %s", v.Type())
+	}
+	return nil
+}
+
+// parseWholeNumber parses the value of a cell that is read into an
+// integer, which may be written as a float, such as "1e3" or "5.0".
+func parseWholeNumber(value string) (float64, error) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q isn't a number", value)
+	}
+	if n != math.Trunc(n) {
+		return 0, fmt.Errorf("%s isn't a whole number", value)
+	}
+	return n, nil
+}
+
+// isRangeError reports whether err is that of strconv for a number
+// that is out of range.
+func isRangeError(err error) bool {
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrRange
+}
+
+// ReadMapsOptions control how ReadMaps reads the rows of a Sheet.
+type ReadMapsOptions struct {
+	// NormalizeHeader, if set, is applied to each header, such as
+	// strings.ToLower, after the space around it is trimmed.
+	NormalizeHeader func(string) string
+	// AllowDuplicateHeaders lets more than one column have the same
+	// header, in which case the first of them is read.  Otherwise
+	// they are an error.
+	AllowDuplicateHeaders bool
+	// SkipBlankRows leaves out rows whose cells are all empty,
+	// rather than reading them as maps of empty cells.
+	SkipBlankRows bool
+	// StopAtBlankRow stops the reading at the first row whose cells
+	// are all empty.
+	StopAtBlankRow bool
+}
+
+// ReadMaps reads the rows of the Sheet below the header row, whose
+// zero based index is headerRow, as maps from the headers of the
+// columns, as their FormattedValue, to the cells in them.  Columns
+// without a header are left out, and cells that don't exist are read
+// as empty ones.  opts may be nil.
+func (s *Sheet) ReadMaps(headerRow int, opts *ReadMapsOptions) ([]map[string]*Cell, error) {
+	if headerRow < 0 || headerRow >= len(s.Rows) || s.Rows[headerRow] == nil {
+		return nil, fmt.Errorf("sheet %q has no header row %d", s.Name, headerRow)
+	}
+	options := ReadMapsOptions{}
+	if opts != nil {
+		options = *opts
+	}
+	columns := make(map[string]int)
+	for i, cell := range s.Rows[headerRow].Cells {
+		header := strings.TrimSpace(cell.FormattedValue())
+		if options.NormalizeHeader != nil {
+			header = options.NormalizeHeader(header)
+		}
+		if header == "" {
+			continue
+		}
+		if first, ok := columns[header]; ok {
+			if options.AllowDuplicateHeaders {
+				continue
+			}
+			return nil, fmt.Errorf("sheet %q has the header %q in columns %s and %s", s.Name, header,
+				numericToLetters(first), numericToLetters(i))
+		}
+		columns[header] = i
+	}
+
+	var maps []map[string]*Cell
+	for _, row := range s.Rows[headerRow+1:] {
+		if isBlankRow(row) {
+			if options.StopAtBlankRow {
+				break
+			}
+			if options.SkipBlankRows {
+				continue
+			}
+		}
+		values := make(map[string]*Cell, len(columns))
+		for header, i := range columns {
+			if row != nil && i < len(row.Cells) && row.Cells[i] != nil {
+				values[header] = row.Cells[i]
+			} else {
+				values[header] = new(Cell)
+			}
+		}
+		maps = append(maps, values)
+	}
+	return maps, nil
+}
+
+// ReadStringMaps is like ReadMaps, but reads the FormattedValue of
+// each cell rather than the cell itself.
+func (s *Sheet) ReadStringMaps(headerRow int, opts *ReadMapsOptions) ([]map[string]string, error) {
+	maps, err := s.ReadMaps(headerRow, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]string, len(maps))
+	for i, cells := range maps {
+		result[i] = make(map[string]string, len(cells))
+		for header, cell := range cells {
+			result[i][header] = cell.FormattedValue()
+		}
+	}
+	return result, nil
+}
+
+// isBlankRow reports whether row, which may be nil, has no cells with
+// a value.
+func isBlankRow(row *Row) bool {
+	if row == nil {
+		return true
+	}
+	for _, cell := range row.Cells {
+		if cell != nil && cell.Value != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,234 @@
+package xlsx
+
+import (
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type ReadSuite struct{}
+
+var _ = Suite(&ReadSuite{})
+
+// ReadStruct reads back what WriteStruct wrote.
+func (s *ReadSuite) TestReadStruct(c *C) {
+	type e struct {
+		FirstName string
+		Age       int
+		GPA       float64
+		LikesPHP  bool
+	}
+	sheet := NewFile().AddSheet("Test1")
+	row := sheet.AddRow()
+	written := e{"Eric", 20, 3.94, true}
+	row.WriteStruct(&written, -1)
+
+	var read e
+	c.Assert(row.ReadStruct(&read), IsNil)
+	c.Assert(read, DeepEquals, written)
+
+	c.Assert(row.ReadStruct(read), ErrorMatches, "ReadStruct needs a pointer to a struct, not xlsx.e")
+}
+
+func (s *ReadSuite) TestReadStructKinds(c *C) {
+	type e struct {
+		Small   int8
+		Big     uint64
+		Ratio   float32
+		When    time.Time
+		Maybe   *int
+		Missing *string
+		Skipped string `xlsx:"-"`
+		private string
+	}
+	f := NewFile()
+	sheet := f.AddSheet("Test1")
+	row := sheet.AddRow()
+	row.AddCell().SetInt(-7)
+	row.AddCell().SetInt64(1 << 40)
+	row.AddCell().SetFloat(0.5)
+	row.AddCell().SetFloat(42005.5)
+	row.AddCell().SetInt(3)
+	row.AddCell()
+	row.AddCell().SetString("skipped")
+	row.AddCell().SetString("private")
+
+	read := e{Skipped: "kept", Missing: new(string)}
+	c.Assert(row.ReadStruct(&read), IsNil)
+	c.Assert(read.Small, Equals, int8(-7))
+	c.Assert(read.Big, Equals, uint64(1<<40))
+	c.Assert(read.Ratio, Equals, float32(0.5))
+	c.Assert(read.When, Equals, time.Date(2015, 1, 1, 12, 0, 0, 0, time.UTC))
+	c.Assert(*read.Maybe, Equals, 3)
+	c.Assert(read.Missing, IsNil)
+	c.Assert(read.Skipped, Equals, "kept")
+	c.Assert(read.private, Equals, "")
+
+	// Dates count from 1904 when the File says so.
+	f.Date1904 = true
+	c.Assert(row.ReadStruct(&read), IsNil)
+	c.Assert(read.When, Equals, time.Date(2019, 1, 2, 12, 0, 0, 0, time.UTC))
+}
+
+func (s *ReadSuite) TestReadStructErrors(c *C) {
+	type e struct {
+		Name string
+		Age  int8
+	}
+	sheet := NewFile().AddSheet("People")
+	sheet.AddRow()
+	row := sheet.AddRow()
+	row.AddCell().SetString("Eric")
+	row.AddCell().SetString("twenty")
+
+	var read e
+	err := row.ReadStruct(&read)
+	c.Assert(err, FitsTypeOf, &CellError{})
+	c.Assert(err.(*CellError).Cell, Equals, "B2")
+	c.Assert(err, ErrorMatches, `sheet "People", cell B2, field Age: "twenty" isn't a number`)
+
+	row.Cells[1].SetInt(200)
+	c.Assert(row.ReadStruct(&read), ErrorMatches, `sheet "People", cell B2, field Age: 200 is out of range for int8`)
+}
+
+func (s *ReadSuite) TestReadAll(c *C) {
+	type person struct {
+		Name string    `xlsx:"header=Name"`
+		Born time.Time `xlsx:"header=Date of Birth"`
+		Pets *int      `xlsx:"header=Pets"`
+	}
+	sheet := NewFile().AddSheet("People")
+	for _, values := range [][]interface{}{
+		{"Pets", "Name", "Date of Birth"},
+		{2, "Ann", 29221},
+		{nil, "Bob", 32874},
+	} {
+		row := sheet.AddRow()
+		for _, value := range values {
+			cell := row.AddCell()
+			switch value := value.(type) {
+			case int:
+				cell.SetInt(value)
+			case string:
+				cell.SetString(value)
+			}
+		}
+	}
+
+	var people []person
+	c.Assert(sheet.ReadAll(&people), IsNil)
+	c.Assert(people, HasLen, 2)
+	c.Assert(people[0].Name, Equals, "Ann")
+	c.Assert(people[0].Born, Equals, time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC))
+	c.Assert(*people[0].Pets, Equals, 2)
+	c.Assert(people[1].Name, Equals, "Bob")
+	c.Assert(people[1].Pets, IsNil)
+
+	var pointers []*person
+	c.Assert(sheet.ReadAll(&pointers), IsNil)
+	c.Assert(pointers, HasLen, 2)
+	c.Assert(*pointers[1], DeepEquals, people[1])
+
+	type missing struct {
+		Age int `xlsx:"header=Age"`
+	}
+	var ages []missing
+	c.Assert(sheet.ReadAll(&ages), ErrorMatches, `sheet "People", cell 2:2, field Age: there is no column with the header "Age"`)
+	c.Assert(sheet.ReadAll(people), ErrorMatches, `ReadAll needs a pointer to a slice, not \[\]xlsx.person`)
+}
+
+// makeMapsSheet returns a Sheet with a title, a header row and some
+// rows below it, one of them blank.
+func makeMapsSheet() *Sheet {
+	sheet := NewFile().AddSheet("Imports")
+	for _, values := range []interface{}{
+		[]string{"Imported today"},
+		[]string{" Name ", "", "Age", "name"},
+		[]string{"Ann", "x", "30"},
+		nil,
+		[]string{"Bob"},
+	} {
+		row := sheet.AddRow()
+		if values != nil {
+			for _, value := range values.([]string) {
+				row.AddCell().SetString(value)
+			}
+		}
+	}
+	sheet.Rows[2].Cells[2].SetInt(30)
+	return sheet
+}
+
+func (s *ReadSuite) TestReadMaps(c *C) {
+	sheet := makeMapsSheet()
+	maps, err := sheet.ReadMaps(1, nil)
+	c.Assert(err, IsNil)
+	c.Assert(maps, HasLen, 3)
+	c.Assert(maps[0], HasLen, 3)
+	c.Assert(maps[0]["Name"], Equals, sheet.Rows[2].Cells[0])
+	c.Assert(maps[0]["Age"].FormattedValue(), Equals, "30")
+	c.Assert(maps[0]["name"].Value, Equals, "")
+	c.Assert(maps[1]["Name"].Value, Equals, "")
+	c.Assert(maps[2]["Name"].Value, Equals, "Bob")
+
+	maps, err = sheet.ReadMaps(1, &ReadMapsOptions{SkipBlankRows: true})
+	c.Assert(err, IsNil)
+	c.Assert(maps, HasLen, 2)
+	maps, err = sheet.ReadMaps(1, &ReadMapsOptions{StopAtBlankRow: true})
+	c.Assert(err, IsNil)
+	c.Assert(maps, HasLen, 1)
+
+	_, err = sheet.ReadMaps(5, nil)
+	c.Assert(err, ErrorMatches, `sheet "Imports" has no header row 5`)
+}
+
+func (s *ReadSuite) TestReadMapsDuplicateHeaders(c *C) {
+	sheet := makeMapsSheet()
+	_, err := sheet.ReadMaps(1, &ReadMapsOptions{NormalizeHeader: strings.ToLower})
+	c.Assert(err, ErrorMatches, `sheet "Imports" has the header "name" in columns A and D`)
+
+	maps, err := sheet.ReadStringMaps(1, &ReadMapsOptions{NormalizeHeader: strings.ToLower, AllowDuplicateHeaders: true, SkipBlankRows: true})
+	c.Assert(err, IsNil)
+	c.Assert(maps, DeepEquals, []map[string]string{
+		{"name": "Ann", "age": "30"},
+		{"name": "Bob", "age": ""}})
+}
+
+// Integers are read exactly, and numbers that aren't whole, or are
+// too big, are errors rather than being cut down to size.
+func (s *ReadSuite) TestReadStructIntegers(c *C) {
+	type numbers struct {
+		Int  int64
+		Uint uint64
+	}
+	sheet := NewFile().AddSheet("Numbers")
+	row := sheet.AddRow()
+	row.AddCell().SetString("9007199254740993")
+	row.AddCell().SetString("18446744073709551615")
+	var read numbers
+	c.Assert(row.ReadStruct(&read), IsNil)
+	c.Assert(read.Int, Equals, int64(9007199254740993))
+	c.Assert(read.Uint, Equals, uint64(18446744073709551615))
+
+	row.Cells[0].SetString("1e3")
+	row.Cells[1].SetString("2.0")
+	c.Assert(row.ReadStruct(&read), IsNil)
+	c.Assert(read.Int, Equals, int64(1000))
+	c.Assert(read.Uint, Equals, uint64(2))
+
+	for _, test := range []struct{ int, uint, err string }{
+		{"1.7", "1", `sheet "Numbers", cell A1, field Int: 1.7 isn't a whole number`},
+		{"1e20", "1", `sheet "Numbers", cell A1, field Int: 1e20 is out of range for int64`},
+		{"-9223372036854775809", "1", `sheet "Numbers", cell A1, field Int: -9223372036854775809 is out of range for int64`},
+		{"1", "0.5", `sheet "Numbers", cell B1, field Uint: 0.5 isn't a whole number`},
+		{"1", "-1", `sheet "Numbers", cell B1, field Uint: -1 is out of range for uint64`},
+		{"1", "2e19", `sheet "Numbers", cell B1, field Uint: 2e19 is out of range for uint64`},
+	} {
+		row.Cells[0].SetString(test.int)
+		row.Cells[1].SetString(test.uint)
+		err := row.ReadStruct(&read)
+		c.Assert(err, FitsTypeOf, &CellError{})
+		c.Assert(err, ErrorMatches, test.err)
+	}
+}
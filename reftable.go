@@ -1,15 +1,23 @@
 package xlsx
 
+import "encoding/xml"
+
 type RefTable struct {
 	indexedStrings []string
 	knownStrings   map[string]int
-	isWrite        bool
+	// Shared strings made up of formatted runs are held, by index,
+	// in richText as well as indexedStrings.
+	richText      map[int]RichText
+	knownRichText map[string]int
+	isWrite       bool
 }
 
 // NewSharedStringRefTable() creates a new, empty RefTable.
 func NewSharedStringRefTable() *RefTable {
 	rt := RefTable{}
 	rt.knownStrings = make(map[string]int)
+	rt.richText = make(map[int]RichText)
+	rt.knownRichText = make(map[string]int)
 	return &rt
 }
 
@@ -20,13 +28,10 @@ func NewSharedStringRefTable() *RefTable {
 func MakeSharedStringRefTable(source *xlsxSST) *RefTable {
 	reftable := NewSharedStringRefTable()
 	reftable.isWrite = false
-	for _, si := range source.SI {
-		if len(si.R) > 0 {
-			newString := ""
-			for j := 0; j < len(si.R); j++ {
-				newString = newString + si.R[j].T
-			}
-			reftable.AddString(newString)
+	for i := range source.SI {
+		si := &source.SI[i]
+		if richText := makeRichText(si); richText != nil {
+			reftable.AddRichText(richText)
 		} else {
 			reftable.AddString(si.T)
 		}
@@ -40,9 +45,13 @@ func (rt *RefTable) makeXLSXSST() xlsxSST {
 	sst := xlsxSST{}
 	sst.Count = len(rt.indexedStrings)
 	sst.UniqueCount = sst.Count
-	for _, ref := range rt.indexedStrings {
+	for i, ref := range rt.indexedStrings {
 		si := xlsxSI{}
-		si.T = ref
+		if richText, ok := rt.richText[i]; ok {
+			si = richText.makeXLSXSI()
+		} else {
+			si.T = ref
+		}
 		sst.SI = append(sst.SI, si)
 	}
 	return sst
@@ -72,6 +81,34 @@ func (rt *RefTable) AddString(str string) int {
 	return index
 }
 
+// ResolveSharedRichText looks up the formatted runs of a shared
+// string by numeric index, returning nil if the string is plain text.
+func (rt *RefTable) ResolveSharedRichText(index int) RichText {
+	return rt.richText[index]
+}
+
+// AddRichText adds a string made up of formatted runs to the
+// reference table and returns its numeric index.  As with AddString,
+// if the same runs, with the same formatting, already exist then it
+// simply returns the existing index.
+func (rt *RefTable) AddRichText(richText RichText) int {
+	// The runs are keyed by their XML, as some of their formatting
+	// is held by pointers.
+	output, _ := xml.Marshal(richText.makeXLSXSI())
+	key := string(output)
+	if rt.isWrite {
+		index, ok := rt.knownRichText[key]
+		if ok {
+			return index
+		}
+	}
+	rt.indexedStrings = append(rt.indexedStrings, richText.String())
+	index := len(rt.indexedStrings) - 1
+	rt.richText[index] = richText
+	rt.knownRichText[key] = index
+	return index
+}
+
 func (rt *RefTable) Length() int {
 	return len(rt.indexedStrings)
 }
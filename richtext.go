@@ -0,0 +1,196 @@
+package xlsx
+
+import "strconv"
+
+// RichText is the value of a cell made up of runs of text, each of
+// which can be formatted differently, for example to make a single
+// word bold.
+type RichText []RichTextRun
+
+// RichTextRun is a run of text within a RichText, along with its
+// formatting.
+type RichTextRun struct {
+	Font RichTextFont
+	Text string
+}
+
+// RichTextFont is the formatting of a RichTextRun.  Anything left at
+// its zero value isn't set on the run, which then takes it from the
+// style of the cell instead.
+type RichTextFont struct {
+	Name string
+	Size float64
+	// Color is ARGB, for example "FFFF0000" for red.  Otherwise
+	// ThemeColor or IndexedColor, if set, pick a colour of the theme
+	// or of the legacy palette, which ColorTint lightens, when it is
+	// positive, or darkens.
+	Color        string
+	ThemeColor   *int
+	IndexedColor *int
+	ColorTint    float64
+	Bold         bool
+	Italic       bool
+	Underline    bool
+	// UnderlineStyle is "double", "singleAccounting" or
+	// "doubleAccounting" for an Underline that isn't a single line.
+	UnderlineStyle string
+	Strike         bool
+	VertAlign      string // "superscript", "subscript" or "baseline".
+	// Family, Charset and Scheme ("major" or "minor") say more
+	// about the font.  Charset is a pointer as 0, ANSI, is a
+	// character set of its own.
+	Family  int
+	Charset *int
+	Scheme  string
+	// Outline, Shadow, Condense and Extend are effects that only
+	// some applications show.
+	Outline  bool
+	Shadow   bool
+	Condense bool
+	Extend   bool
+}
+
+// String returns the text of the RichText, without its formatting.
+func (rt RichText) String() string {
+	result := ""
+	for _, run := range rt {
+		result += run.Text
+	}
+	return result
+}
+
+// makeXLSXSI returns the xlsxSI representation of the RichText.
+func (rt RichText) makeXLSXSI() xlsxSI {
+	si := xlsxSI{}
+	si.R = make([]xlsxR, len(rt))
+	for i, run := range rt {
+		si.R[i].RPr = run.Font.makeXLSXRunProperties()
+		si.R[i].T = makeXLSXT(run.Text)
+	}
+	return si
+}
+
+// makeRichText returns the RichText held by an xlsxSI, or nil if it
+// holds plain text rather than runs.
+func makeRichText(si *xlsxSI) RichText {
+	if si == nil || len(si.R) == 0 {
+		return nil
+	}
+	rt := make(RichText, len(si.R))
+	for i, r := range si.R {
+		rt[i].Text = r.T.Text
+		if r.RPr != nil {
+			rt[i].Font = r.RPr.makeRichTextFont()
+		}
+	}
+	return rt
+}
+
+// makeXLSXRunProperties returns the rPr element for the font, or nil
+// if it doesn't set anything.
+func (font *RichTextFont) makeXLSXRunProperties() *xlsxRunProperties {
+	if *font == (RichTextFont{}) {
+		return nil
+	}
+	rPr := &xlsxRunProperties{
+		B:        makeXLSXBoolProperty(font.Bold),
+		I:        makeXLSXBoolProperty(font.Italic),
+		Strike:   makeXLSXBoolProperty(font.Strike),
+		Outline:  makeXLSXBoolProperty(font.Outline),
+		Shadow:   makeXLSXBoolProperty(font.Shadow),
+		Condense: makeXLSXBoolProperty(font.Condense),
+		Extend:   makeXLSXBoolProperty(font.Extend),
+	}
+	if font.Underline {
+		rPr.U = &xlsxVal{}
+		if font.UnderlineStyle != "single" {
+			rPr.U.Val = font.UnderlineStyle
+		}
+	}
+	if font.VertAlign != "" {
+		rPr.VertAlign = &xlsxVal{Val: font.VertAlign}
+	}
+	if font.Size != 0 {
+		rPr.Sz = &xlsxVal{Val: strconv.FormatFloat(font.Size, 'f', -1, 64)}
+	}
+	if font.Color != "" || font.ThemeColor != nil || font.IndexedColor != nil {
+		rPr.Color = &xlsxColor{RGB: font.Color, Tint: font.ColorTint}
+		if font.Color == "" {
+			rPr.Color.Theme = font.ThemeColor
+			rPr.Color.Indexed = font.IndexedColor
+		}
+	}
+	if font.Name != "" {
+		rPr.RFont = &xlsxVal{Val: font.Name}
+	}
+	if font.Family != 0 {
+		rPr.Family = &xlsxVal{Val: strconv.Itoa(font.Family)}
+	}
+	if font.Charset != nil {
+		rPr.Charset = &xlsxVal{Val: strconv.Itoa(*font.Charset)}
+	}
+	if font.Scheme != "" {
+		rPr.Scheme = &xlsxVal{Val: font.Scheme}
+	}
+	return rPr
+}
+
+// makeXLSXBoolProperty returns an element, such as b, that sets a
+// property of a font when it is present, or nil if set is false.
+func makeXLSXBoolProperty(set bool) *xlsxVal {
+	if !set {
+		return nil
+	}
+	return &xlsxVal{}
+}
+
+// isSet reports whether an element, such as b, sets a property of a
+// font: it does if it is present, unless its val says otherwise.
+func (val *xlsxVal) isSet() bool {
+	return val != nil && val.Val != "0" && val.Val != "false"
+}
+
+// makeRichTextFont returns the formatting described by an rPr element.
+func (rPr *xlsxRunProperties) makeRichTextFont() RichTextFont {
+	font := RichTextFont{}
+	font.Bold = rPr.B.isSet()
+	font.Italic = rPr.I.isSet()
+	font.Strike = rPr.Strike.isSet()
+	font.Outline = rPr.Outline.isSet()
+	font.Shadow = rPr.Shadow.isSet()
+	font.Condense = rPr.Condense.isSet()
+	font.Extend = rPr.Extend.isSet()
+	if rPr.U != nil && rPr.U.Val != "none" {
+		font.Underline = true
+		if rPr.U.Val != "single" {
+			font.UnderlineStyle = rPr.U.Val
+		}
+	}
+	if rPr.VertAlign != nil {
+		font.VertAlign = rPr.VertAlign.Val
+	}
+	if rPr.Sz != nil {
+		font.Size, _ = strconv.ParseFloat(rPr.Sz.Val, 64)
+	}
+	if rPr.Color != nil {
+		font.Color = rPr.Color.RGB
+		font.ThemeColor = rPr.Color.Theme
+		font.IndexedColor = rPr.Color.Indexed
+		font.ColorTint = rPr.Color.Tint
+	}
+	if rPr.RFont != nil {
+		font.Name = rPr.RFont.Val
+	}
+	if rPr.Family != nil {
+		font.Family, _ = strconv.Atoi(rPr.Family.Val)
+	}
+	if rPr.Charset != nil {
+		if charset, err := strconv.Atoi(rPr.Charset.Val); err == nil {
+			font.Charset = &charset
+		}
+	}
+	if rPr.Scheme != nil {
+		font.Scheme = rPr.Scheme.Val
+	}
+	return font
+}
@@ -0,0 +1,160 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type RichTextSuite struct{}
+
+var _ = Suite(&RichTextSuite{})
+
+func (s *RichTextSuite) TestSetRichText(c *C) {
+	cell := NewCell(nil)
+	c.Assert(cell.RichText(), IsNil)
+	richText := RichText{
+		{Text: "Partly "},
+		{Font: RichTextFont{Bold: true}, Text: "bold"},
+	}
+	cell.SetRichText(richText)
+	c.Assert(cell.Value, Equals, "Partly bold")
+	c.Assert(cell.Type(), Equals, CellTypeString)
+	c.Assert(cell.RichText(), DeepEquals, richText)
+
+	// Changing the value discards the runs.
+	cell.Value = "Plain"
+	c.Assert(cell.RichText(), IsNil)
+	cell.SetRichText(richText)
+	cell.SetString("Plain")
+	c.Assert(cell.RichText(), IsNil)
+}
+
+// The runs are marshalled with only the formatting they set, and
+// whitespace at their edges is preserved.
+func (s *RichTextSuite) TestMarshalRichText(c *C) {
+	richText := RichText{
+		{Text: "Partly "},
+		{Font: RichTextFont{
+			Name:      "Arial",
+			Size:      10.5,
+			Color:     "FFFF0000",
+			Bold:      true,
+			Italic:    true,
+			Underline: true,
+			VertAlign: "superscript"}, Text: "formatted"},
+	}
+	si := richText.makeXLSXSI()
+	output, err := xml.Marshal(si)
+	c.Assert(err, IsNil)
+	c.Assert(string(output), Equals, `<xlsxSI><r><t xml:space="preserve">Partly </t></r><r><rPr><b></b><i></i><u></u><vertAlign val="superscript"></vertAlign><sz val="10.5"></sz><color rgb="FFFF0000"></color><rFont val="Arial"></rFont></rPr><t>formatted</t></r></xlsxSI>`)
+
+	c.Assert(makeRichText(&si), DeepEquals, richText)
+	c.Assert(makeRichText(&xlsxSI{T: "Plain"}), IsNil)
+}
+
+// Shared strings made up of runs are read into the RefTable along
+// with their formatting, and written back out the same way.
+func (s *RichTextSuite) TestRefTableRichText(c *C) {
+	sharedStringsXML := bytes.NewBufferString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="2" uniqueCount="2">
+  <si><t>Plain</t></si>
+  <si><r><rPr><b/><sz val="11"/><color rgb="FF0000FF"/><rFont val="Calibri"/><family val="2"/></rPr><t>Bold</t></r><r><t xml:space="preserve"> and not</t></r></si>
+</sst>`)
+	sst := new(xlsxSST)
+	err := xml.NewDecoder(sharedStringsXML).Decode(sst)
+	c.Assert(err, IsNil)
+	refTable := MakeSharedStringRefTable(sst)
+	c.Assert(refTable.ResolveSharedString(0), Equals, "Plain")
+	c.Assert(refTable.ResolveSharedRichText(0), IsNil)
+	c.Assert(refTable.ResolveSharedString(1), Equals, "Bold and not")
+	c.Assert(refTable.ResolveSharedRichText(1), DeepEquals, RichText{
+		{Font: RichTextFont{Name: "Calibri", Size: 11, Color: "FF0000FF", Bold: true, Family: 2}, Text: "Bold"},
+		{Text: " and not"},
+	})
+
+	output, err := xml.Marshal(refTable.makeXLSXSST())
+	c.Assert(err, IsNil)
+	c.Assert(string(output), Equals, `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" count="2" uniqueCount="2"><si><t>Plain</t></si><si><r><rPr><b></b><sz val="11"></sz><color rgb="FF0000FF"></color><rFont val="Calibri"></rFont><family val="2"></family></rPr><t>Bold</t></r><r><t xml:space="preserve"> and not</t></r></si></sst>`)
+}
+
+// All of the formatting of a run is read, and written back as it was.
+func (s *RichTextSuite) TestRunPropertiesRoundTrip(c *C) {
+	rPrXML := `<rPr><b val="0"/><i/><strike val="1"/><shadow/><u val="double"/><sz val="9"/><color theme="4" tint="-0.25"/><rFont val="Cambria"/><family val="1"/><charset val="0"/><scheme val="major"/></rPr>`
+	rPr := new(xlsxRunProperties)
+	c.Assert(xml.Unmarshal([]byte(rPrXML), rPr), IsNil)
+	font := rPr.makeRichTextFont()
+	theme, charset := 4, 0
+	c.Assert(font, DeepEquals, RichTextFont{
+		Name:           "Cambria",
+		Size:           9,
+		ThemeColor:     &theme,
+		ColorTint:      -0.25,
+		Italic:         true,
+		Underline:      true,
+		UnderlineStyle: "double",
+		Strike:         true,
+		Family:         1,
+		Charset:        &charset,
+		Scheme:         "major",
+		Shadow:         true})
+
+	output, err := xml.Marshal(font.makeXLSXRunProperties())
+	c.Assert(err, IsNil)
+	c.Assert(string(output), Equals, `<xlsxRunProperties><i></i><strike></strike><shadow></shadow><u val="double"></u><sz val="9"></sz><color theme="4" tint="-0.25"></color><rFont val="Cambria"></rFont><family val="1"></family><charset val="0"></charset><scheme val="major"></scheme></xlsxRunProperties>`)
+
+	// Runs formatted alike are shared, even though their colours
+	// are held by pointers.
+	refTable := NewSharedStringRefTable()
+	refTable.isWrite = true
+	other := font
+	otherTheme := 4
+	other.ThemeColor = &otherTheme
+	c.Assert(refTable.AddRichText(RichText{{Font: font, Text: "x"}}), Equals, 0)
+	c.Assert(refTable.AddRichText(RichText{{Font: other, Text: "x"}}), Equals, 0)
+}
+
+// When writing, identical rich text is shared, but isn't confused
+// with plain text of the same value.
+func (s *RichTextSuite) TestAddRichText(c *C) {
+	refTable := NewSharedStringRefTable()
+	refTable.isWrite = true
+	bold := RichText{{Font: RichTextFont{Bold: true}, Text: "Foo"}}
+	c.Assert(refTable.AddString("Foo"), Equals, 0)
+	c.Assert(refTable.AddRichText(bold), Equals, 1)
+	c.Assert(refTable.AddRichText(RichText{{Font: RichTextFont{Bold: true}, Text: "Foo"}}), Equals, 1)
+	c.Assert(refTable.AddRichText(RichText{{Font: RichTextFont{Italic: true}, Text: "Foo"}}), Equals, 2)
+	c.Assert(refTable.AddString("Foo"), Equals, 0)
+}
+
+// Rich text survives being written to, and read from, a file, both as
+// a shared string and inline.
+func (s *RichTextSuite) TestRichTextRoundTrip(c *C) {
+	richText := RichText{
+		{Font: RichTextFont{Italic: true, Color: "FF00FF00"}, Text: "E = mc"},
+		{Font: RichTextFont{VertAlign: "superscript"}, Text: "2"},
+	}
+	for _, inline := range []bool{false, true} {
+		f := NewFile()
+		f.InlineStrings = inline
+		sheet := f.AddSheet("Sheet1")
+		sheet.AddRow().AddCell().SetRichText(richText)
+
+		parts, err := f.MarshallParts()
+		c.Assert(err, IsNil)
+		c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<vertAlign val="superscript">`), Equals, inline)
+
+		var buf bytes.Buffer
+		c.Assert(f.Write(&buf), IsNil)
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		c.Assert(err, IsNil)
+		f2, err := ReadZipReader(zr)
+		c.Assert(err, IsNil)
+		cell := f2.Sheets[0].Cell(0, 0)
+		c.Assert(cell.Value, Equals, "E = mc2")
+		c.Assert(cell.RichText(), DeepEquals, richText)
+	}
+}
@@ -3,7 +3,19 @@ package xlsx
 type Row struct {
 	Cells  []*Cell
 	Hidden bool
-	Sheet  *Sheet
+	// Height is the height of the row in points, or 0 for the
+	// default height.
+	Height float64
+	// CustomHeight is whether Height was set by hand, rather than
+	// fitted to the contents of the row.
+	CustomHeight bool
+	Sheet        *Sheet
+}
+
+// SetHeight sets the height of the row in points, by hand.
+func (r *Row) SetHeight(height float64) {
+	r.Height = height
+	r.CustomHeight = true
 }
 
 func (r *Row) AddCell() *Cell {
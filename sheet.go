@@ -1,8 +1,10 @@
 package xlsx
 
 import (
+	"encoding/xml"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // Sheet is a high level structure intended to provide user access to
@@ -15,7 +17,53 @@ type Sheet struct {
 	MaxRow int
 	MaxCol int
 	Hidden bool
+	// veryHidden is whether the Sheet, when it is Hidden, was read
+	// as one that can't be unhidden from Excel's user interface.
+	veryHidden bool
 	SheetViews []SheetView
+	// PageSetup and PageMargins control how the Sheet is printed.
+	// When they're nil NewPageSetup and NewPageMargins are used.
+	PageSetup   *PageSetup
+	PageMargins *PageMargins
+	// HeaderFooter is printed on each page of the Sheet.  When it
+	// is nil NewHeaderFooter is used.
+	HeaderFooter *HeaderFooter
+	// rowBreaks and colBreaks are the rows and columns, in
+	// ascending order, that manual page breaks come before.
+	rowBreaks []int
+	colBreaks []int
+	// otherRowBreaks and otherColBreaks are the page breaks, read
+	// with the Sheet, that aren't manual ones, which are written
+	// back out as they were.
+	otherRowBreaks []xlsxBreak
+	otherColBreaks []xlsxBreak
+	// protection is how the Sheet is protected, or nil if it isn't.
+	protection *xlsxSheetProtection
+	// pictures and charts are those drawn on the Sheet.
+	pictures []*Picture
+	charts   []*sheetChart
+	// retainedPictures is the number of pictures, at the start of
+	// pictures, that are in the drawing read with the Sheet.
+	retainedPictures int
+	// sparklineGroups are the groups of sparklines on the Sheet.
+	sparklineGroups []*SparklineGroup
+	// retainedSparklineGroups is the number of sparkline groups, at
+	// the start of sparklineGroups, that are in the extLst read
+	// with the Sheet.
+	retainedSparklineGroups int
+	// pivotTables are the pivot tables on the Sheet.
+	pivotTables []*PivotTable
+	// tables are the Excel tables added to the Sheet, and
+	// autoFilter the range, if any, with filter buttons on its
+	// first row.
+	tables     []*sheetTable
+	autoFilter string
+
+	// unmodelled holds the elements of the worksheet we don't model,
+	// and rels the worksheet's relationships, when the Sheet has been
+	// read from a file, so that they can be written back out.
+	unmodelled *xlsxWorksheet
+	rels       string
 }
 
 type SheetView struct {
@@ -75,7 +123,7 @@ func (sh *Sheet) Cell(row, col int) *Cell {
 //Set the width of a single column or multipel columns.
 func (s *Sheet) SetColWidth(startcol, endcol int, width float64) error {
 	if startcol > endcol {
-		return fmt.Errorf("Could not set width for range %g-%g: startcol must be less than endcol.", startcol, endcol)
+		return fmt.Errorf("Could not set width for range %d-%d: startcol must be less than endcol.", startcol, endcol)
 	}
 	col := &Col{
 		Min:       startcol + 1,
@@ -91,6 +139,18 @@ func (s *Sheet) SetColWidth(startcol, endcol int, width float64) error {
 	return nil
 }
 
+// state returns the state the Sheet is written with: visible, hidden
+// or, if it was read as such, veryHidden.
+func (s *Sheet) state() string {
+	switch {
+	case !s.Hidden:
+		return sheetStateVisible
+	case s.veryHidden:
+		return sheetStateVeryHidden
+	}
+	return sheetStateHidden
+}
+
 // Dump sheet to it's XML representation, intended for internal use only
 func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxWorksheet {
 	worksheet := newXlsxWorksheet()
@@ -98,29 +158,21 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 	maxRow := 0
 	maxCell := 0
 	XfId := 0
+	inlineStrings := s.File != nil && s.File.InlineStrings
 	for r, row := range s.Rows {
 		if r > maxRow {
 			maxRow = r
 		}
 		xRow := xlsxRow{}
 		xRow.R = r + 1
+		if row.Height > 0 {
+			xRow.Ht = row.Height
+			xRow.CustomHeight = row.CustomHeight
+		}
 		for c, cell := range row.Cells {
 			style := cell.GetStyle()
 			if style != nil {
-				xFont, xFill, xBorder, xCellStyleXf, xCellXf := style.makeXLSXStyleElements()
-				fontId := styles.addFont(xFont)
-				fillId := styles.addFill(xFill)
-				borderId := styles.addBorder(xBorder)
-				xCellStyleXf.FontId = fontId
-				xCellStyleXf.FillId = fillId
-				xCellStyleXf.BorderId = borderId
-				xCellStyleXf.NumFmtId = 0 // General
-				xCellXf.FontId = fontId
-				xCellXf.FillId = fillId
-				xCellXf.BorderId = borderId
-				xCellXf.NumFmtId = 0 // General
-				styles.addCellStyleXf(xCellStyleXf)
-				XfId = styles.addCellXf(xCellXf)
+				XfId = styles.addStyle(style, cell.numFmt)
 			}
 			if c > maxCell {
 				maxCell = c
@@ -128,9 +180,23 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 			xC := xlsxC{}
 			xC.R = fmt.Sprintf("%s%d", numericToLetters(c), r+1)
 			switch cell.cellType {
-			case CellTypeString:
-				xC.V = strconv.Itoa(refTable.AddString(cell.Value))
-				xC.T = "s"
+			case CellTypeString, CellTypeInline:
+				richText := cell.RichText()
+				switch {
+				case inlineStrings || cell.cellType == CellTypeInline:
+					xC.Is = &xlsxSI{T: cell.Value}
+					if richText != nil {
+						si := richText.makeXLSXSI()
+						xC.Is = &si
+					}
+					xC.T = "inlineStr"
+				case richText != nil:
+					xC.V = strconv.Itoa(refTable.AddRichText(richText))
+					xC.T = "s"
+				default:
+					xC.V = strconv.Itoa(refTable.AddString(cell.Value))
+					xC.T = "s"
+				}
 				xC.S = XfId
 			case CellTypeBool:
 				xC.V = cell.Value
@@ -156,6 +222,11 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 
 	worksheet.Cols = xlsxCols{Col: []xlsxCol{}}
 	for _, col := range s.Cols {
+		if col.Min == 0 {
+			// A placeholder for a column that had no
+			// definition in the file it was read from.
+			continue
+		}
 		if col.Width == 0 {
 			col.Width = ColWidth
 		}
@@ -169,6 +240,19 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 			})
 	}
 	worksheet.SheetData = xSheet
+	if len(s.SheetViews) > 0 && s.SheetViews[0].Pane != nil {
+		pane := s.SheetViews[0].Pane
+		sheetView := &worksheet.SheetViews.SheetView[0]
+		sheetView.Pane = &xlsxPane{
+			XSplit:      pane.XSplit,
+			YSplit:      pane.YSplit,
+			TopLeftCell: pane.TopLeftCell,
+			ActivePane:  pane.ActivePane,
+			State:       pane.State}
+		if pane.ActivePane != "" {
+			sheetView.Selection[0].Pane = pane.ActivePane
+		}
+	}
 	dimension := xlsxDimension{}
 	dimension.Ref = fmt.Sprintf("A1:%s%d",
 		numericToLetters(maxCell), maxRow+1)
@@ -176,5 +260,98 @@ func (s *Sheet) makeXLSXSheet(refTable *RefTable, styles *xlsxStyleSheet) *xlsxW
 		dimension.Ref = "A1"
 	}
 	worksheet.Dimension = dimension
+	s.makeXLSXPageSetup(worksheet)
+	if s.HeaderFooter != nil {
+		worksheet.HeaderFooter = s.HeaderFooter.makeXLSXHeaderFooter()
+	}
+	worksheet.SheetProtection = s.protection
+	worksheet.RowBreaks = makeXLSXPageBreaks(s.rowBreaks, s.otherRowBreaks, 16383)
+	worksheet.ColBreaks = makeXLSXPageBreaks(s.colBreaks, s.otherColBreaks, 1048575)
+	if s.unmodelled != nil {
+		worksheet.copyUnmodelled(s.unmodelled)
+	}
+	if s.autoFilter != "" {
+		worksheet.AutoFilter = &xlsxRawXML{
+			XMLName: xml.Name{Local: "autoFilter"},
+			Attrs:   []xml.Attr{{Name: xml.Name{Local: "ref"}, Value: s.autoFilter}}}
+	}
+	worksheet.ExtLst = makeXLSXSparklineExtLst(worksheet.ExtLst, s.sparklineGroups[s.retainedSparklineGroups:])
 	return worksheet
 }
+
+// cellRange is a range of cells, by the zero based coordinates of its
+// top left and bottom right cells.
+type cellRange struct {
+	x1, y1, x2, y2 int
+}
+
+// rangeBounds returns the range of cells that ref, such as "B2:D10"
+// or "Sheet1!B2:D10", refers to on the Sheet, or, if ref is empty, the
+// range from A1 to the last cell of the longest row.
+func (s *Sheet) rangeBounds(ref string) (cellRange, error) {
+	if ref == "" {
+		bounds := cellRange{0, 0, -1, len(s.Rows) - 1}
+		for _, row := range s.Rows {
+			if row != nil && len(row.Cells)-1 > bounds.x2 {
+				bounds.x2 = len(row.Cells) - 1
+			}
+		}
+		return bounds, nil
+	}
+	var bounds cellRange
+	sheet, ok := s.Name, false
+	if strings.Contains(ref, "!") {
+		sheet, bounds.x1, bounds.y1, bounds.x2, bounds.y2, ok = parseRangeRef(ref)
+	} else {
+		bounds.x1, bounds.y1, bounds.x2, bounds.y2, ok = parseCellRange(ref)
+	}
+	if !ok || sheet != s.Name {
+		return bounds, fmt.Errorf("invalid range %q", ref)
+	}
+	return bounds, nil
+}
+
+// mergedRanges returns the ranges of cells that are merged on the
+// Sheet, which are kept, as they were read, with its unmodelled
+// elements.
+func (s *Sheet) mergedRanges() []cellRange {
+	if s.unmodelled == nil || s.unmodelled.MergeCells == nil {
+		return nil
+	}
+	var mergeCells struct {
+		MergeCell []struct {
+			Ref string `xml:"ref,attr"`
+		} `xml:"mergeCell"`
+	}
+	if err := xml.Unmarshal([]byte("<mergeCells>"+s.unmodelled.MergeCells.InnerXML+"</mergeCells>"), &mergeCells); err != nil {
+		return nil
+	}
+	var ranges []cellRange
+	for _, mergeCell := range mergeCells.MergeCell {
+		if x1, y1, x2, y2, ok := parseCellRange(mergeCell.Ref); ok {
+			ranges = append(ranges, cellRange{x1, y1, x2, y2})
+		}
+	}
+	return ranges
+}
+
+// mergedCells maps the coordinates of each cell of the Sheet's merged
+// ranges, but for the top left one, to the top left cell, which holds
+// the value of the range.
+func (s *Sheet) mergedCells() map[[2]int]*Cell {
+	merged := make(map[[2]int]*Cell)
+	for _, r := range s.mergedRanges() {
+		if r.y1 >= len(s.Rows) || s.Rows[r.y1] == nil || r.x1 >= len(s.Rows[r.y1].Cells) {
+			continue
+		}
+		topLeft := s.Rows[r.y1].Cells[r.x1]
+		for y := r.y1; y <= r.y2; y++ {
+			for x := r.x1; x <= r.x2; x++ {
+				if x != r.x1 || y != r.y1 {
+					merged[[2]int{x, y}] = topLeft
+				}
+			}
+		}
+	}
+	return merged
+}
@@ -1,8 +1,10 @@
 package xlsx
 
 import (
+	"archive/zip"
 	"bytes"
 	"encoding/xml"
+	"strings"
 
 	. "gopkg.in/check.v1"
 )
@@ -49,6 +51,30 @@ func (s *SheetSuite) TestMakeXLSXSheetFromRows(c *C) {
 	c.Assert(xSI.T, Equals, "A cell!")
 }
 
+// With InlineStrings set on the File, string cells are written
+// inline rather than to the shared strings table.
+func (s *SheetSuite) TestMakeXLSXSheetWithInlineStrings(c *C) {
+	file := NewFile()
+	file.InlineStrings = true
+	sheet := file.AddSheet("Sheet1")
+	row := sheet.AddRow()
+	cell := row.AddCell()
+	cell.SetString("A cell!")
+	refTable := NewSharedStringRefTable()
+	styles := newXlsxStyleSheet(nil)
+	xSheet := sheet.makeXLSXSheet(refTable, styles)
+	xC := xSheet.SheetData.Row[0].C[0]
+	c.Assert(xC.T, Equals, "inlineStr")
+	c.Assert(xC.V, Equals, "")
+	c.Assert(xC.Is, NotNil)
+	c.Assert(xC.Is.T, Equals, "A cell!")
+	c.Assert(refTable.Length(), Equals, 0)
+
+	output, err := xml.Marshal(xC)
+	c.Assert(err, IsNil)
+	c.Assert(string(output), Equals, `<xlsxC r="A1" s="0" t="inlineStr"><is><t>A cell!</t></is></xlsxC>`)
+}
+
 // When we create the xlsxSheet we also populate the xlsxStyles struct
 // with style information.
 func (s *SheetSuite) TestMakeXLSXSheetAlsoPopulatesXLSXSTyles(c *C) {
@@ -166,3 +192,126 @@ func (s *SheetSuite) TestSetColWidth(c *C) {
 	c.Assert(sheet.Cols[1].Max, Equals, 6)
 	c.Assert(sheet.Cols[1].Min, Equals, 2)
 }
+
+// The heights of rows are written out, and read back in.
+func (s *SheetSuite) TestRowHeight(c *C) {
+	file := NewFile()
+	sheet := file.AddSheet("Sheet1")
+	sheet.AddRow().AddCell().SetString("tall")
+	sheet.Rows[0].SetHeight(30)
+	sheet.AddRow().AddCell().SetString("default")
+	sheet.AddRow().AddCell().SetString("fitted")
+	sheet.Rows[2].Height = 18
+
+	parts, err := file.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<row r="1" ht="30" customHeight="true">`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<row r="2">`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<row r="3" ht="18">`), Equals, true)
+
+	var buf bytes.Buffer
+	c.Assert(file.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	read, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	c.Assert(read.Sheet["Sheet1"].Rows[0].Height, Equals, 30.0)
+	c.Assert(read.Sheet["Sheet1"].Rows[0].CustomHeight, Equals, true)
+	c.Assert(read.Sheet["Sheet1"].Rows[1].Height, Equals, 0.0)
+	c.Assert(read.Sheet["Sheet1"].Rows[2].Height, Equals, 18.0)
+	c.Assert(read.Sheet["Sheet1"].Rows[2].CustomHeight, Equals, false)
+}
+
+// makeStyledSheet builds a sheet of rows x cols string cells, where
+// cells cycle through styleCount distinct styles.  If shared is
+// false every cell gets its own (equal valued) Style struct.
+func makeStyledSheet(rows, cols, styleCount int, shared bool) *Sheet {
+	file := NewFile()
+	sheet := file.AddSheet("Sheet1")
+	styles := make([]*Style, styleCount)
+	for i := range styles {
+		styles[i] = NewStyle()
+		styles[i].Font = *NewFont(8+i, "Verdana")
+	}
+	for r := 0; r < rows; r++ {
+		row := sheet.AddRow()
+		for c := 0; c < cols; c++ {
+			cell := row.AddCell()
+			cell.SetString("x")
+			style := styles[(r*cols+c)%styleCount]
+			if !shared {
+				copied := *style
+				style = &copied
+			}
+			cell.SetStyle(style)
+		}
+	}
+	return sheet
+}
+
+func benchmarkMakeXLSXSheet(c *C, rows, styleCount int, shared bool) {
+	c.StopTimer()
+	sheet := makeStyledSheet(rows, 10, styleCount, shared)
+	c.StartTimer()
+	for i := 0; i < c.N; i++ {
+		sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil))
+	}
+}
+
+// The cost of writing styled cells should grow linearly with the
+// number of cells, regardless of the number of distinct styles.  Run
+// these with "go test -check.b".
+func (s *SheetSuite) BenchmarkMakeXLSXSheet1kCellsSharedStyles(c *C) {
+	benchmarkMakeXLSXSheet(c, 100, 100, true)
+}
+
+func (s *SheetSuite) BenchmarkMakeXLSXSheet10kCellsSharedStyles(c *C) {
+	benchmarkMakeXLSXSheet(c, 1000, 100, true)
+}
+
+func (s *SheetSuite) BenchmarkMakeXLSXSheet100kCellsSharedStyles(c *C) {
+	benchmarkMakeXLSXSheet(c, 10000, 100, true)
+}
+
+func (s *SheetSuite) BenchmarkMakeXLSXSheet1kCellsDistinctStyles(c *C) {
+	benchmarkMakeXLSXSheet(c, 100, 100, false)
+}
+
+func (s *SheetSuite) BenchmarkMakeXLSXSheet10kCellsDistinctStyles(c *C) {
+	benchmarkMakeXLSXSheet(c, 1000, 100, false)
+}
+
+func (s *SheetSuite) BenchmarkMakeXLSXSheet100kCellsDistinctStyles(c *C) {
+	benchmarkMakeXLSXSheet(c, 10000, 100, false)
+}
+
+// The range a reference refers to on a Sheet, with or without the
+// name of the Sheet, or all of it when there is no reference.
+func (s *SheetSuite) TestRangeBounds(c *C) {
+	sheet := makeExportSheet()
+	bounds, err := sheet.rangeBounds("")
+	c.Assert(err, IsNil)
+	c.Assert(bounds, Equals, cellRange{0, 0, 4, 3})
+	bounds, err = sheet.rangeBounds("B2:C3")
+	c.Assert(err, IsNil)
+	c.Assert(bounds, Equals, cellRange{1, 1, 2, 2})
+	bounds, err = sheet.rangeBounds("Export!$D$1")
+	c.Assert(err, IsNil)
+	c.Assert(bounds, Equals, cellRange{3, 0, 3, 0})
+	for _, ref := range []string{"Other!A1:B2", "A0:B2", "Export!$A$0:$A$3", "B"} {
+		_, err = sheet.rangeBounds(ref)
+		c.Assert(err, ErrorMatches, `invalid range ".*"`, Commentf(ref))
+	}
+}
+
+// Each cell of a merged range but the top left one maps to the top
+// left one.
+func (s *SheetSuite) TestMergedCells(c *C) {
+	sheet := makeExportSheet()
+	c.Assert(sheet.mergedRanges(), DeepEquals, []cellRange{{0, 3, 2, 3}})
+	merged := sheet.mergedCells()
+	c.Assert(merged, HasLen, 2)
+	c.Assert(merged[[2]int{1, 3}], Equals, sheet.Rows[3].Cells[0])
+	c.Assert(merged[[2]int{2, 3}], Equals, sheet.Rows[3].Cells[0])
+	c.Assert(NewFile().AddSheet("Empty").mergedCells(), HasLen, 0)
+}
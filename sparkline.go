@@ -0,0 +1,310 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// sparklineExtURI identifies the ext element, of a worksheet's extLst,
+// that holds its sparkline groups.
+const sparklineExtURI = "{05C60535-1F16-4fd2-B633-F4F36F0B64E0}"
+
+// SparklineGroup is a group of sparklines, small charts drawn in a
+// cell, that share their type and options.
+type SparklineGroup struct {
+	Type       string // "line", "column" or "winLoss".
+	Sparklines []Sparkline
+	Options    SparklineOptions
+}
+
+// Sparkline is a sparkline of the data in DataRange, such as
+// "Sheet1!A2:F2", drawn in the cell Location, such as "G2".
+type Sparkline struct {
+	DataRange string
+	Location  string
+}
+
+// SparklineOptions control how the sparklines of a SparklineGroup are
+// drawn.  Colours are ARGB hex strings, such as "FF376092", and are
+// given Excel's defaults when they are left empty.
+type SparklineOptions struct {
+	// Markers marks every point of line sparklines, and High,
+	// Low, First, Last and Negative the points they name.
+	Markers      bool
+	High         bool
+	Low          bool
+	First        bool
+	Last         bool
+	Negative     bool
+	DisplayXAxis bool
+	LineWeight   float64 // In points, for line sparklines; 0 leaves it as Excel's default.
+
+	SeriesColor   string
+	NegativeColor string
+	AxisColor     string
+	MarkersColor  string
+	FirstColor    string
+	LastColor     string
+	HighColor     string
+	LowColor      string
+}
+
+// AddSparklineGroup adds a group of sparklines, of sparklineType
+// ("line", "column" or "winLoss"), to the Sheet.  A sparkline of each
+// of dataRanges is drawn in the cell at the same index of locations.
+// options may be nil, to use Excel's defaults.
+func (s *Sheet) AddSparklineGroup(sparklineType string, dataRanges, locations []string, options *SparklineOptions) error {
+	switch sparklineType {
+	case "line", "column", "winLoss":
+	default:
+		return fmt.Errorf("unknown sparkline type %q", sparklineType)
+	}
+	if len(dataRanges) == 0 || len(dataRanges) != len(locations) {
+		return fmt.Errorf("%d data ranges for %d sparkline locations", len(dataRanges), len(locations))
+	}
+	group := &SparklineGroup{Type: sparklineType}
+	for i, location := range locations {
+		if _, _, err := getCoordsFromCellIDString(location); err != nil {
+			return fmt.Errorf("invalid cell reference %q: %s", location, err)
+		}
+		group.Sparklines = append(group.Sparklines, Sparkline{DataRange: dataRanges[i], Location: location})
+	}
+	if options != nil {
+		group.Options = *options
+	}
+	for _, color := range []struct {
+		value        *string
+		defaultValue string
+	}{
+		{&group.Options.SeriesColor, "FF376092"},
+		{&group.Options.NegativeColor, "FFD00000"},
+		{&group.Options.AxisColor, "FF000000"},
+		{&group.Options.MarkersColor, "FFD00000"},
+		{&group.Options.FirstColor, "FFD00000"},
+		{&group.Options.LastColor, "FFD00000"},
+		{&group.Options.HighColor, "FFD00000"},
+		{&group.Options.LowColor, "FFD00000"},
+	} {
+		if *color.value == "" {
+			*color.value = color.defaultValue
+		}
+	}
+	s.sparklineGroups = append(s.sparklineGroups, group)
+	return nil
+}
+
+// SparklineGroups returns the groups of sparklines on the Sheet, both
+// those read from a file and those that have been added.  Those read
+// from a file are written back out as they were read, so changes made
+// to them aren't saved.
+func (s *Sheet) SparklineGroups() []*SparklineGroup {
+	return append([]*SparklineGroup{}, s.sparklineGroups...)
+}
+
+// marshal returns the group as an x14:sparklineGroup element.
+func (g *SparklineGroup) marshal() string {
+	flag := func(name string, on bool) string {
+		if on {
+			return ` ` + name + `="1"`
+		}
+		return ""
+	}
+	o := g.Options
+	result := `<x14:sparklineGroup type="` + g.Type + `" displayEmptyCellsAs="gap"`
+	if o.LineWeight != 0 {
+		result += ` lineWeight="` + strconv.FormatFloat(o.LineWeight, 'f', -1, 64) + `"`
+	}
+	result += flag("markers", o.Markers) + flag("high", o.High) + flag("low", o.Low) +
+		flag("first", o.First) + flag("last", o.Last) + flag("negative", o.Negative) +
+		flag("displayXAxis", o.DisplayXAxis) + `>`
+	for _, color := range []struct{ name, rgb string }{
+		{"colorSeries", o.SeriesColor},
+		{"colorNegative", o.NegativeColor},
+		{"colorAxis", o.AxisColor},
+		{"colorMarkers", o.MarkersColor},
+		{"colorFirst", o.FirstColor},
+		{"colorLast", o.LastColor},
+		{"colorHigh", o.HighColor},
+		{"colorLow", o.LowColor},
+	} {
+		if color.rgb != "" {
+			result += `<x14:` + color.name + ` rgb="` + escapeAttr(color.rgb) + `"/>`
+		}
+	}
+	result += `<x14:sparklines>`
+	for _, sparkline := range g.Sparklines {
+		result += `<x14:sparkline><xm:f>` + escapeAttr(sparkline.DataRange) + `</xm:f><xm:sqref>` + escapeAttr(sparkline.Location) + `</xm:sqref></x14:sparkline>`
+	}
+	return result + `</x14:sparklines></x14:sparklineGroup>`
+}
+
+// sparklineNamespaces declares the prefixes that the sparkline groups
+// we write use.
+const sparklineNamespaces = `xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main" xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main"`
+
+// makeXLSXSparklineExtLst returns extLst, which may be nil, with the
+// sparkline groups that have been added to a Sheet added to it.  They
+// go into the ext element that holds the groups read with the Sheet,
+// if there is one, which is otherwise kept as it was read.
+func makeXLSXSparklineExtLst(extLst *xlsxRawXML, groups []*SparklineGroup) *xlsxRawXML {
+	if len(groups) == 0 {
+		return extLst
+	}
+	result := &xlsxRawXML{XMLName: xml.Name{Local: "extLst"}}
+	if extLst != nil {
+		result.Attrs = extLst.Attrs
+		result.InnerXML = extLst.InnerXML
+	}
+	extStart, extEnd, groupsEnd := findSparklineExt(result.InnerXML)
+	content := ""
+	for _, group := range groups {
+		content += group.marshal()
+	}
+	if groupsEnd >= 0 {
+		// The prefixes declared by the ext that was read may not
+		// be ours, so each group declares its own.
+		content = strings.Replace(content, "<x14:sparklineGroup ", "<x14:sparklineGroup "+sparklineNamespaces+" ", -1)
+		result.InnerXML = result.InnerXML[:groupsEnd] + content + result.InnerXML[groupsEnd:]
+		return result
+	}
+	ext := `<ext uri="` + sparklineExtURI + `" xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main">` +
+		`<x14:sparklineGroups xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main">` + content + `</x14:sparklineGroups></ext>`
+	if extStart >= 0 {
+		// An ext without any groups in it is replaced.
+		result.InnerXML = result.InnerXML[:extStart] + ext + result.InnerXML[extEnd:]
+	} else {
+		result.InnerXML += ext
+	}
+	return result
+}
+
+// findSparklineExt returns where, in the inner XML of an extLst, the
+// ext element holding sparkline groups starts and ends, and where the
+// end tag of its sparklineGroups element is.  Each is -1 if there
+// isn't one.  The XML was read with the Sheet, so it is well formed.
+func findSparklineExt(inner string) (extStart, extEnd, groupsEnd int) {
+	extStart, extEnd, groupsEnd = -1, -1, -1
+	decoder := xml.NewDecoder(strings.NewReader(inner))
+	depth, inExt, groupsStart := 0, false, -1
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err != nil {
+			return extStart, extEnd, groupsEnd
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 && extStart < 0 && t.Name.Local == "ext" && hasAttr(t, "uri", sparklineExtURI) {
+				inExt, extStart = true, offset
+			}
+			if inExt && depth == 2 && t.Name.Local == "sparklineGroups" {
+				groupsStart = int(decoder.InputOffset())
+			}
+		case xml.EndElement:
+			// An element that closes itself has nothing inside
+			// it that groups can be added to.
+			if inExt && depth == 2 && t.Name.Local == "sparklineGroups" && !(offset == groupsStart && strings.HasSuffix(inner[:offset], "/>")) {
+				groupsEnd = offset
+			}
+			if inExt && depth == 1 {
+				inExt, extEnd = false, int(decoder.InputOffset())
+			}
+			depth--
+		}
+	}
+}
+
+// readSparklineGroups returns the sparkline groups held in the extLst
+// of a worksheet, which may be nil.  The extLst itself is retained as
+// it was read, so that what isn't modelled of the groups isn't lost.
+func readSparklineGroups(extLst *xlsxRawXML, styles *xlsxStyleSheet) ([]*SparklineGroup, error) {
+	if extLst == nil {
+		return nil, nil
+	}
+	var groups []*SparklineGroup
+	decoder := xml.NewDecoder(strings.NewReader(extLst.InnerXML))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if element.Name.Local != "ext" || !hasAttr(element, "uri", sparklineExtURI) {
+			if err := decoder.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		xGroups := new(xlsxX14SparklineGroups)
+		if err := decoder.DecodeElement(xGroups, &element); err != nil {
+			return nil, err
+		}
+		for _, xGroup := range xGroups.SparklineGroup {
+			groups = append(groups, readSparklineGroup(xGroup, styles))
+		}
+	}
+	return groups, nil
+}
+
+// readSparklineGroup returns the SparklineGroup for an
+// x14:sparklineGroup element.
+func readSparklineGroup(xGroup xlsxX14SparklineGroup, styles *xlsxStyleSheet) *SparklineGroup {
+	group := &SparklineGroup{
+		Type: xGroup.Type,
+		Options: SparklineOptions{
+			Markers:      xGroup.Markers,
+			High:         xGroup.High,
+			Low:          xGroup.Low,
+			First:        xGroup.First,
+			Last:         xGroup.Last,
+			Negative:     xGroup.Negative,
+			DisplayXAxis: xGroup.DisplayXAxis,
+			LineWeight:   xGroup.LineWeight,
+		},
+	}
+	if group.Type == "" {
+		group.Type = "line"
+	}
+	color := func(xColor *xlsxColor) string {
+		if xColor == nil {
+			return ""
+		}
+		if styles == nil {
+			return xColor.RGB
+		}
+		return styles.argbValue(*xColor)
+	}
+	group.Options.SeriesColor = color(xGroup.ColorSeries)
+	group.Options.NegativeColor = color(xGroup.ColorNegative)
+	group.Options.AxisColor = color(xGroup.ColorAxis)
+	group.Options.MarkersColor = color(xGroup.ColorMarkers)
+	group.Options.FirstColor = color(xGroup.ColorFirst)
+	group.Options.LastColor = color(xGroup.ColorLast)
+	group.Options.HighColor = color(xGroup.ColorHigh)
+	group.Options.LowColor = color(xGroup.ColorLow)
+	for _, xSparkline := range xGroup.Sparklines {
+		group.Sparklines = append(group.Sparklines, Sparkline{DataRange: xSparkline.F, Location: xSparkline.Sqref})
+	}
+	return group
+}
+
+// hasAttr reports whether element has an attribute with the given
+// local name and value.
+func hasAttr(element xml.StartElement, name, value string) bool {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name && attr.Value == value {
+			return true
+		}
+	}
+	return false
+}
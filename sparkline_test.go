@@ -0,0 +1,122 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"strings"
+
+	. "gopkg.in/check.v1"
+)
+
+type SparklineSuite struct{}
+
+var _ = Suite(&SparklineSuite{})
+
+func (s *SparklineSuite) TestAddSparklineGroup(c *C) {
+	sheet := NewFile().AddSheet("Sheet1")
+	c.Assert(sheet.AddSparklineGroup("column",
+		[]string{"Sheet1!A1:E1", "Sheet1!A2:E2"}, []string{"F1", "F2"},
+		&SparklineOptions{High: true, HighColor: "FF00B050"}), IsNil)
+	groups := sheet.SparklineGroups()
+	c.Assert(groups, HasLen, 1)
+	c.Assert(groups[0].Sparklines, DeepEquals, []Sparkline{{"Sheet1!A1:E1", "F1"}, {"Sheet1!A2:E2", "F2"}})
+	c.Assert(groups[0].Options.SeriesColor, Equals, "FF376092")
+	c.Assert(groups[0].Options.HighColor, Equals, "FF00B050")
+
+	output, err := xml.Marshal(sheet.makeXLSXSheet(NewSharedStringRefTable(), newXlsxStyleSheet(nil)))
+	c.Assert(err, IsNil)
+	c.Assert(strings.HasSuffix(string(output), `<extLst><ext uri="{05C60535-1F16-4fd2-B633-F4F36F0B64E0}" xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main"><x14:sparklineGroups xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main">`+
+		`<x14:sparklineGroup type="column" displayEmptyCellsAs="gap" high="1"><x14:colorSeries rgb="FF376092"/><x14:colorNegative rgb="FFD00000"/><x14:colorAxis rgb="FF000000"/><x14:colorMarkers rgb="FFD00000"/><x14:colorFirst rgb="FFD00000"/><x14:colorLast rgb="FFD00000"/><x14:colorHigh rgb="FF00B050"/><x14:colorLow rgb="FFD00000"/>`+
+		`<x14:sparklines><x14:sparkline><xm:f>Sheet1!A1:E1</xm:f><xm:sqref>F1</xm:sqref></x14:sparkline><x14:sparkline><xm:f>Sheet1!A2:E2</xm:f><xm:sqref>F2</xm:sqref></x14:sparkline></x14:sparklines></x14:sparklineGroup></x14:sparklineGroups></ext></extLst></worksheet>`), Equals, true)
+}
+
+func (s *SparklineSuite) TestAddSparklineGroupErrors(c *C) {
+	sheet := NewFile().AddSheet("Sheet1")
+	c.Assert(sheet.AddSparklineGroup("bar", []string{"Sheet1!A1:E1"}, []string{"F1"}, nil), ErrorMatches, `unknown sparkline type "bar"`)
+	c.Assert(sheet.AddSparklineGroup("line", []string{"Sheet1!A1:E1"}, nil, nil), ErrorMatches, "1 data ranges for 0 sparkline locations")
+	c.Assert(sheet.AddSparklineGroup("line", []string{"Sheet1!A1:E1"}, []string{"F"}, nil), ErrorMatches, `invalid cell reference "F": .*`)
+	c.Assert(sheet.SparklineGroups(), HasLen, 0)
+}
+
+// Sparkline groups are read from the extLst of a worksheet, which is
+// itself retained as it was.
+func (s *SparklineSuite) TestReadSparklineGroups(c *C) {
+	extLst := new(xlsxRawXML)
+	err := xml.Unmarshal([]byte(`<extLst><ext uri="{78C0D931-6437-407d-A8EE-F0AAD7539E65}" xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main"><x14:conditionalFormattings/></ext>`+
+		`<ext uri="{05C60535-1F16-4fd2-B633-F4F36F0B64E0}" xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main"><x14:sparklineGroups xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main">`+
+		`<x14:sparklineGroup displayEmptyCellsAs="gap" markers="1" lineWeight="1.5"><x14:colorSeries theme="4" tint="-0.499984740745262"/><x14:colorMarkers rgb="FFD00000"/>`+
+		`<x14:sparklines><x14:sparkline><xm:f>Sheet1!A1:E1</xm:f><xm:sqref>F1</xm:sqref></x14:sparkline></x14:sparklines></x14:sparklineGroup></x14:sparklineGroups></ext></extLst>`), extLst)
+	c.Assert(err, IsNil)
+
+	groups, err := readSparklineGroups(extLst, nil)
+	c.Assert(err, IsNil)
+	c.Assert(groups, HasLen, 1)
+	c.Assert(*groups[0], DeepEquals, SparklineGroup{
+		Type:       "line",
+		Sparklines: []Sparkline{{"Sheet1!A1:E1", "F1"}},
+		Options:    SparklineOptions{Markers: true, LineWeight: 1.5, MarkersColor: "FFD00000"}})
+
+	// Theme colours are resolved when there is a theme.
+	var themeXml xlsxTheme
+	c.Assert(xml.Unmarshal([]byte(TEMPLATE_XL_THEME_THEME), &themeXml), IsNil)
+	groups, err = readSparklineGroups(extLst, newXlsxStyleSheet(newTheme(themeXml)))
+	c.Assert(err, IsNil)
+	c.Assert(groups[0].Options.SeriesColor, Equals, "FF254061")
+
+	groups, err = readSparklineGroups(&xlsxRawXML{InnerXML: `<ext uri="{05C60535-1F16-4fd2-B633-F4F36F0B64E0}"/>`}, nil)
+	c.Assert(err, IsNil)
+	c.Assert(groups, HasLen, 0)
+}
+
+// Groups that are added go into the ext element that holds those that
+// were read, which are otherwise left as they were.
+func (s *SparklineSuite) TestMakeXLSXSparklineExtLst(c *C) {
+	group := &SparklineGroup{Type: "column", Sparklines: []Sparkline{{"Sheet1!A2:E2", "F2"}}}
+	other := `<ext uri="{78C0D931-6437-407d-A8EE-F0AAD7539E65}"><x14:conditionalFormattings xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main"/></ext>`
+	read := `<ext xmlns:s="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main" uri="{05C60535-1F16-4fd2-B633-F4F36F0B64E0}"><s:sparklineGroups xmlns:m="http://schemas.microsoft.com/office/excel/2006/main">` +
+		`<s:sparklineGroup displayEmptyCellsAs="zero" manualMax="10" maxAxisType="custom" dateAxis="1" displayHidden="1" rightToLeft="1"><s:colorSeries theme="4" tint="-0.5"/>` +
+		`<s:sparklines><s:sparkline><m:f>Sheet1!A1:E1</m:f><m:sqref>F1</m:sqref></s:sparkline></s:sparklines><m:f>Sheet1!A10:E10</m:f></s:sparklineGroup></s:sparklineGroups></ext>`
+	extLst := makeXLSXSparklineExtLst(&xlsxRawXML{InnerXML: other + read}, []*SparklineGroup{group})
+	end := strings.Index(read, "</s:sparklineGroups>")
+	c.Assert(extLst.InnerXML, Equals, other+read[:end]+
+		`<x14:sparklineGroup `+sparklineNamespaces+` type="column" displayEmptyCellsAs="gap"><x14:sparklines><x14:sparkline><xm:f>Sheet1!A2:E2</xm:f><xm:sqref>F2</xm:sqref></x14:sparkline></x14:sparklines></x14:sparklineGroup>`+
+		read[end:])
+
+	// Without groups the extLst is as it was.
+	original := &xlsxRawXML{InnerXML: other + read}
+	c.Assert(makeXLSXSparklineExtLst(original, nil), Equals, original)
+
+	// An ext without groups is replaced, and one is added where
+	// there isn't one.
+	fresh := `<ext uri="{05C60535-1F16-4fd2-B633-F4F36F0B64E0}" xmlns:x14="http://schemas.microsoft.com/office/spreadsheetml/2009/9/main"><x14:sparklineGroups xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main">` +
+		group.marshal() + `</x14:sparklineGroups></ext>`
+	extLst = makeXLSXSparklineExtLst(&xlsxRawXML{InnerXML: `<ext uri="{05C60535-1F16-4fd2-B633-F4F36F0B64E0}"/>` + other}, []*SparklineGroup{group})
+	c.Assert(extLst.InnerXML, Equals, fresh+other)
+	extLst = makeXLSXSparklineExtLst(&xlsxRawXML{InnerXML: other}, []*SparklineGroup{group})
+	c.Assert(extLst.InnerXML, Equals, other+fresh)
+}
+
+func (s *SparklineSuite) TestSparklinesRoundTrip(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	c.Assert(sheet.AddSparklineGroup("winLoss", []string{"Sheet1!A1:E1"}, []string{"F1"}, &SparklineOptions{Negative: true}), IsNil)
+	c.Assert(sheet.AddSparklineGroup("line", []string{"Sheet1!A2:E2"}, []string{"F2"}, &SparklineOptions{LineWeight: 0.75, Markers: true}), IsNil)
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	sheet2 := f2.Sheet["Sheet1"]
+	c.Assert(sheet2.SparklineGroups(), DeepEquals, sheet.SparklineGroups())
+
+	// They aren't written twice over.
+	c.Assert(sheet2.AddSparklineGroup("column", []string{"Sheet1!A3:E3"}, []string{"F3"}, nil), IsNil)
+	parts, err := f2.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Count(parts["xl/worksheets/sheet1.xml"], "<x14:sparklineGroup "), Equals, 3)
+	c.Assert(strings.Count(parts["xl/worksheets/sheet1.xml"], "<extLst>"), Equals, 1)
+	c.Assert(strings.Count(parts["xl/worksheets/sheet1.xml"], "<x14:sparklineGroups "), Equals, 1)
+}
@@ -12,14 +12,19 @@ type Style struct {
 	ApplyFill   bool
 	ApplyFont   bool
 	Alignment   Alignment
+	// Protection only takes effect when ApplyProtection is set,
+	// and the Sheet is protected.
+	ApplyProtection bool
+	Protection      Protection
 }
 
 // Return a new Style structure initialised with the default values.
 func NewStyle() *Style {
 	return &Style{
-		Font:   *DefaulFont(),
-		Border: *DefaulBorder(),
-		Fill:   *DefaulFill(),
+		Font:       *DefaulFont(),
+		Border:     *DefaulBorder(),
+		Fill:       *DefaulFill(),
+		Protection: Protection{Locked: true},
 	}
 }
 
@@ -35,6 +40,15 @@ func (style *Style) makeXLSXStyleElements() (xFont xlsxFont, xFill xlsxFill, xBo
 	xFont.Family.Val = strconv.Itoa(style.Font.Family)
 	xFont.Charset.Val = strconv.Itoa(style.Font.Charset)
 	xFont.Color.RGB = style.Font.Color
+	if style.Font.Bold {
+		xFont.B = &struct{}{}
+	}
+	if style.Font.Italic {
+		xFont.I = &struct{}{}
+	}
+	if style.Font.Underline {
+		xFont.U = &xlsxVal{}
+	}
 	xPatternFill := xlsxPatternFill{}
 	xPatternFill.PatternType = style.Fill.PatternType
 	xPatternFill.FgColor.RGB = style.Fill.FgColor
@@ -48,6 +62,15 @@ func (style *Style) makeXLSXStyleElements() (xFont xlsxFont, xFill xlsxFill, xBo
 	xCellXf.ApplyFill = style.ApplyFill
 	xCellXf.ApplyFont = style.ApplyFont
 	xCellXf.NumFmtId = 0
+	if style.ApplyProtection {
+		xCellXf.ApplyProtection = true
+		xCellXf.Protection.Locked = strconv.FormatBool(style.Protection.Locked)
+		xCellXf.Protection.Hidden = strconv.FormatBool(style.Protection.Hidden)
+	}
+	if style.Alignment.Horizontal != "" {
+		xCellXf.ApplyAlignment = true
+		xCellXf.Alignment.Horizontal = style.Alignment.Horizontal
+	}
 	xCellStyleXf.ApplyBorder = style.ApplyBorder
 	xCellStyleXf.ApplyFill = style.ApplyFill
 	xCellStyleXf.ApplyFont = style.ApplyFont
@@ -99,6 +122,14 @@ type Alignment struct {
 	Horizontal string
 }
 
+// Protection controls what happens to a cell when its Sheet is
+// protected.  Locked cells can't be changed, and the formulas of
+// Hidden cells aren't shown.  Cells are locked by default.
+type Protection struct {
+	Locked bool
+	Hidden bool
+}
+
 func DefaulFont() *Font {
 	return NewFont(12, "Verdana")
 }
@@ -0,0 +1,332 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	tableRelType     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/table"
+	tableContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.table+xml"
+)
+
+// TableOptions control how WriteTable writes a slice of structs to a
+// Sheet.
+type TableOptions struct {
+	// HeaderStyle, if set, is the Style of the cells of the header
+	// row.
+	HeaderStyle *Style
+	// FreezeHeader keeps the header row in view as the Sheet is
+	// scrolled.
+	FreezeHeader bool
+	// AutoFilter puts filter buttons on the header row.  Tables
+	// have them anyway.
+	AutoFilter bool
+	// Table makes the header and rows an Excel table, named
+	// TableName and styled with TableStyle.  They default to
+	// "Table1", "Table2" and so on, and "TableStyleMedium2".  The
+	// name can't be that of another table, or a defined name, in
+	// the workbook.
+	Table      bool
+	TableName  string
+	TableStyle string
+}
+
+// sheetTable is an Excel table that has been added to a Sheet.
+type sheetTable struct {
+	name    string
+	style   string
+	ref     string
+	columns []string
+}
+
+// WriteTable writes slice, a slice of structs or of pointers to them,
+// to the Sheet, below any rows it already has.  A header row comes
+// first, with the names that the fields' tags give, or the names of
+// the fields, and then each element is written with WriteStruct.  The
+// widths that the tags give, such as `xlsx:"Price,width=12"`, are
+// given to the columns.  opts may be nil.
+func (s *Sheet) WriteTable(slice interface{}, opts *TableOptions) error {
+	v := reflect.ValueOf(slice)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return fmt.Errorf("WriteTable needs a slice of structs, not %T", slice)
+	}
+	structType := v.Type().Elem()
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("WriteTable needs a slice of structs, not %T", slice)
+	}
+	fields := structFields(structType)
+	if len(fields) == 0 {
+		return fmt.Errorf("%s has no fields that can be written", structType)
+	}
+	options := TableOptions{}
+	if opts != nil {
+		options = *opts
+	}
+	if options.TableName != "" && !isTableName(options.TableName) {
+		return fmt.Errorf("invalid table name %q", options.TableName)
+	}
+	if options.Table && options.TableName != "" && s.File != nil && s.File.tableNames()[strings.ToLower(options.TableName)] {
+		return fmt.Errorf("the name %q is already used in the workbook", options.TableName)
+	}
+
+	columns := make([]string, len(fields))
+	used := make(map[string]bool, len(fields))
+	for i, field := range fields {
+		columns[i] = field.header
+		if columns[i] == "" {
+			columns[i] = field.name
+		}
+		if options.Table && used[strings.ToLower(columns[i])] {
+			return fmt.Errorf("the columns of a table need different headers, but %q is used twice", columns[i])
+		}
+		used[strings.ToLower(columns[i])] = true
+	}
+
+	first := len(s.Rows)
+	header := s.AddRow()
+	for i, column := range columns {
+		cell := header.AddCell()
+		cell.SetString(column)
+		if options.HeaderStyle != nil {
+			cell.SetStyle(options.HeaderStyle)
+		}
+		if fields[i].width > 0 {
+			s.setColumnWidth(i, fields[i].width)
+		}
+	}
+	for i := 0; i < v.Len(); i++ {
+		row := s.AddRow()
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+		if _, err := row.writeStruct(elem, fields); err != nil {
+			return err
+		}
+	}
+	if options.Table && v.Len() == 0 {
+		// A table needs a row, even an empty one, below its
+		// header.
+		s.AddRow()
+	}
+
+	ref := getCellIDStringFromCoords(0, first) + ":" + getCellIDStringFromCoords(len(fields)-1, len(s.Rows)-1)
+	if options.Table {
+		style := options.TableStyle
+		if style == "" {
+			style = "TableStyleMedium2"
+		}
+		s.tables = append(s.tables, &sheetTable{
+			name:    options.TableName,
+			style:   style,
+			ref:     ref,
+			columns: columns})
+	} else if options.AutoFilter {
+		s.autoFilter = ref
+	}
+	if options.FreezeHeader {
+		pane := &Pane{
+			YSplit:      first + 1,
+			TopLeftCell: getCellIDStringFromCoords(0, first+1),
+			ActivePane:  "bottomLeft",
+			State:       "frozen"}
+		if len(s.SheetViews) == 0 {
+			s.SheetViews = []SheetView{{}}
+		}
+		s.SheetViews[0].Pane = pane
+	}
+	return nil
+}
+
+// setColumnWidth sets the width of the column, with the zero based
+// index col, of the Sheet.  A range of columns that includes it is
+// split, so that the column has a Col of its own and the ranges of
+// the Cols don't overlap, which Excel won't accept.
+func (s *Sheet) setColumnWidth(col int, width float64) {
+	n := col + 1
+	cols := make([]*Col, 0, len(s.Cols)+2)
+	split := make(map[[2]int]bool)
+	for _, c := range s.Cols {
+		if c.Min == 0 || n < c.Min || c.Max < n {
+			cols = append(cols, c)
+			continue
+		}
+		// Cols read from a file share the range they were
+		// defined with, once for each of its columns.
+		if split[[2]int{c.Min, c.Max}] {
+			continue
+		}
+		split[[2]int{c.Min, c.Max}] = true
+		if c.Min < n {
+			before := *c
+			before.Max = n - 1
+			cols = append(cols, &before)
+		}
+		single := *c
+		single.Min, single.Max, single.Width = n, n, width
+		cols = append(cols, &single)
+		if n < c.Max {
+			after := *c
+			after.Min = n + 1
+			cols = append(cols, &after)
+		}
+	}
+	if len(split) == 0 {
+		s.SetColWidth(col, col, width)
+		return
+	}
+	s.Cols = cols
+}
+
+// isTableName reports whether name can be the name of a table, which
+// is made up of letters, digits, underscores and full stops, doesn't
+// start with a digit or full stop, and doesn't look like a cell
+// reference.
+func isTableName(name string) bool {
+	if cellLikeName.MatchString(name) {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', r == '\\', r > 127,
+			'a' <= r && r <= 'z', 'A' <= r && r <= 'Z':
+		case '0' <= r && r <= '9', r == '.':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+// retainedTables returns the tables amongst the parts retained from a
+// file.
+func retainedTables(retainedParts map[string]string) []*xlsxTable {
+	var tables []*xlsxTable
+	for name, part := range retainedParts {
+		if !strings.HasPrefix(name, "xl/tables/") || !strings.HasSuffix(name, ".xml") {
+			continue
+		}
+		table := new(xlsxTable)
+		if err := xml.Unmarshal([]byte(part), table); err == nil {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// maxTableId returns the largest id of the tables amongst the parts
+// retained from a file, or 0 if there aren't any.
+func maxTableId(retainedParts map[string]string) int {
+	maxId := 0
+	for _, table := range retainedTables(retainedParts) {
+		if table.Id > maxId {
+			maxId = table.Id
+		}
+	}
+	return maxId
+}
+
+// tableNames returns the names, in lower case, that a table can't be
+// given, as they are already used in the File: those of the tables
+// read with it or added to its sheets, and its defined names.
+func (f *File) tableNames() map[string]bool {
+	used := make(map[string]bool)
+	for _, table := range retainedTables(f.retainedParts) {
+		used[strings.ToLower(table.Name)] = true
+		used[strings.ToLower(table.DisplayName)] = true
+	}
+	for _, sheet := range f.Sheets {
+		for _, table := range sheet.tables {
+			if table.name != "" {
+				used[strings.ToLower(table.name)] = true
+			}
+		}
+	}
+	for _, name := range f.definedNames {
+		used[strings.ToLower(name.Name)] = true
+	}
+	return used
+}
+
+// makeTableParts adds the parts for the tables that have been added
+// to the Sheet to parts, and refers to them from worksheet.  The
+// tables are given ids after lastId, which is updated, and those
+// without names are given ones that aren't amongst usedNames, which
+// are added to it.  It returns rels, the relationships of the
+// worksheet, with the tables added to them.
+func (s *Sheet) makeTableParts(worksheet *xlsxWorksheet, rels string, names *partNamer, parts map[string]string, types *xlsxTypes, lastId *int, usedNames map[string]bool) (string, error) {
+	if len(s.tables) == 0 {
+		return rels, nil
+	}
+	tableParts := &xlsxRawXML{XMLName: xml.Name{Local: "tableParts"}}
+	count := 0
+	if worksheet.TableParts != nil {
+		for _, attr := range worksheet.TableParts.Attrs {
+			if attr.Name.Local == "count" {
+				count, _ = strconv.Atoi(attr.Value)
+			} else {
+				tableParts.Attrs = append(tableParts.Attrs, attr)
+			}
+		}
+		tableParts.InnerXML = worksheet.TableParts.InnerXML
+	}
+	for _, table := range s.tables {
+		*lastId++
+		name := table.name
+		if name == "" {
+			for n := *lastId; name == "" || usedNames[strings.ToLower(name)]; n++ {
+				name = fmt.Sprintf("Table%d", n)
+			}
+		}
+		usedNames[strings.ToLower(name)] = true
+		xTable := xlsxTable{
+			Id:          *lastId,
+			Name:        name,
+			DisplayName: name,
+			Ref:         table.ref,
+			AutoFilter:  &xlsxTableFilter{Ref: table.ref},
+			TableColumns: xlsxTableColumns{
+				Count: len(table.columns)},
+			TableStyleInfo: &xlsxTableStyleInfo{
+				Name:           table.style,
+				ShowRowStripes: true},
+		}
+		for i, column := range table.columns {
+			xTable.TableColumns.TableColumn = append(xTable.TableColumns.TableColumn,
+				xlsxTableColumn{Id: i + 1, Name: column})
+		}
+		body, err := xml.Marshal(xTable)
+		if err != nil {
+			return "", err
+		}
+		part := names.next("xl/tables/table%d.xml")
+		parts[part] = xml.Header + string(body)
+		types.Overrides = append(types.Overrides, xlsxOverride{PartName: "/" + part, ContentType: tableContentType})
+		var rId string
+		rels, rId, err = addRel(rels, tableRelType, relTarget("xl/worksheets/sheet.xml", part))
+		if err != nil {
+			return "", err
+		}
+		tableParts.InnerXML += `<tablePart xmlns:r="` + relationshipsNS + `" r:id="` + rId + `"/>`
+		count++
+	}
+	tableParts.Attrs = append([]xml.Attr{{Name: xml.Name{Local: "count"}, Value: strconv.Itoa(count)}}, tableParts.Attrs...)
+	worksheet.TableParts = tableParts
+	return rels, nil
+}
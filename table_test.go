@@ -0,0 +1,191 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+)
+
+type TableSuite struct{}
+
+var _ = Suite(&TableSuite{})
+
+type tableOrder struct {
+	Id     int       `xlsx:"Order"`
+	Placed time.Time `xlsx:"Placed,format=d-mmm-yy,width=12"`
+	Total  float64   `xlsx:"Total,format=#,##0.00"`
+	Notes  string    `xlsx:",omitempty"`
+	secret string
+}
+
+func (s *TableSuite) TestWriteTable(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Orders")
+	orders := []*tableOrder{
+		{1, time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), 1234.5, "", ""},
+		nil,
+		{3, time.Date(2015, 1, 3, 0, 0, 0, 0, time.UTC), 10, "rush", ""},
+	}
+	bold := NewStyle()
+	bold.Font.Bold = true
+	c.Assert(sheet.WriteTable(orders, &TableOptions{HeaderStyle: bold, FreezeHeader: true, AutoFilter: true}), IsNil)
+
+	c.Assert(sheet.Rows, HasLen, 4)
+	var header []string
+	for _, cell := range sheet.Rows[0].Cells {
+		header = append(header, cell.Value)
+		c.Assert(cell.GetStyle(), Equals, bold)
+	}
+	c.Assert(header, DeepEquals, []string{"Order", "Placed", "Total", "Notes"})
+	c.Assert(sheet.Rows[1].Cells[1].String(), Equals, "1-Jan-15")
+	c.Assert(sheet.Rows[1].Cells[2].Value, Equals, "1234.50")
+	c.Assert(sheet.Rows[2].Cells, HasLen, 0)
+	c.Assert(sheet.Rows[3].Cells[3].Value, Equals, "rush")
+	c.Assert(sheet.Cols[1].Width, Equals, 12.0)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	worksheet := parts["xl/worksheets/sheet1.xml"]
+	c.Assert(strings.Contains(worksheet, `<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"></pane><selection pane="bottomLeft"`), Equals, true)
+	c.Assert(strings.Contains(worksheet, `<autoFilter ref="A1:D4"></autoFilter>`), Equals, true)
+	c.Assert(strings.Contains(worksheet, `<col collapsed="false" hidden="false" max="2" min="2" width="12"`), Equals, true)
+
+	// The number formats are written to the stylesheet.
+	c.Assert(strings.Contains(parts["xl/styles.xml"], `numFmtId="15"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/styles.xml"], `numFmtId="4"`), Equals, true)
+}
+
+func (s *TableSuite) TestWriteTableAsTable(c *C) {
+	f := NewFile()
+	sheet := f.AddSheet("Orders")
+	sheet.AddRow().AddCell().SetString("Orders for January")
+	orders := []tableOrder{{Id: 1, Total: 5}, {Id: 2, Total: 7.5}}
+	c.Assert(sheet.WriteTable(orders, &TableOptions{Table: true, TableName: "Orders"}), IsNil)
+	c.Assert(sheet.WriteTable(&[]tableOrder{}, &TableOptions{Table: true, TableStyle: "TableStyleLight1"}), IsNil)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(parts["xl/tables/table1.xml"], Equals, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<table xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" id="1" name="Orders" displayName="Orders" ref="A2:D4" totalsRowShown="0">`+
+		`<autoFilter ref="A2:D4"></autoFilter><tableColumns count="4">`+
+		`<tableColumn id="1" name="Order"></tableColumn><tableColumn id="2" name="Placed"></tableColumn><tableColumn id="3" name="Total"></tableColumn><tableColumn id="4" name="Notes"></tableColumn></tableColumns>`+
+		`<tableStyleInfo name="TableStyleMedium2" showFirstColumn="false" showLastColumn="false" showRowStripes="true" showColumnStripes="false"></tableStyleInfo></table>`)
+	c.Assert(strings.Contains(parts["xl/tables/table2.xml"], `id="2" name="Table2" displayName="Table2" ref="A5:D6"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<tableParts count="2"><tablePart xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="rId1"/><tablePart xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" r:id="rId2"/></tableParts>`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/worksheets/_rels/sheet1.xml.rels"], `Target="../tables/table2.xml" Type="`+tableRelType+`"`), Equals, true)
+	c.Assert(strings.Contains(parts["[Content_Types].xml"], `PartName="/xl/tables/table1.xml" ContentType="`+tableContentType+`"`), Equals, true)
+
+	// Tables that are read are retained, and ones added alongside
+	// them get ids of their own.
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	c.Assert(f2.Sheet["Orders"].WriteTable(orders, &TableOptions{Table: true}), IsNil)
+	parts, err = f2.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/tables/table3.xml"], `id="3" name="Table3"`), Equals, true)
+	c.Assert(strings.Count(parts["xl/worksheets/sheet1.xml"], "<tablePart "), Equals, 3)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<tableParts count="3">`), Equals, true)
+}
+
+func (s *TableSuite) TestWriteTableErrors(c *C) {
+	sheet := NewFile().AddSheet("Orders")
+	c.Assert(sheet.WriteTable([]int{1, 2}, nil), ErrorMatches, `WriteTable needs a slice of structs, not \[\]int`)
+	c.Assert(sheet.WriteTable(tableOrder{}, nil), ErrorMatches, `WriteTable needs a slice of structs, not xlsx.tableOrder`)
+	c.Assert(sheet.WriteTable([]struct{ a int }{}, nil), ErrorMatches, `struct { a int } has no fields that can be written`)
+	c.Assert(sheet.WriteTable([]tableOrder{}, &TableOptions{Table: true, TableName: "2015 Orders"}), ErrorMatches, `invalid table name "2015 Orders"`)
+	for _, name := range []string{"A1", "xfd1048576", "R1C1", "r", "C"} {
+		c.Assert(sheet.WriteTable([]tableOrder{}, &TableOptions{Table: true, TableName: name}), ErrorMatches, `invalid table name "`+name+`"`)
+	}
+	type twice struct {
+		A int `xlsx:"Total"`
+		B int `xlsx:"total"`
+	}
+	c.Assert(sheet.WriteTable([]twice{}, &TableOptions{Table: true}), ErrorMatches, `the columns of a table need different headers, but "total" is used twice`)
+	c.Assert(sheet.Rows, HasLen, 0)
+	c.Assert(sheet.WriteTable([]twice{}, nil), IsNil)
+}
+
+// Tables are given names that aren't used elsewhere in the workbook,
+// and can't be given ones that are.
+func (s *TableSuite) TestWriteTableNamesAreUnique(c *C) {
+	f := NewFile()
+	a, b := f.AddSheet("A"), f.AddSheet("B")
+	orders := []tableOrder{{Id: 1}}
+	c.Assert(a.WriteTable(orders, &TableOptions{Table: true, TableName: "Table2"}), IsNil)
+	c.Assert(a.WriteTable(orders, &TableOptions{Table: true}), IsNil)
+	c.Assert(f.AddDefinedName(&DefinedName{Name: "Table3", Formula: "A!$A$1"}), IsNil)
+	c.Assert(b.WriteTable(orders, &TableOptions{Table: true}), IsNil)
+	c.Assert(b.WriteTable(orders, &TableOptions{Table: true, TableName: "table2"}), ErrorMatches, `the name "table2" is already used in the workbook`)
+	c.Assert(b.WriteTable(orders, &TableOptions{Table: true, TableName: "Table3"}), ErrorMatches, `the name "Table3" is already used in the workbook`)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/tables/table1.xml"], `id="1" name="Table2"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/tables/table2.xml"], `id="2" name="Table4"`), Equals, true)
+	c.Assert(strings.Contains(parts["xl/tables/table3.xml"], `id="3" name="Table5"`), Equals, true)
+
+	// The names of the tables read with the workbook are used too.
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	c.Assert(f2.Sheet["B"].WriteTable(orders, &TableOptions{Table: true, TableName: "Table5"}), ErrorMatches, `the name "Table5" is already used in the workbook`)
+	c.Assert(f2.Sheet["B"].WriteTable(orders, &TableOptions{Table: true}), IsNil)
+	parts, err = f2.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/tables/table4.xml"], `id="4" name="Table6"`), Equals, true)
+}
+
+// Widths given to columns in a range of them split the range, rather
+// than adding columns that overlap it.
+func (s *TableSuite) TestWriteTableSplitsColumnRanges(c *C) {
+	type e struct {
+		A int
+		B int `xlsx:",width=12"`
+	}
+	f := NewFile()
+	sheet := f.AddSheet("Sheet1")
+	c.Assert(sheet.SetColWidth(0, 4, 20), IsNil)
+	c.Assert(sheet.WriteTable([]e{{1, 2}}, nil), IsNil)
+
+	var ranges [][3]float64
+	for _, col := range sheet.Cols {
+		ranges = append(ranges, [3]float64{float64(col.Min), float64(col.Max), col.Width})
+	}
+	c.Assert(ranges, DeepEquals, [][3]float64{{1, 1, 20}, {2, 2, 12}, {3, 5, 20}})
+
+	// So do those of a range read from a file, which is read as a
+	// Col for each of its columns.
+	f = NewFile()
+	sheet = f.AddSheet("Sheet1")
+	c.Assert(sheet.SetColWidth(0, 4, 20), IsNil)
+	row := sheet.AddRow()
+	for i := 0; i < 5; i++ {
+		row.AddCell().SetInt(i)
+	}
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	sheet = f2.Sheet["Sheet1"]
+	c.Assert(sheet.Cols, HasLen, 5)
+	sheet.setColumnWidth(3, 15)
+	parts, err := f2.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/worksheets/sheet1.xml"], `<cols>`+
+		`<col collapsed="false" hidden="false" max="3" min="1" width="20"></col>`+
+		`<col collapsed="false" hidden="false" max="4" min="4" width="15"></col>`+
+		`<col collapsed="false" hidden="false" max="5" min="5" width="20"></col>`+
+		`</cols>`), Equals, true)
+}
@@ -1,6 +1,10 @@
 package xlsx
 
-import "reflect"
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
 
 // Writes an array to row r. Accepts a pointer to array type 'e',
 // and writes the number of columns to write, 'cols'. If 'cols' is < 0,
@@ -57,46 +61,154 @@ func (r *Row) WriteSlice(e interface{}, cols int) int {
 	return i
 }
 
+// CellMarshaler is implemented by types that write themselves to a
+// cell, for WriteStruct.
+type CellMarshaler interface {
+	MarshalCell(cell *Cell) error
+}
+
 // Writes a struct to row r. Accepts a pointer to struct type 'e',
 // and the number of columns to write, `cols`. If 'cols' is < 0,
 // the entire struct will be written if possible. Returns -1 if the 'e'
-// doesn't point to a struct, otherwise the number of columns written
+// doesn't point to a struct, or a field can't be written, otherwise
+// the number of columns written.
+//
+// Each field takes a column.  Strings, bools, all kinds of number,
+// time.Time, types that implement CellMarshaler or fmt.Stringer, and
+// pointers to them, are written; other fields, and nil pointers, leave
+// their cell empty.  The fields of embedded structs take the place of
+// the struct.  Tags such as `xlsx:"Name,format=0.00,omitempty"` give a
+// field's header, the number format of its cells, and leave empty
+// values out; fields tagged `xlsx:"-"` are skipped.
 func (r *Row) WriteStruct(e interface{}, cols int) int {
 	if cols == 0 {
 		return cols
 	}
 
-	v := reflect.ValueOf(e).Elem()
-	if v.Kind() != reflect.Struct {
+	v := reflect.ValueOf(e)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
 		return -1 // bail if it's not a struct
 	}
+	v = v.Elem()
 
-	n := v.NumField() // number of fields in struct
-	if cols < n && cols > 0 {
-		n = cols
+	fields := structFields(v.Type())
+	if cols < len(fields) && cols > 0 {
+		fields = fields[:cols]
 	}
+	k, err := r.writeStruct(v, fields)
+	if err != nil {
+		return -1
+	}
+	return k
+}
 
+// writeStruct writes the fields of the struct v to the Row, and
+// returns the number of them that weren't left empty.
+func (r *Row) writeStruct(v reflect.Value, fields []structField) (int, error) {
 	var k int
-	for i := 0; i < n; i, k = i+1, k+1 {
-		f := v.Field(i).Kind()
+	for _, field := range fields {
 		cell := r.AddCell()
+		fieldValue, ok := fieldByIndex(v, field.index, false)
+		if !ok {
+			continue
+		}
+		written, err := writeCell(cell, fieldValue, field)
+		if err != nil {
+			return k, r.cellError(len(r.Cells)-1, field, err)
+		}
+		if written {
+			k++
+		}
+	}
+	return k, nil
+}
 
-		switch f {
-		case reflect.Int, reflect.Int8,
-			reflect.Int16, reflect.Int32:
-			cell.SetInt(v.Field(i).Interface().(int))
-		case reflect.Int64:
-			cell.SetInt64(v.Field(i).Interface().(int64))
-		case reflect.String:
-			cell.SetString(v.Field(i).Interface().(string))
-		case reflect.Float64, reflect.Float32:
-			cell.SetFloat(v.Field(i).Interface().(float64))
-		case reflect.Bool:
-			cell.SetBool(v.Field(i).Interface().(bool))
-		default:
-			k-- // nothing set so reset to previous
+// writeCell writes v, the value of field, to cell.  It reports whether
+// it wrote anything.
+func writeCell(cell *Cell, v reflect.Value, field structField) (bool, error) {
+	if field.omitEmpty && isEmptyValue(v) {
+		return false, nil
+	}
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return false, nil
+	}
+	if v.Type().Implements(cellMarshalerType) {
+		return true, v.Interface().(CellMarshaler).MarshalCell(cell)
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(cellMarshalerType) {
+		return true, v.Addr().Interface().(CellMarshaler).MarshalCell(cell)
+	}
+	if v.Kind() == reflect.Ptr {
+		return writeCell(cell, v.Elem(), field)
+	}
+	if v.Type() == timeType {
+		format := field.format
+		if format == "" {
+			format = "yyyy-mm-dd hh:mm:ss"
+		}
+		cell.SetDateTimeWithFormat(v.Interface().(time.Time), format)
+		return true, nil
+	}
+	if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		cell.SetString(stringer.String())
+		return true, nil
+	}
+	if v.CanAddr() {
+		if stringer, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			cell.SetString(stringer.String())
+			return true, nil
 		}
 	}
 
-	return k
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8,
+		reflect.Int16, reflect.Int32, reflect.Int64:
+		cell.SetInt64(v.Int())
+	case reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		cell.SetUint64(v.Uint())
+	case reflect.String:
+		cell.SetString(v.String())
+		return true, nil
+	case reflect.Float64, reflect.Float32:
+		if field.format != "" {
+			cell.SetFloatWithFormat(v.Float(), field.format)
+		} else {
+			cell.SetFloat(v.Float())
+		}
+		return true, nil
+	case reflect.Bool:
+		cell.SetBool(v.Bool())
+		return true, nil
+	default:
+		return false, nil // nothing set
+	}
+	if field.format != "" {
+		cell.numFmt = field.format
+	}
+	return true, nil
+}
+
+// isEmptyValue reports whether v is empty, and so left out of the
+// cells of fields tagged omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
 }
@@ -1,6 +1,13 @@
 package xlsx
 
 import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
 	. "gopkg.in/check.v1"
 )
 
@@ -84,3 +91,175 @@ func (r *RowSuite) TestWriteSlice(c *C) {
 	c3 := row3.Cells[0].Bool()
 	c.Assert(c3, Equals, true)
 }
+
+type writeLevel int
+
+func (l writeLevel) String() string {
+	return [...]string{"low", "high"}[l]
+}
+
+// writeMoney is written as a number of pounds, and kept as pence.
+type writeMoney struct {
+	pence int64
+}
+
+func (m writeMoney) MarshalCell(cell *Cell) error {
+	cell.SetFloatWithFormat(float64(m.pence)/100, "0.00")
+	return nil
+}
+
+func (m *writeMoney) UnmarshalCell(cell *Cell) error {
+	f, err := cell.Float()
+	m.pence = int64(f*100 + 0.5)
+	return err
+}
+
+type writeAudit struct {
+	Created time.Time `xlsx:"Created,format=d-mmm-yy"`
+	By      string    `xlsx:"By,omitempty"`
+}
+
+func (r *RowSuite) TestWriteStructKinds(c *C) {
+	type e struct {
+		writeAudit
+		Small  int8
+		Ratio  float32 `xlsx:",format=0.00"`
+		Count  uint16
+		Level  writeLevel
+		Price  writeMoney
+		Maybe  *int
+		Notes  []string
+		Secret string `xlsx:"-"`
+		hidden string
+	}
+	sheet := NewFile().AddSheet("Test1")
+	row := sheet.AddRow()
+	written := e{
+		writeAudit: writeAudit{Created: time.Date(2015, 1, 1, 12, 0, 0, 0, time.UTC)},
+		Small:      -7,
+		Ratio:      0.25,
+		Count:      40000,
+		Level:      1,
+		Price:      writeMoney{1999},
+		Secret:     "secret",
+		hidden:     "hidden",
+	}
+	c.Assert(row.WriteStruct(&written, -1), Equals, 6)
+	c.Assert(row.Cells, HasLen, 9)
+	c.Assert(row.Cells[0].Value, Equals, "42005.5")
+	c.Assert(row.Cells[0].String(), Equals, "1-Jan-15")
+	c.Assert(row.Cells[1].Value, Equals, "")
+	c.Assert(row.Cells[2].Value, Equals, "-7")
+	c.Assert(row.Cells[3].String(), Equals, "0.25")
+	c.Assert(row.Cells[4].Value, Equals, "40000")
+	c.Assert(row.Cells[5].Value, Equals, "high")
+	c.Assert(row.Cells[6].Value, Equals, "19.99")
+	c.Assert(row.Cells[7].Value, Equals, "")
+	c.Assert(row.Cells[8].Value, Equals, "")
+
+	// Only the first columns are written when asked.
+	row = sheet.AddRow()
+	c.Assert(row.WriteStruct(&written, 3), Equals, 2)
+	c.Assert(row.Cells, HasLen, 3)
+
+	c.Assert(sheet.AddRow().WriteStruct(written, -1), Equals, -1)
+}
+
+// What WriteStruct writes, ReadStruct reads back, embedded structs and
+// CellUnmarshalers included.
+func (r *RowSuite) TestWriteStructReadStruct(c *C) {
+	type e struct {
+		writeAudit
+		Price writeMoney
+		Tax   *writeMoney
+		Count *uint
+	}
+	sheet := NewFile().AddSheet("Test1")
+	header := sheet.AddRow()
+	for _, name := range []string{"Created", "By", "Price", "Tax", "Count"} {
+		header.AddCell().SetString(name)
+	}
+	count := uint(3)
+	written := e{writeAudit{time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), "Ann"}, writeMoney{1999}, &writeMoney{250}, &count}
+	c.Assert(sheet.AddRow().WriteStruct(&written, -1), Equals, 5)
+
+	var read []e
+	c.Assert(sheet.ReadAll(&read), IsNil)
+	c.Assert(read, DeepEquals, []e{written})
+}
+
+type writeFailure struct{}
+
+func (writeFailure) MarshalCell(cell *Cell) error {
+	return errors.New("no can do")
+}
+
+func (r *RowSuite) TestWriteStructErrors(c *C) {
+	type e struct {
+		Name    string
+		Failure writeFailure
+	}
+	sheet := NewFile().AddSheet("Test1")
+	row := sheet.AddRow()
+	c.Assert(row.WriteStruct(&e{Name: "Eric"}, -1), Equals, -1)
+	_, err := row.writeStruct(reflect.ValueOf(e{}), structFields(reflect.TypeOf(e{})))
+	c.Assert(err, ErrorMatches, `sheet "Test1", cell D1, field Failure: no can do`)
+}
+
+// The formats of the cells that WriteStruct writes reach the
+// stylesheet, so that they are there when the file is read back.
+func (r *RowSuite) TestWriteStructFormatsRoundTrip(c *C) {
+	type e struct {
+		writeAudit
+		Ratio float32 `xlsx:",format=0.00"`
+		Share float64 `xlsx:",format=0.0%"`
+	}
+	f := NewFile()
+	sheet := f.AddSheet("Test1")
+	written := e{writeAudit: writeAudit{Created: time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)}, Ratio: 0.25, Share: 0.5}
+	c.Assert(sheet.AddRow().WriteStruct(&written, -1), Equals, 3)
+
+	parts, err := f.MarshallParts()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(parts["xl/styles.xml"], `<numFmts count="1"><numFmt numFmtId="164" formatCode="0.0%"/></numFmts>`), Equals, true)
+	for _, id := range []string{"15", "2", "164"} {
+		c.Assert(strings.Contains(parts["xl/styles.xml"], ` numFmtId="`+id+`"><alignment`), Equals, true, Commentf(id))
+	}
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	cells := f2.Sheet["Test1"].Rows[0].Cells
+	c.Assert(cells[0].FormattedValue(), Equals, "1-Jan-15")
+	c.Assert(cells[2].FormattedValue(), Equals, "0.25")
+	c.Assert(cells[3].GetNumberFormat(), Equals, "0.0%")
+}
+
+// Dates written to a File whose dates count from 1904 count from 1904
+// too, and are read back as they were written.
+func (r *RowSuite) TestWriteStructReadStruct1904(c *C) {
+	type e struct {
+		When time.Time `xlsx:",format=yyyy-mm-dd"`
+	}
+	f := NewFile()
+	f.Date1904 = true
+	sheet := f.AddSheet("Test1")
+	written := e{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c.Assert(sheet.AddRow().WriteStruct(&written, -1), Equals, 1)
+	c.Assert(sheet.Rows[0].Cells[0].Value, Equals, "42369")
+
+	var buf bytes.Buffer
+	c.Assert(f.Write(&buf), IsNil)
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(err, IsNil)
+	f2, err := ReadZipReader(zr)
+	c.Assert(err, IsNil)
+	row := f2.Sheet["Test1"].Rows[0]
+	c.Assert(row.Cells[0].FormattedValue(), Equals, "2020-01-01")
+	var read e
+	c.Assert(row.ReadStruct(&read), IsNil)
+	c.Assert(read, DeepEquals, written)
+}
@@ -2,6 +2,7 @@ package xlsx
 
 import (
 	"encoding/xml"
+	"strings"
 )
 
 type xlsxTypes struct {
@@ -48,3 +49,48 @@ func MakeDefaultContentTypes() (types xlsxTypes) {
 	types.Defaults[1].ContentType = "application/xml"
 	return
 }
+
+// keepOriginal adds the defaults and overrides declared by the
+// content types of a file that has been read, original, that are
+// needed by the parts retained from it.  The workbook's own content
+// type is taken from original too, as it is what marks a workbook as,
+// for example, macro-enabled or a template.
+func (types *xlsxTypes) keepOriginal(original *xlsxTypes, retainedParts map[string]string) {
+	for _, def := range original.Defaults {
+		if !types.hasDefault(def.Extension) {
+			types.Defaults = append(types.Defaults, def)
+		}
+	}
+	for _, override := range original.Overrides {
+		if override.PartName == "/xl/workbook.xml" {
+			for i := range types.Overrides {
+				if types.Overrides[i].PartName == override.PartName {
+					types.Overrides[i].ContentType = override.ContentType
+				}
+			}
+			continue
+		}
+		_, retained := retainedParts[strings.TrimPrefix(override.PartName, "/")]
+		if retained && !types.hasOverride(override.PartName) {
+			types.Overrides = append(types.Overrides, override)
+		}
+	}
+}
+
+func (types *xlsxTypes) hasDefault(extension string) bool {
+	for _, def := range types.Defaults {
+		if strings.EqualFold(def.Extension, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+func (types *xlsxTypes) hasOverride(partName string) bool {
+	for _, override := range types.Overrides {
+		if override.PartName == partName {
+			return true
+		}
+	}
+	return false
+}
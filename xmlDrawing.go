@@ -0,0 +1,78 @@
+package xlsx
+
+import (
+	"encoding/xml"
+)
+
+// xlsxWsDr directly maps the wsDr element in the namespace
+// http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+//
+// The anchors are captured in the order they appear, whatever kind
+// they are, and their kind is given by their XMLName.
+type xlsxWsDr struct {
+	XMLName xml.Name            `xml:"http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing wsDr"`
+	Anchors []xlsxDrawingAnchor `xml:",any"`
+}
+
+// xlsxDrawingAnchor directly maps the twoCellAnchor, oneCellAnchor
+// and absoluteAnchor elements in the namespace
+// http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxDrawingAnchor struct {
+	XMLName xml.Name
+	EditAs  string             `xml:"editAs,attr"`
+	From    *xlsxDrawingMarker `xml:"from"`
+	To      *xlsxDrawingMarker `xml:"to"`
+	Pos     *xlsxDrawingPoint  `xml:"pos"`
+	Ext     *xlsxDrawingExtent `xml:"ext"`
+	Pic     *xlsxDrawingPic    `xml:"pic"`
+}
+
+// xlsxDrawingMarker directly maps the from and to elements in the
+// namespace
+// http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxDrawingMarker struct {
+	Col    int   `xml:"col"`
+	ColOff int64 `xml:"colOff"`
+	Row    int   `xml:"row"`
+	RowOff int64 `xml:"rowOff"`
+}
+
+// xlsxDrawingPoint directly maps the pos and off elements in the
+// namespace http://schemas.openxmlformats.org/drawingml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxDrawingPoint struct {
+	X int64 `xml:"x,attr"`
+	Y int64 `xml:"y,attr"`
+}
+
+// xlsxDrawingExtent directly maps the ext element in the namespace
+// http://schemas.openxmlformats.org/drawingml/2006/main - currently I
+// have not checked it for completeness - it does as much as I need.
+type xlsxDrawingExtent struct {
+	Cx int64 `xml:"cx,attr"`
+	Cy int64 `xml:"cy,attr"`
+}
+
+// xlsxDrawingPic directly maps the pic element in the namespace
+// http://schemas.openxmlformats.org/drawingml/2006/spreadsheetDrawing
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxDrawingPic struct {
+	CNvPr struct {
+		Name  string `xml:"name,attr"`
+		Descr string `xml:"descr,attr"`
+	} `xml:"nvPicPr>cNvPr"`
+	Blip struct {
+		Embed string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships embed,attr"`
+	} `xml:"blipFill>blip"`
+	Xfrm struct {
+		Ext *xlsxDrawingExtent `xml:"ext"`
+	} `xml:"spPr>xfrm"`
+}
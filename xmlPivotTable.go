@@ -0,0 +1,257 @@
+package xlsx
+
+import (
+	"encoding/xml"
+)
+
+// xlsxPivotTableDefinition directly maps the pivotTableDefinition
+// element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxPivotTableDefinition struct {
+	XMLName               xml.Name                 `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main pivotTableDefinition"`
+	Name                  string                   `xml:"name,attr"`
+	CacheId               int                      `xml:"cacheId,attr"`
+	DataCaption           string                   `xml:"dataCaption,attr"`
+	UpdatedVersion        int                      `xml:"updatedVersion,attr,omitempty"`
+	MinRefreshableVersion int                      `xml:"minRefreshableVersion,attr,omitempty"`
+	CreatedVersion        int                      `xml:"createdVersion,attr,omitempty"`
+	UseAutoFormatting     bool                     `xml:"useAutoFormatting,attr,omitempty"`
+	ItemPrintTitles       bool                     `xml:"itemPrintTitles,attr,omitempty"`
+	Outline               bool                     `xml:"outline,attr,omitempty"`
+	OutlineData           bool                     `xml:"outlineData,attr,omitempty"`
+	Location              xlsxPivotLocation        `xml:"location"`
+	PivotFields           xlsxPivotFields          `xml:"pivotFields"`
+	RowFields             *xlsxPivotFieldRefs      `xml:"rowFields"`
+	ColFields             *xlsxPivotFieldRefs      `xml:"colFields"`
+	PageFields            *xlsxPivotPageFields     `xml:"pageFields"`
+	DataFields            *xlsxPivotDataFields     `xml:"dataFields"`
+	PivotTableStyleInfo   *xlsxPivotTableStyleInfo `xml:"pivotTableStyleInfo"`
+}
+
+// xlsxPivotLocation directly maps the location element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotLocation struct {
+	Ref            string `xml:"ref,attr"`
+	FirstHeaderRow int    `xml:"firstHeaderRow,attr"`
+	FirstDataRow   int    `xml:"firstDataRow,attr"`
+	FirstDataCol   int    `xml:"firstDataCol,attr"`
+	RowPageCount   int    `xml:"rowPageCount,attr,omitempty"`
+	ColPageCount   int    `xml:"colPageCount,attr,omitempty"`
+}
+
+// xlsxPivotFields directly maps the pivotFields element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotFields struct {
+	Count      int              `xml:"count,attr"`
+	PivotField []xlsxPivotField `xml:"pivotField"`
+}
+
+// xlsxPivotField directly maps the pivotField element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotField struct {
+	Axis      string          `xml:"axis,attr,omitempty"`
+	DataField bool            `xml:"dataField,attr,omitempty"`
+	ShowAll   bool            `xml:"showAll,attr"`
+	Items     *xlsxPivotItems `xml:"items"`
+}
+
+// xlsxPivotItems directly maps the items element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxPivotItems struct {
+	Count int             `xml:"count,attr"`
+	Item  []xlsxPivotItem `xml:"item"`
+}
+
+// xlsxPivotItem directly maps the item element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxPivotItem struct {
+	X *int   `xml:"x,attr,omitempty"`
+	T string `xml:"t,attr,omitempty"`
+}
+
+// xlsxPivotFieldRefs directly maps the rowFields and colFields
+// elements in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxPivotFieldRefs struct {
+	Count int                 `xml:"count,attr"`
+	Field []xlsxPivotFieldRef `xml:"field"`
+}
+
+// xlsxPivotFieldRef directly maps the field element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.  An X of -2 stands for the values of the data fields.
+type xlsxPivotFieldRef struct {
+	X int `xml:"x,attr"`
+}
+
+// xlsxPivotPageFields directly maps the pageFields element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotPageFields struct {
+	Count     int                  `xml:"count,attr"`
+	PageField []xlsxPivotPageField `xml:"pageField"`
+}
+
+// xlsxPivotPageField directly maps the pageField element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotPageField struct {
+	Fld  int `xml:"fld,attr"`
+	Hier int `xml:"hier,attr"`
+}
+
+// xlsxPivotDataFields directly maps the dataFields element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotDataFields struct {
+	Count     int                  `xml:"count,attr"`
+	DataField []xlsxPivotDataField `xml:"dataField"`
+}
+
+// xlsxPivotDataField directly maps the dataField element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotDataField struct {
+	Name      string `xml:"name,attr,omitempty"`
+	Fld       int    `xml:"fld,attr"`
+	Subtotal  string `xml:"subtotal,attr,omitempty"`
+	BaseField int    `xml:"baseField,attr"`
+	BaseItem  int    `xml:"baseItem,attr"`
+}
+
+// xlsxPivotTableStyleInfo directly maps the pivotTableStyleInfo
+// element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxPivotTableStyleInfo struct {
+	Name           string `xml:"name,attr"`
+	ShowRowHeaders bool   `xml:"showRowHeaders,attr"`
+	ShowColHeaders bool   `xml:"showColHeaders,attr"`
+	ShowRowStripes bool   `xml:"showRowStripes,attr"`
+	ShowColStripes bool   `xml:"showColStripes,attr"`
+	ShowLastColumn bool   `xml:"showLastColumn,attr"`
+}
+
+// xlsxPivotCacheDefinition directly maps the pivotCacheDefinition
+// element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxPivotCacheDefinition struct {
+	XMLName               xml.Name             `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main pivotCacheDefinition"`
+	RId                   string               `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr,omitempty"`
+	RefreshOnLoad         bool                 `xml:"refreshOnLoad,attr,omitempty"`
+	RefreshedBy           string               `xml:"refreshedBy,attr,omitempty"`
+	CreatedVersion        int                  `xml:"createdVersion,attr,omitempty"`
+	RefreshedVersion      int                  `xml:"refreshedVersion,attr,omitempty"`
+	MinRefreshableVersion int                  `xml:"minRefreshableVersion,attr,omitempty"`
+	RecordCount           int                  `xml:"recordCount,attr"`
+	CacheSource           xlsxPivotCacheSource `xml:"cacheSource"`
+	CacheFields           xlsxPivotCacheFields `xml:"cacheFields"`
+}
+
+// xlsxPivotCacheSource directly maps the cacheSource element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotCacheSource struct {
+	Type            string                    `xml:"type,attr"`
+	WorksheetSource *xlsxPivotWorksheetSource `xml:"worksheetSource"`
+}
+
+// xlsxPivotWorksheetSource directly maps the worksheetSource element
+// in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.  Name is set, instead of Ref and Sheet, when the source
+// is a defined name or table.
+type xlsxPivotWorksheetSource struct {
+	Ref   string `xml:"ref,attr,omitempty"`
+	Name  string `xml:"name,attr,omitempty"`
+	Sheet string `xml:"sheet,attr,omitempty"`
+}
+
+// xlsxPivotCacheFields directly maps the cacheFields element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotCacheFields struct {
+	Count      int                   `xml:"count,attr"`
+	CacheField []xlsxPivotCacheField `xml:"cacheField"`
+}
+
+// xlsxPivotCacheField directly maps the cacheField element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxPivotCacheField struct {
+	Name        string               `xml:"name,attr"`
+	NumFmtId    int                  `xml:"numFmtId,attr"`
+	SharedItems xlsxPivotSharedItems `xml:"sharedItems"`
+}
+
+// xlsxPivotSharedItems directly maps the sharedItems element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.  The flags are "0" or "1", and left out when they
+// have their default value.
+type xlsxPivotSharedItems struct {
+	ContainsSemiMixedTypes string                `xml:"containsSemiMixedTypes,attr,omitempty"`
+	ContainsString         string                `xml:"containsString,attr,omitempty"`
+	ContainsBlank          string                `xml:"containsBlank,attr,omitempty"`
+	ContainsMixedTypes     string                `xml:"containsMixedTypes,attr,omitempty"`
+	ContainsNumber         string                `xml:"containsNumber,attr,omitempty"`
+	MinValue               string                `xml:"minValue,attr,omitempty"`
+	MaxValue               string                `xml:"maxValue,attr,omitempty"`
+	Count                  int                   `xml:"count,attr,omitempty"`
+	Items                  []xlsxPivotCacheValue `xml:",any"`
+}
+
+// xlsxPivotCacheValue directly maps the s, n, b, e, d, m and x
+// elements, of shared items and records, in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.  Which of them it is is given by its XMLName.
+type xlsxPivotCacheValue struct {
+	XMLName xml.Name
+	V       string `xml:"v,attr,omitempty"`
+}
+
+// xlsxPivotCacheRecords directly maps the pivotCacheRecords element
+// in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxPivotCacheRecords struct {
+	XMLName xml.Name               `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main pivotCacheRecords"`
+	Count   int                    `xml:"count,attr"`
+	R       []xlsxPivotCacheRecord `xml:"r"`
+}
+
+// xlsxPivotCacheRecord directly maps the r element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxPivotCacheRecord struct {
+	Values []xlsxPivotCacheValue `xml:",any"`
+}
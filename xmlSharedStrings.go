@@ -2,6 +2,7 @@ package xlsx
 
 import (
 	"encoding/xml"
+	"strings"
 )
 
 // xlsxSST directly maps the sst element from the namespace
@@ -18,15 +19,85 @@ type xlsxSST struct {
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked this for completeness - it does as
 // much as I need.
+//
+// The same structure is used for the is element, holding an inline
+// string, in a worksheet cell.
 type xlsxSI struct {
 	T string  `xml:"t"`
 	R []xlsxR `xml:"r"`
 }
 
+// String returns the text of the string item, which is held either
+// directly or spread across a number of rich text runs.
+func (si *xlsxSI) String() string {
+	if len(si.R) == 0 {
+		return si.T
+	}
+	result := ""
+	for _, r := range si.R {
+		result += r.T.Text
+	}
+	return result
+}
+
+// MarshalXML writes the string item with either its text or its
+// runs, as the schema allows only one of the two.
+func (si xlsxSI) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if len(si.R) > 0 {
+		return e.EncodeElement(struct {
+			R []xlsxR `xml:"r"`
+		}{si.R}, start)
+	}
+	return e.EncodeElement(struct {
+		T xlsxT `xml:"t"`
+	}{makeXLSXT(si.T)}, start)
+}
+
 // xlsxR directly maps the r element from the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked this for completeness - it does as
 // much as I need.
 type xlsxR struct {
-	T string `xml:"t"`
+	RPr *xlsxRunProperties `xml:"rPr"`
+	T   xlsxT              `xml:"t"`
+}
+
+// xlsxRunProperties directly maps the rPr element from the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked this for completeness - it does as
+// much as I need.
+type xlsxRunProperties struct {
+	B         *xlsxVal   `xml:"b"`
+	I         *xlsxVal   `xml:"i"`
+	Strike    *xlsxVal   `xml:"strike"`
+	Outline   *xlsxVal   `xml:"outline"`
+	Shadow    *xlsxVal   `xml:"shadow"`
+	Condense  *xlsxVal   `xml:"condense"`
+	Extend    *xlsxVal   `xml:"extend"`
+	U         *xlsxVal   `xml:"u"`
+	VertAlign *xlsxVal   `xml:"vertAlign"`
+	Sz        *xlsxVal   `xml:"sz"`
+	Color     *xlsxColor `xml:"color"`
+	RFont     *xlsxVal   `xml:"rFont"`
+	Family    *xlsxVal   `xml:"family"`
+	Charset   *xlsxVal   `xml:"charset"`
+	Scheme    *xlsxVal   `xml:"scheme"`
+}
+
+// xlsxT directly maps the t element from the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main.  Text
+// with leading or trailing whitespace has to be marked with
+// xml:space="preserve" or the whitespace is lost.
+type xlsxT struct {
+	Space string `xml:"http://www.w3.org/XML/1998/namespace space,attr,omitempty"`
+	Text  string `xml:",chardata"`
+}
+
+// makeXLSXT returns the xlsxT representation of text.
+func makeXLSXT(text string) xlsxT {
+	t := xlsxT{Text: text}
+	if strings.TrimSpace(text) != text {
+		t.Space = "preserve"
+	}
+	return t
 }
@@ -8,11 +8,13 @@
 package xlsx
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 )
 
 // xlsxStyle directly maps the styleSheet element in the namespace
@@ -29,27 +31,104 @@ type xlsxStyleSheet struct {
 	CellXfs      xlsxCellXfs      `xml:"cellXfs,omitempty"`
 	NumFmts      xlsxNumFmts      `xml:"numFmts,omitempty"`
 
+	// We don't model these in any detail, but keep them so that
+	// they survive being read and written back out.
+	CellStyles  *xlsxRawXML `xml:"cellStyles"`
+	Dxfs        *xlsxRawXML `xml:"dxfs"`
+	TableStyles *xlsxRawXML `xml:"tableStyles"`
+	Colors      *xlsxRawXML `xml:"colors"`
+	ExtLst      *xlsxRawXML `xml:"extLst"`
+	Attrs       []xml.Attr  `xml:",any,attr"`
+
 	theme      *theme
 	styleCache map[int]*Style // `-`
 	numFmtRefTable map[int]xlsxNumFmt `xml:"-"`
 	lock       *sync.RWMutex
+
+	// The following maps intern the style components we write,
+	// so that adding a component is a constant time operation,
+	// rather than a scan of everything added so far.  They are
+	// built lazily, from whatever the slices already contain, the
+	// first time they are needed.
+	fontIndex        map[xlsxFontKey]int
+	fillIndex        map[xlsxFillKey]int
+	borderIndex      map[xlsxBorderKey]int
+	cellStyleXfIndex map[xlsxXf]int
+	cellXfIndex      map[xlsxXf]int
+	// styleXfCache maps a *Style, and number format, as used by
+	// cells, to the index of the CellXf that was generated for
+	// them.  This means that cells sharing a Style only pay for it
+	// once.
+	styleXfCache map[styleXfKey]int
+
+	// original holds the components of a stylesheet that was
+	// read from a file, reset returns us to this state.
+	original xlsxStyleComponents
+	// styleOrigins records the CellXf index, and the value at
+	// the time, of each Style handed out by getStyle, so that a
+	// cell whose Style is unchanged keeps its original index.
+	styleOrigins map[*Style]styleOrigin
+}
+
+// xlsxStyleComponents holds the parts of a stylesheet that are
+// extended as styles are added.
+type xlsxStyleComponents struct {
+	Fonts        xlsxFonts
+	Fills        xlsxFills
+	Borders      xlsxBorders
+	CellStyleXfs xlsxCellStyleXfs
+	CellXfs      xlsxCellXfs
+	NumFmts      xlsxNumFmts
+}
+
+type styleXfKey struct {
+	style  *Style
+	numFmt string
+}
+
+type styleOrigin struct {
+	xfId  int
+	style Style
 }
 
 func newXlsxStyleSheet(t *theme) *xlsxStyleSheet {
 	stylesheet := new(xlsxStyleSheet)
 	stylesheet.theme = t
 	stylesheet.styleCache = make(map[int]*Style)
+	stylesheet.styleOrigins = make(map[*Style]styleOrigin)
 	stylesheet.lock = new(sync.RWMutex)
 	return stylesheet
 }
 
+// keepOriginal records the current components of the stylesheet as
+// those read from file, so that reset will preserve them.
+func (styles *xlsxStyleSheet) keepOriginal() {
+	styles.original = xlsxStyleComponents{
+		Fonts:        styles.Fonts,
+		Fills:        styles.Fills,
+		Borders:      styles.Borders,
+		CellStyleXfs: styles.CellStyleXfs,
+		CellXfs:      styles.CellXfs,
+		NumFmts:      styles.NumFmts,
+	}
+}
+
+// reset discards any components added since the stylesheet was
+// created or read from file.
 func (styles *xlsxStyleSheet) reset() {
-	styles.Fonts = xlsxFonts{}
-	styles.Fills = xlsxFills{}
-	styles.Borders = xlsxBorders{}
-	styles.CellStyleXfs = xlsxCellStyleXfs{}
-	styles.CellXfs = xlsxCellXfs{}
-	styles.NumFmts = xlsxNumFmts{}
+	styles.Fonts = styles.original.Fonts
+	styles.Fills = styles.original.Fills
+	styles.Borders = styles.original.Borders
+	styles.CellStyleXfs = styles.original.CellStyleXfs
+	styles.CellXfs = styles.original.CellXfs
+	styles.NumFmts = styles.original.NumFmts
+	buildNumFmtRefTable(styles)
+	styles.fontIndex = nil
+	styles.fillIndex = nil
+	styles.borderIndex = nil
+	styles.cellStyleXfIndex = nil
+	styles.cellXfIndex = nil
+	styles.styleXfCache = nil
 }
 
 func (styles *xlsxStyleSheet) getStyle(styleIndex int) (style *Style) {
@@ -80,6 +159,9 @@ func (styles *xlsxStyleSheet) getStyle(styleIndex int) (style *Style) {
 		style.ApplyBorder = xf.ApplyBorder || styleXf.ApplyBorder
 		style.ApplyFill = xf.ApplyFill || styleXf.ApplyFill
 		style.ApplyFont = xf.ApplyFont || styleXf.ApplyFont
+		style.ApplyProtection = xf.ApplyProtection || styleXf.ApplyProtection
+		style.Protection.Locked = xf.Protection.isLocked()
+		style.Protection.Hidden = xf.Protection.isHidden()
 
 		if xf.BorderId > -1 && xf.BorderId < styles.Borders.Count {
 			var border xlsxBorder
@@ -120,6 +202,7 @@ func (styles *xlsxStyleSheet) getStyle(styleIndex int) (style *Style) {
 		}
 		styles.lock.Lock()
 		styles.styleCache[styleIndex] = style
+		styles.styleOrigins[style] = styleOrigin{xfId: styleIndex, style: *style}
 		styles.lock.Unlock()
 	}
 	return style
@@ -219,78 +302,208 @@ func (styles *xlsxStyleSheet) getNumberFormat(styleIndex int) string {
 			numberFormat = numFmt.FormatCode
 		}
 	}
-	return strings.ToLower(numberFormat)
+	return foldNumFmt(numberFormat)
+}
+
+// addStyle adds the style elements that correspond to a Style, and a
+// number format, to the stylesheet and returns the index of the
+// resulting CellXf.  Results are cached by Style pointer, so a Style
+// shared by many cells is only converted once.
+func (styles *xlsxStyleSheet) addStyle(style *Style, numFmt string) (xfId int) {
+	if styles.styleXfCache == nil {
+		styles.styleXfCache = make(map[styleXfKey]int)
+	}
+	key := styleXfKey{style, numFmt}
+	xfId, ok := styles.styleXfCache[key]
+	if ok {
+		return xfId
+	}
+	origin, ok := styles.styleOrigins[style]
+	if ok && origin.style == *style && sameNumFmt(styles.getNumberFormat(origin.xfId), numFmt) {
+		styles.styleXfCache[key] = origin.xfId
+		return origin.xfId
+	}
+	xFont, xFill, xBorder, xCellStyleXf, xCellXf := style.makeXLSXStyleElements()
+	fontId := styles.addFont(xFont)
+	fillId := styles.addFill(xFill)
+	borderId := styles.addBorder(xBorder)
+	xCellStyleXf.FontId = fontId
+	xCellStyleXf.FillId = fillId
+	xCellStyleXf.BorderId = borderId
+	xCellStyleXf.NumFmtId = 0 // General
+	xCellXf.FontId = fontId
+	xCellXf.FillId = fillId
+	xCellXf.BorderId = borderId
+	xCellXf.NumFmtId = styles.numFmtId(numFmt)
+	xCellXf.XfId = styles.addCellStyleXf(xCellStyleXf)
+	xfId = styles.addCellXf(xCellXf)
+	styles.styleXfCache[key] = xfId
+	return xfId
+}
+
+// numFmtId returns the id of the number format with the given code,
+// adding it to the stylesheet if it is neither built in nor there
+// already.  General is 0.
+func (styles *xlsxStyleSheet) numFmtId(formatCode string) int {
+	if formatCode == "" || strings.EqualFold(formatCode, "general") {
+		return 0
+	}
+	for id := 1; id < 164; id++ {
+		if builtin := getBuiltinNumberFormat(id); builtin != "" && sameNumFmt(builtin, formatCode) {
+			return id
+		}
+	}
+	maxId := 163
+	for _, numFmt := range styles.NumFmts.NumFmt {
+		if sameNumFmt(numFmt.FormatCode, formatCode) {
+			return numFmt.NumFmtId
+		}
+		if numFmt.NumFmtId > maxId {
+			maxId = numFmt.NumFmtId
+		}
+	}
+	styles.addNumFmt(xlsxNumFmt{NumFmtId: maxId + 1, FormatCode: formatCode})
+	return maxId + 1
+}
+
+// sameNumFmt reports whether two number format codes are the same.
+// Their codes are compared regardless of case, but the literal text
+// in them, quoted or escaped with a backslash, has to match exactly.
+func sameNumFmt(a, b string) bool {
+	return foldNumFmt(a) == foldNumFmt(b)
+}
+
+// foldNumFmt returns a number format code with everything but its
+// literal text in lower case.
+func foldNumFmt(code string) string {
+	folded := []rune(code)
+	quoted := false
+	for i := 0; i < len(folded); i++ {
+		switch {
+		case folded[i] == '"':
+			quoted = !quoted
+		case quoted:
+		case folded[i] == '\\':
+			i++
+		default:
+			folded[i] = unicode.ToLower(folded[i])
+		}
+	}
+	return string(folded)
 }
 
 func (styles *xlsxStyleSheet) addFont(xFont xlsxFont) (index int) {
-	var font xlsxFont
 	if xFont.Name.Val == "" {
 		return 0
 	}
-	for index, font = range styles.Fonts.Font {
-		if font.Equals(xFont) {
-			return index
+	if styles.fontIndex == nil {
+		styles.fontIndex = make(map[xlsxFontKey]int, len(styles.Fonts.Font))
+		for i, font := range styles.Fonts.Font {
+			key := font.key()
+			if _, ok := styles.fontIndex[key]; !ok {
+				styles.fontIndex[key] = i
+			}
 		}
 	}
+	key := xFont.key()
+	index, ok := styles.fontIndex[key]
+	if ok {
+		return index
+	}
 	styles.Fonts.Font = append(styles.Fonts.Font, xFont)
 	index = styles.Fonts.Count
 	styles.Fonts.Count += 1
+	styles.fontIndex[key] = index
 	return
 }
 
 func (styles *xlsxStyleSheet) addFill(xFill xlsxFill) (index int) {
-	var fill xlsxFill
-	for index, fill = range styles.Fills.Fill {
-		if fill.Equals(xFill) {
-			return index
+	if styles.fillIndex == nil {
+		styles.fillIndex = make(map[xlsxFillKey]int, len(styles.Fills.Fill))
+		for i, fill := range styles.Fills.Fill {
+			key := fill.key()
+			if _, ok := styles.fillIndex[key]; !ok {
+				styles.fillIndex[key] = i
+			}
 		}
 	}
+	key := xFill.key()
+	index, ok := styles.fillIndex[key]
+	if ok {
+		return index
+	}
 	styles.Fills.Fill = append(styles.Fills.Fill, xFill)
 	index = styles.Fills.Count
 	styles.Fills.Count += 1
+	styles.fillIndex[key] = index
 	return
 }
 
 func (styles *xlsxStyleSheet) addBorder(xBorder xlsxBorder) (index int) {
-	var border xlsxBorder
-	for index, border = range styles.Borders.Border {
-		if border.Equals(xBorder) {
-			return index
+	if styles.borderIndex == nil {
+		styles.borderIndex = make(map[xlsxBorderKey]int, len(styles.Borders.Border))
+		for i, border := range styles.Borders.Border {
+			key := border.key()
+			if _, ok := styles.borderIndex[key]; !ok {
+				styles.borderIndex[key] = i
+			}
 		}
 	}
+	key := xBorder.key()
+	index, ok := styles.borderIndex[key]
+	if ok {
+		return index
+	}
 	styles.Borders.Border = append(styles.Borders.Border, xBorder)
 	index = styles.Borders.Count
 	styles.Borders.Count += 1
+	styles.borderIndex[key] = index
 	return
 }
 
 func (styles *xlsxStyleSheet) addCellStyleXf(xCellStyleXf xlsxXf) (index int) {
-	var cellStyleXf xlsxXf
-	for index, cellStyleXf = range styles.CellStyleXfs.Xf {
-		if cellStyleXf.Equals(xCellStyleXf) {
-			return index
-		}
+	if styles.cellStyleXfIndex == nil {
+		styles.cellStyleXfIndex = makeXfIndex(styles.CellStyleXfs.Xf)
+	}
+	index, ok := styles.cellStyleXfIndex[xCellStyleXf]
+	if ok {
+		return index
 	}
 	styles.CellStyleXfs.Xf = append(styles.CellStyleXfs.Xf, xCellStyleXf)
 	index = styles.CellStyleXfs.Count
 	styles.CellStyleXfs.Count += 1
+	styles.cellStyleXfIndex[xCellStyleXf] = index
 	return
 }
 
 func (styles *xlsxStyleSheet) addCellXf(xCellXf xlsxXf) (index int) {
-	var cellXf xlsxXf
-	for index, cellXf = range styles.CellXfs.Xf {
-		if cellXf.Equals(xCellXf) {
-			return index
-		}
+	if styles.cellXfIndex == nil {
+		styles.cellXfIndex = makeXfIndex(styles.CellXfs.Xf)
+	}
+	index, ok := styles.cellXfIndex[xCellXf]
+	if ok {
+		return index
 	}
-
 	styles.CellXfs.Xf = append(styles.CellXfs.Xf, xCellXf)
 	index = styles.CellXfs.Count
 	styles.CellXfs.Count += 1
+	styles.cellXfIndex[xCellXf] = index
 	return
 }
 
+// makeXfIndex builds a map from each xlsxXf to the index of its first
+// occurrence in xfs.  xlsxXf is comparable, and == agrees with
+// xlsxXf.Equals, so it can be used as its own key.
+func makeXfIndex(xfs []xlsxXf) map[xlsxXf]int {
+	index := make(map[xlsxXf]int, len(xfs))
+	for i, xf := range xfs {
+		if _, ok := index[xf]; !ok {
+			index[xf] = i
+		}
+	}
+	return index
+}
+
 func (styles *xlsxStyleSheet) addNumFmt(xNumFmt xlsxNumFmt) (index int) {
 	numFmt, ok := styles.numFmtRefTable[xNumFmt.NumFmtId]
 	if !ok {
@@ -320,7 +533,9 @@ func (styles *xlsxStyleSheet) Marshal() (result string, err error) {
 	var outputBorderMap map[int]int = make(map[int]int)
 
 	result = xml.Header
-	result += `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`
+	result += `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+	result += marshalRawAttrs(styles.Attrs)
+	result += `>`
 
 	xNumFmts, err = styles.NumFmts.Marshal()
 	if err != nil {
@@ -358,6 +573,12 @@ func (styles *xlsxStyleSheet) Marshal() (result string, err error) {
 	}
 	result += xcellXfs
 
+	for _, raw := range []*xlsxRawXML{styles.CellStyles, styles.Dxfs, styles.TableStyles, styles.Colors, styles.ExtLst} {
+		if raw != nil {
+			result += raw.Marshal()
+		}
+	}
+
 	result += `</styleSheet>`
 	return
 }
@@ -397,7 +618,7 @@ type xlsxNumFmt struct {
 }
 
 func (numFmt *xlsxNumFmt) Marshal() (result string, err error) {
-	return fmt.Sprintf(`<numFmt numFmtId="%d" formatCode="%s"/>`, numFmt.NumFmtId, numFmt.FormatCode), nil
+	return fmt.Sprintf(`<numFmt numFmtId="%d" formatCode="%s"/>`, numFmt.NumFmtId, escapeAttr(numFmt.FormatCode)), nil
 }
 
 // xlsxFonts directly maps the fonts element in the namespace
@@ -440,18 +661,58 @@ func (fonts *xlsxFonts) Marshal(outputFontMap map[int]int) (result string, err e
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxFont struct {
-	Sz      xlsxVal   `xml:"sz,omitempty"`
-	Name    xlsxVal   `xml:"name,omitempty"`
-	Family  xlsxVal   `xml:"family,omitempty"`
-	Charset xlsxVal   `xml:"charset,omitempty"`
-	Color   xlsxColor `xml:"color,omitempty"`
-	B       *struct{} `xml:"b,omitempty"`
-	I       *struct{} `xml:"i,omitempty"`
-	U       *struct{} `xml:"u,omitempty"`
+	Sz        xlsxVal   `xml:"sz,omitempty"`
+	Name      xlsxVal   `xml:"name,omitempty"`
+	Family    xlsxVal   `xml:"family,omitempty"`
+	Charset   xlsxVal   `xml:"charset,omitempty"`
+	Color     xlsxColor `xml:"color,omitempty"`
+	B         *struct{} `xml:"b,omitempty"`
+	I         *struct{} `xml:"i,omitempty"`
+	U         *xlsxVal  `xml:"u,omitempty"`
+	Strike    *struct{} `xml:"strike,omitempty"`
+	VertAlign *xlsxVal  `xml:"vertAlign,omitempty"`
+	Scheme    *xlsxVal  `xml:"scheme,omitempty"`
 }
 
 func (font *xlsxFont) Equals(other xlsxFont) bool {
-	return font.Sz.Equals(other.Sz) && font.Name.Equals(other.Name) && font.Family.Equals(other.Family) && font.Charset.Equals(other.Charset) && font.Color.Equals(other.Color)
+	return font.key() == other.key()
+}
+
+// xlsxFontKey holds exactly the parts of an xlsxFont that are
+// compared by xlsxFont.Equals, in a form that can be used as a map
+// key.
+type xlsxFontKey struct {
+	Sz, Name, Family, Charset string
+	Color                     xlsxColorKey
+	B, I, Strike              bool
+	U, VertAlign, Scheme      string
+}
+
+func (font *xlsxFont) key() xlsxFontKey {
+	key := xlsxFontKey{
+		Sz:      font.Sz.Val,
+		Name:    font.Name.Val,
+		Family:  font.Family.Val,
+		Charset: font.Charset.Val,
+		Color:   font.Color.key(),
+		B:       font.B != nil,
+		I:       font.I != nil,
+		Strike:  font.Strike != nil,
+	}
+	if font.U != nil {
+		// An underline with no value is a single underline.
+		key.U = font.U.Val
+		if key.U == "" {
+			key.U = "single"
+		}
+	}
+	if font.VertAlign != nil {
+		key.VertAlign = font.VertAlign.Val
+	}
+	if font.Scheme != nil {
+		key.Scheme = font.Scheme.Val
+	}
+	return key
 }
 
 func (font *xlsxFont) Marshal() (result string, err error) {
@@ -468,8 +729,24 @@ func (font *xlsxFont) Marshal() (result string, err error) {
 	if font.Charset.Val != "" {
 		result += fmt.Sprintf(`<charset val="%s"/>`, font.Charset.Val)
 	}
-	if font.Color.RGB != "" {
-		result += fmt.Sprintf(`<color rgb="%s"/>`, font.Color.RGB)
+	result += font.Color.marshal("color")
+	if font.B != nil {
+		result += `<b/>`
+	}
+	if font.I != nil {
+		result += `<i/>`
+	}
+	if font.Strike != nil {
+		result += `<strike/>`
+	}
+	if font.U != nil {
+		result += font.U.marshal("u")
+	}
+	if font.VertAlign != nil {
+		result += font.VertAlign.marshal("vertAlign")
+	}
+	if font.Scheme != nil {
+		result += font.Scheme.marshal("scheme")
 	}
 	result += `</font>`
 	return
@@ -487,6 +764,13 @@ func (val *xlsxVal) Equals(other xlsxVal) bool {
 	return val.Val == other.Val
 }
 
+func (val *xlsxVal) marshal(elementName string) string {
+	if val.Val == "" {
+		return fmt.Sprintf(`<%s/>`, elementName)
+	}
+	return fmt.Sprintf(`<%s val="%s"/>`, elementName, escapeAttr(val.Val))
+}
+
 // xlsxFills directly maps the fills element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
@@ -524,25 +808,50 @@ func (fills *xlsxFills) Marshal(outputFillMap map[int]int) (result string, err e
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxFill struct {
-	PatternFill xlsxPatternFill `xml:"patternFill,omitempty"`
+	PatternFill  xlsxPatternFill `xml:"patternFill,omitempty"`
+	GradientFill *xlsxRawXML     `xml:"gradientFill"`
 }
 
 func (fill *xlsxFill) Equals(other xlsxFill) bool {
-	return fill.PatternFill.Equals(other.PatternFill)
+	return fill.key() == other.key()
 }
 
-func (fill *xlsxFill) Marshal() (result string, err error) {
-	if fill.PatternFill.PatternType != "" {
-		var xpatternFill string
-		result = `<fill>`
+// xlsxFillKey holds exactly the parts of an xlsxFill that are
+// compared by xlsxFill.Equals, in a form that can be used as a map
+// key.
+type xlsxFillKey struct {
+	PatternType      string
+	FgColor, BgColor xlsxColorKey
+	GradientFill     string
+}
+
+func (fill *xlsxFill) key() xlsxFillKey {
+	key := xlsxFillKey{
+		PatternType: fill.PatternFill.PatternType,
+		FgColor:     fill.PatternFill.FgColor.key(),
+		BgColor:     fill.PatternFill.BgColor.key(),
+	}
+	if fill.GradientFill != nil {
+		key.GradientFill = fill.GradientFill.Marshal()
+	}
+	return key
+}
 
+// Marshal always produces a fill element, even an empty one, so that
+// fill indices remain stable when the stylesheet is written out.
+func (fill *xlsxFill) Marshal() (result string, err error) {
+	var xpatternFill string
+	result = `<fill>`
+	if fill.GradientFill != nil && fill.PatternFill.PatternType == "" {
+		result += fill.GradientFill.Marshal()
+	} else {
 		xpatternFill, err = fill.PatternFill.Marshal()
 		if err != nil {
 			return
 		}
 		result += xpatternFill
-		result += `</fill>`
 	}
+	result += `</fill>`
 	return
 }
 
@@ -561,19 +870,16 @@ func (patternFill *xlsxPatternFill) Equals(other xlsxPatternFill) bool {
 }
 
 func (patternFill *xlsxPatternFill) Marshal() (result string, err error) {
-	result = fmt.Sprintf(`<patternFill patternType="%s"`, patternFill.PatternType)
+	result = `<patternFill`
+	if patternFill.PatternType != "" {
+		result += fmt.Sprintf(` patternType="%s"`, patternFill.PatternType)
+	}
 	ending := `/>`
 	terminator := ""
-	subparts := ""
-	if patternFill.FgColor.RGB != "" {
-		ending = `>`
-		terminator = "</patternFill>"
-		subparts += fmt.Sprintf(`<fgColor rgb="%s"/>`, patternFill.FgColor.RGB)
-	}
-	if patternFill.BgColor.RGB != "" {
+	subparts := patternFill.FgColor.marshal("fgColor") + patternFill.BgColor.marshal("bgColor")
+	if subparts != "" {
 		ending = `>`
 		terminator = "</patternFill>"
-		subparts += fmt.Sprintf(`<bgColor rgb="%s"/>`, patternFill.BgColor.RGB)
 	}
 	result += ending
 	result += subparts
@@ -587,13 +893,60 @@ func (patternFill *xlsxPatternFill) Marshal() (result string, err error) {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxColor struct {
-	RGB   string  `xml:"rgb,attr,omitempty"`
-	Theme *int    `xml:"theme,attr,omitempty"`
-	Tint  float64 `xml:"tint,attr,omitempty"`
+	RGB     string  `xml:"rgb,attr,omitempty"`
+	Theme   *int    `xml:"theme,attr,omitempty"`
+	Tint    float64 `xml:"tint,attr,omitempty"`
+	Indexed *int    `xml:"indexed,attr,omitempty"`
+	Auto    bool    `xml:"auto,attr,omitempty"`
 }
 
 func (color *xlsxColor) Equals(other xlsxColor) bool {
-	return color.RGB == other.RGB
+	return color.key() == other.key()
+}
+
+// xlsxColorKey is the comparable form of an xlsxColor.  Theme and
+// Indexed are -1 when not set.
+type xlsxColorKey struct {
+	RGB            string
+	Theme, Indexed int
+	Tint           float64
+	Auto           bool
+}
+
+func (color *xlsxColor) key() xlsxColorKey {
+	key := xlsxColorKey{RGB: color.RGB, Theme: -1, Indexed: -1, Tint: color.Tint, Auto: color.Auto}
+	if color.Theme != nil {
+		key.Theme = *color.Theme
+	}
+	if color.Indexed != nil {
+		key.Indexed = *color.Indexed
+	}
+	return key
+}
+
+// marshal returns the color as an element with the given name, or an
+// empty string if no color is set.
+func (color *xlsxColor) marshal(elementName string) string {
+	attrs := ""
+	if color.Auto {
+		attrs += ` auto="1"`
+	}
+	if color.Indexed != nil {
+		attrs += fmt.Sprintf(` indexed="%d"`, *color.Indexed)
+	}
+	if color.RGB != "" {
+		attrs += fmt.Sprintf(` rgb="%s"`, color.RGB)
+	}
+	if color.Theme != nil {
+		attrs += fmt.Sprintf(` theme="%d"`, *color.Theme)
+	}
+	if color.Tint != 0 {
+		attrs += fmt.Sprintf(` tint="%s"`, strconv.FormatFloat(color.Tint, 'g', -1, 64))
+	}
+	if attrs == "" {
+		return ""
+	}
+	return fmt.Sprintf(`<%s%s/>`, elementName, attrs)
 }
 
 // xlsxBorders directly maps the borders element in the namespace
@@ -615,11 +968,9 @@ func (borders *xlsxBorders) Marshal(outputBorderMap map[int]int) (result string,
 		if err != nil {
 			return
 		}
-		if xborder != "" {
-			outputBorderMap[i] = emittedCount
-			emittedCount += 1
-			subparts += xborder
-		}
+		outputBorderMap[i] = emittedCount
+		emittedCount += 1
+		subparts += xborder
 	}
 	if emittedCount > 0 {
 		result += fmt.Sprintf(`<borders count="%d">`, emittedCount)
@@ -634,40 +985,57 @@ func (borders *xlsxBorders) Marshal(outputBorderMap map[int]int) (result string,
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxBorder struct {
-	Left   xlsxLine `xml:"left,omitempty"`
-	Right  xlsxLine `xml:"right,omitempty"`
-	Top    xlsxLine `xml:"top,omitempty"`
-	Bottom xlsxLine `xml:"bottom,omitempty"`
+	DiagonalUp   bool     `xml:"diagonalUp,attr,omitempty"`
+	DiagonalDown bool     `xml:"diagonalDown,attr,omitempty"`
+	Left         xlsxLine `xml:"left,omitempty"`
+	Right        xlsxLine `xml:"right,omitempty"`
+	Top          xlsxLine `xml:"top,omitempty"`
+	Bottom       xlsxLine `xml:"bottom,omitempty"`
+	Diagonal     xlsxLine `xml:"diagonal,omitempty"`
 }
 
 func (border *xlsxBorder) Equals(other xlsxBorder) bool {
-	return border.Left.Equals(other.Left) && border.Right.Equals(other.Right) && border.Top.Equals(other.Top) && border.Bottom.Equals(other.Bottom)
+	return border.key() == other.key()
 }
 
-func (border *xlsxBorder) Marshal() (result string, err error) {
-	emit := false
-	subparts := ""
-	if border.Left.Style != "" {
-		emit = true
-		subparts += fmt.Sprintf(`<left style="%s"/>`, border.Left.Style)
-	}
-	if border.Right.Style != "" {
-		emit = true
-		subparts += fmt.Sprintf(`<right style="%s"/>`, border.Right.Style)
-	}
-	if border.Top.Style != "" {
-		emit = true
-		subparts += fmt.Sprintf(`<top style="%s"/>`, border.Top.Style)
+// xlsxBorderKey holds exactly the parts of an xlsxBorder that are
+// compared by xlsxBorder.Equals, in a form that can be used as a map
+// key.
+type xlsxBorderKey struct {
+	DiagonalUp, DiagonalDown           bool
+	Left, Right, Top, Bottom, Diagonal xlsxLineKey
+}
+
+func (border *xlsxBorder) key() xlsxBorderKey {
+	return xlsxBorderKey{
+		DiagonalUp:   border.DiagonalUp,
+		DiagonalDown: border.DiagonalDown,
+		Left:         border.Left.key(),
+		Right:        border.Right.key(),
+		Top:          border.Top.key(),
+		Bottom:       border.Bottom.key(),
+		Diagonal:     border.Diagonal.key(),
 	}
-	if border.Bottom.Style != "" {
-		emit = true
-		subparts += fmt.Sprintf(`<bottom style="%s"/>`, border.Bottom.Style)
+}
+
+// Marshal always produces a border element, even an empty one, so
+// that border indices remain stable when the stylesheet is written
+// out.
+func (border *xlsxBorder) Marshal() (result string, err error) {
+	result = `<border`
+	if border.DiagonalUp {
+		result += ` diagonalUp="1"`
 	}
-	if emit {
-		result += `<border>`
-		result += subparts
-		result += `</border>`
+	if border.DiagonalDown {
+		result += ` diagonalDown="1"`
 	}
+	result += `>`
+	result += border.Left.marshal("left")
+	result += border.Right.marshal("right")
+	result += border.Top.marshal("top")
+	result += border.Bottom.marshal("bottom")
+	result += border.Diagonal.marshal("diagonal")
+	result += `</border>`
 	return
 }
 
@@ -676,11 +1044,38 @@ func (border *xlsxBorder) Marshal() (result string, err error) {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxLine struct {
-	Style string `xml:"style,attr,omitempty"`
+	Style string    `xml:"style,attr,omitempty"`
+	Color xlsxColor `xml:"color,omitempty"`
 }
 
 func (line *xlsxLine) Equals(other xlsxLine) bool {
-	return line.Style == other.Style
+	return line.key() == other.key()
+}
+
+type xlsxLineKey struct {
+	Style string
+	Color xlsxColorKey
+}
+
+func (line *xlsxLine) key() xlsxLineKey {
+	return xlsxLineKey{line.Style, line.Color.key()}
+}
+
+// marshal returns the line as an element with the given name, or an
+// empty string if it has neither a style nor a color.
+func (line *xlsxLine) marshal(elementName string) string {
+	color := line.Color.marshal("color")
+	if line.Style == "" && color == "" {
+		return ""
+	}
+	result := `<` + elementName
+	if line.Style != "" {
+		result += fmt.Sprintf(` style="%s"`, line.Style)
+	}
+	if color == "" {
+		return result + `/>`
+	}
+	return result + `>` + color + `</` + elementName + `>`
 }
 
 // xlsxCellStyleXfs directly maps the cellStyleXfs element in the
@@ -738,16 +1133,18 @@ func (cellXfs *xlsxCellXfs) Marshal(outputBorderMap, outputFillMap, outputFontMa
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxXf struct {
-	ApplyAlignment  bool          `xml:"applyAlignment,attr"`
-	ApplyBorder     bool          `xml:"applyBorder,attr"`
-	ApplyFont       bool          `xml:"applyFont,attr"`
-	ApplyFill       bool          `xml:"applyFill,attr"`
-	ApplyProtection bool          `xml:"applyProtection,attr"`
-	BorderId        int           `xml:"borderId,attr"`
-	FillId          int           `xml:"fillId,attr"`
-	FontId          int           `xml:"fontId,attr"`
-	NumFmtId        int           `xml:"numFmtId,attr"`
-	Alignment       xlsxAlignment `xml:"alignment"`
+	ApplyAlignment  bool           `xml:"applyAlignment,attr"`
+	ApplyBorder     bool           `xml:"applyBorder,attr"`
+	ApplyFont       bool           `xml:"applyFont,attr"`
+	ApplyFill       bool           `xml:"applyFill,attr"`
+	ApplyProtection bool           `xml:"applyProtection,attr"`
+	BorderId        int            `xml:"borderId,attr"`
+	FillId          int            `xml:"fillId,attr"`
+	FontId          int            `xml:"fontId,attr"`
+	NumFmtId        int            `xml:"numFmtId,attr"`
+	XfId            int            `xml:"xfId,attr,omitempty"`
+	Alignment       xlsxAlignment  `xml:"alignment"`
+	Protection      xlsxProtection `xml:"protection"`
 }
 
 func (xf *xlsxXf) Equals(other xlsxXf) bool {
@@ -760,17 +1157,24 @@ func (xf *xlsxXf) Equals(other xlsxXf) bool {
 		xf.FillId == other.FillId &&
 		xf.FontId == other.FontId &&
 		xf.NumFmtId == other.NumFmtId &&
-		xf.Alignment.Equals(other.Alignment)
+		xf.XfId == other.XfId &&
+		xf.Alignment.Equals(other.Alignment) &&
+		xf.Protection == other.Protection
 }
 
 func (xf *xlsxXf) Marshal(outputBorderMap, outputFillMap, outputFontMap map[int]int) (result string, err error) {
 	var xAlignment string
-	result = fmt.Sprintf(`<xf applyAlignment="%b" applyBorder="%b" applyFont="%b" applyFill="%b" applyProtection="%b" borderId="%d" fillId="%d" fontId="%d" numFmtId="%d">`, bool2Int(xf.ApplyAlignment), bool2Int(xf.ApplyBorder), bool2Int(xf.ApplyFont), bool2Int(xf.ApplyFill), bool2Int(xf.ApplyProtection), outputBorderMap[xf.BorderId], outputFillMap[xf.FillId], outputFontMap[xf.FontId], xf.NumFmtId)
+	result = fmt.Sprintf(`<xf applyAlignment="%b" applyBorder="%b" applyFont="%b" applyFill="%b" applyProtection="%b" borderId="%d" fillId="%d" fontId="%d" numFmtId="%d"`, bool2Int(xf.ApplyAlignment), bool2Int(xf.ApplyBorder), bool2Int(xf.ApplyFont), bool2Int(xf.ApplyFill), bool2Int(xf.ApplyProtection), outputBorderMap[xf.BorderId], outputFillMap[xf.FillId], outputFontMap[xf.FontId], xf.NumFmtId)
+	if xf.XfId != 0 {
+		result += fmt.Sprintf(` xfId="%d"`, xf.XfId)
+	}
+	result += `>`
 	xAlignment, err = xf.Alignment.Marshal()
 	if err != nil {
 		return
 	}
 	result += xAlignment
+	result += xf.Protection.Marshal()
 	result += `</xf>`
 	return
 }
@@ -798,9 +1202,139 @@ func (alignment *xlsxAlignment) Marshal() (result string, err error) {
 	return
 }
 
+// xlsxProtection directly maps the protection element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+//
+// The attributes are kept as strings so that we can tell an absent
+// attribute, which takes its default, from a false one.
+type xlsxProtection struct {
+	Locked string `xml:"locked,attr"`
+	Hidden string `xml:"hidden,attr"`
+}
+
+// isLocked reports whether cells are locked, which they are unless
+// they say otherwise.
+func (protection *xlsxProtection) isLocked() bool {
+	return !(protection.Locked == "0" || protection.Locked == "false")
+}
+
+// isHidden reports whether the formulas of cells are hidden.
+func (protection *xlsxProtection) isHidden() bool {
+	return protection.Hidden == "1" || protection.Hidden == "true"
+}
+
+// Marshal returns the protection element, or nothing if neither of
+// its attributes are set.
+func (protection *xlsxProtection) Marshal() string {
+	if protection.Locked == "" && protection.Hidden == "" {
+		return ""
+	}
+	result := `<protection`
+	if protection.Locked != "" {
+		result += fmt.Sprintf(` locked="%b"`, bool2Int(protection.isLocked()))
+	}
+	if protection.Hidden != "" {
+		result += fmt.Sprintf(` hidden="%b"`, bool2Int(protection.isHidden()))
+	}
+	return result + `/>`
+}
+
 func bool2Int(b bool) int {
 	if b {
 		return 1
 	}
 	return 0
 }
+
+// xlsxRawXML captures an element, that we don't otherwise model, in
+// its entirety so that it can be written back out unchanged.
+type xlsxRawXML struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
+}
+
+func (raw *xlsxRawXML) Marshal() string {
+	name := raw.XMLName.Local
+	return `<` + name + marshalRawAttrs(raw.Attrs) + `>` + raw.InnerXML + `</` + name + `>`
+}
+
+// MarshalXML writes the captured element back out when it sits in a
+// structure that is marshalled with encoding/xml.  Its attributes
+// should already have been through flattenRawAttrs.
+func (raw *xlsxRawXML) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	out := struct {
+		XMLName  xml.Name
+		Attrs    []xml.Attr `xml:",any,attr"`
+		InnerXML string     `xml:",innerxml"`
+	}{
+		XMLName:  xml.Name{Local: raw.XMLName.Local},
+		Attrs:    flattenRawAttrs(raw.Attrs, nil),
+		InnerXML: raw.InnerXML,
+	}
+	return e.Encode(out)
+}
+
+// marshalRawAttrs formats attributes captured with ",any,attr" so
+// that they can be written back out.
+func marshalRawAttrs(attrs []xml.Attr) string {
+	result := ""
+	for _, attr := range flattenRawAttrs(attrs, nil) {
+		result += fmt.Sprintf(` %s="%s"`, attr.Name.Local, escapeAttr(attr.Value))
+	}
+	return result
+}
+
+// rawPrefixes returns the namespace prefixes declared amongst attrs,
+// keyed by namespace.
+func rawPrefixes(attrs []xml.Attr) map[string]string {
+	prefixes := make(map[string]string)
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" {
+			prefixes[attr.Value] = attr.Name.Local
+		}
+	}
+	return prefixes
+}
+
+// flattenRawAttrs folds the namespace of attributes captured with
+// ",any,attr" into their local name, so that they are written out
+// verbatim.  Namespaced attributes are given the prefix declared for
+// their namespace amongst attrs, or failing that in inherited, and
+// are dropped if there isn't one.  The default namespace declaration
+// is always dropped, as we write our own.
+func flattenRawAttrs(attrs []xml.Attr, inherited map[string]string) []xml.Attr {
+	prefixes := rawPrefixes(attrs)
+	result := make([]xml.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		name := attr.Name.Local
+		switch attr.Name.Space {
+		case "":
+			if name == "xmlns" {
+				continue
+			}
+		case "xmlns":
+			name = "xmlns:" + name
+		default:
+			prefix, ok := prefixes[attr.Name.Space]
+			if !ok {
+				prefix, ok = inherited[attr.Name.Space]
+			}
+			if !ok {
+				continue
+			}
+			name = prefix + ":" + name
+		}
+		result = append(result, xml.Attr{Name: xml.Name{Local: name}, Value: attr.Value})
+	}
+	return result
+}
+
+// escapeAttr escapes a string for use as an XML attribute value.
+func escapeAttr(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -1,6 +1,9 @@
 package xlsx
 
 import (
+	"encoding/xml"
+	"strings"
+
 	. "gopkg.in/check.v1"
 )
 
@@ -283,3 +286,102 @@ func (x *XMLStyleSuite) TestXfEquals(c *C) {
 	// for sanity
 	c.Assert(xfA.Equals(xfB), Equals, true)
 }
+
+// Adding an equal component twice yields the same index, even when
+// the stylesheet already contained entries before the index was
+// built.
+func (x *XMLStyleSuite) TestAddComponentsInterned(c *C) {
+	styles := newXlsxStyleSheet(nil)
+	styles.Fonts.Font = []xlsxFont{
+		xlsxFont{Name: xlsxVal{Val: "Calibri"}, Sz: xlsxVal{Val: "11"}}}
+	styles.Fonts.Count = 1
+
+	c.Assert(styles.addFont(xlsxFont{Name: xlsxVal{Val: "Calibri"}, Sz: xlsxVal{Val: "11"}}), Equals, 0)
+	c.Assert(styles.addFont(xlsxFont{Name: xlsxVal{Val: "Arial"}, Sz: xlsxVal{Val: "11"}}), Equals, 1)
+	c.Assert(styles.addFont(xlsxFont{Name: xlsxVal{Val: "Arial"}, Sz: xlsxVal{Val: "11"}}), Equals, 1)
+	c.Assert(styles.Fonts.Count, Equals, 2)
+
+	fill := xlsxFill{PatternFill: xlsxPatternFill{PatternType: "solid"}}
+	c.Assert(styles.addFill(fill), Equals, 0)
+	c.Assert(styles.addFill(fill), Equals, 0)
+	c.Assert(styles.Fills.Count, Equals, 1)
+
+	border := xlsxBorder{Left: xlsxLine{Style: "thin"}}
+	c.Assert(styles.addBorder(border), Equals, 0)
+	c.Assert(styles.addBorder(border), Equals, 0)
+	c.Assert(styles.Borders.Count, Equals, 1)
+
+	xf := xlsxXf{FontId: 1}
+	c.Assert(styles.addCellXf(xf), Equals, 0)
+	c.Assert(styles.addCellXf(xlsxXf{FontId: 0}), Equals, 1)
+	c.Assert(styles.addCellXf(xf), Equals, 0)
+	c.Assert(styles.CellXfs.Count, Equals, 2)
+	c.Assert(styles.addCellStyleXf(xf), Equals, 0)
+	c.Assert(styles.addCellStyleXf(xf), Equals, 0)
+	c.Assert(styles.CellStyleXfs.Count, Equals, 1)
+
+	styles.reset()
+	c.Assert(styles.addFont(xlsxFont{Name: xlsxVal{Val: "Arial"}}), Equals, 0)
+}
+
+// Distinct Style values that are equal share a single CellXf, while
+// a Style is only converted once however many times it is added.
+func (x *XMLStyleSuite) TestAddStyle(c *C) {
+	styles := newXlsxStyleSheet(nil)
+	styleA := NewStyle()
+	styleB := NewStyle()
+	styleC := NewStyle()
+	styleC.Font = *NewFont(10, "Arial")
+
+	c.Assert(styles.addStyle(styleA, ""), Equals, 0)
+	c.Assert(styles.addStyle(styleB, ""), Equals, 0)
+	c.Assert(styles.addStyle(styleC, ""), Equals, 1)
+	c.Assert(styles.addStyle(styleA, ""), Equals, 0)
+	c.Assert(styles.Fonts.Count, Equals, 2)
+	c.Assert(styles.CellXfs.Count, Equals, 2)
+	c.Assert(styles.styleXfCache, HasLen, 3)
+}
+
+// Elements we don't model, and the namespace declarations they rely
+// upon, survive being read and written back out.
+func (x *XMLStyleSuite) TestUnmodelledElementsRoundTrip(c *C) {
+	input := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006" mc:Ignorable="xr" xmlns:xr="http://schemas.microsoft.com/office/spreadsheetml/2014/revision"><fonts count="1"><font><b/><u val="double"/><sz val="11"/><color theme="1"/><name val="Calibri"/><scheme val="minor"/></font></fonts><cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0" xr:uid="{1}"/></cellStyles><dxfs count="0"/></styleSheet>`
+	styles := newXlsxStyleSheet(nil)
+	err := xml.NewDecoder(strings.NewReader(input)).Decode(styles)
+	c.Assert(err, IsNil)
+	result, err := styles.Marshal()
+	c.Assert(err, IsNil)
+	c.Assert(result, Equals, `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006" mc:Ignorable="xr" xmlns:xr="http://schemas.microsoft.com/office/spreadsheetml/2014/revision"><fonts count="1"><font><sz val="11"/><name val="Calibri"/><color theme="1"/><b/><u val="double"/><scheme val="minor"/></font></fonts><cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0" xr:uid="{1}"/></cellStyles><dxfs count="0"></dxfs></styleSheet>`)
+}
+
+// Number formats are built in, already in the stylesheet, or added to
+// it with an id of their own.
+func (x *XMLStyleSuite) TestNumFmtId(c *C) {
+	styles := newXlsxStyleSheet(nil)
+	c.Assert(styles.numFmtId(""), Equals, 0)
+	c.Assert(styles.numFmtId("General"), Equals, 0)
+	c.Assert(styles.numFmtId("0.00e+00"), Equals, 11)
+	c.Assert(styles.numFmtId("yyyy-mm-dd"), Equals, 164)
+	c.Assert(styles.numFmtId("YYYY-MM-DD"), Equals, 164)
+	c.Assert(styles.numFmtId("0.000"), Equals, 165)
+	c.Assert(styles.NumFmts.Count, Equals, 2)
+
+	// Literal text in quotes or after a backslash is compared
+	// exactly.
+	c.Assert(styles.numFmtId(`0 "Kg"`), Equals, 166)
+	c.Assert(styles.numFmtId(`0 "kg"`), Equals, 167)
+	c.Assert(styles.numFmtId(`0 "KG"`), Equals, 168)
+	c.Assert(styles.numFmtId(`0 "Kg"`), Equals, 166)
+	c.Assert(styles.numFmtId(`[RED]0 \M`), Equals, 169)
+	c.Assert(styles.numFmtId(`[red]0 \M`), Equals, 169)
+	c.Assert(styles.numFmtId(`[red]0 \m`), Equals, 170)
+	c.Assert(styles.NumFmts.Count, Equals, 7)
+	c.Assert(foldNumFmt(`[RED]#,##0.0 "Kg" \M;"Neg" YYYY`), Equals, `[red]#,##0.0 "Kg" \M;"Neg" yyyy`)
+
+	style := NewStyle()
+	c.Assert(styles.addStyle(style, "0.000"), Equals, 0)
+	c.Assert(styles.addStyle(style, ""), Equals, 1)
+	c.Assert(styles.CellXfs.Xf[0].NumFmtId, Equals, 165)
+}
@@ -0,0 +1,59 @@
+package xlsx
+
+import (
+	"encoding/xml"
+)
+
+// xlsxTable directly maps the table element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxTable struct {
+	XMLName        xml.Name            `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main table"`
+	Id             int                 `xml:"id,attr"`
+	Name           string              `xml:"name,attr"`
+	DisplayName    string              `xml:"displayName,attr"`
+	Ref            string              `xml:"ref,attr"`
+	TotalsRowShown int                 `xml:"totalsRowShown,attr"`
+	AutoFilter     *xlsxTableFilter    `xml:"autoFilter"`
+	TableColumns   xlsxTableColumns    `xml:"tableColumns"`
+	TableStyleInfo *xlsxTableStyleInfo `xml:"tableStyleInfo"`
+}
+
+// xlsxTableFilter directly maps the autoFilter element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxTableFilter struct {
+	Ref string `xml:"ref,attr"`
+}
+
+// xlsxTableColumns directly maps the tableColumns element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxTableColumns struct {
+	Count       int               `xml:"count,attr"`
+	TableColumn []xlsxTableColumn `xml:"tableColumn"`
+}
+
+// xlsxTableColumn directly maps the tableColumn element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxTableColumn struct {
+	Id   int    `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+}
+
+// xlsxTableStyleInfo directly maps the tableStyleInfo element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxTableStyleInfo struct {
+	Name              string `xml:"name,attr"`
+	ShowFirstColumn   bool   `xml:"showFirstColumn,attr"`
+	ShowLastColumn    bool   `xml:"showLastColumn,attr"`
+	ShowRowStripes    bool   `xml:"showRowStripes,attr"`
+	ShowColumnStripes bool   `xml:"showColumnStripes,attr"`
+}
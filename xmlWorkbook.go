@@ -24,24 +24,87 @@ type xlsxWorkbookRels struct {
 
 // xmlxWorkbookRelation maps sheet id and xl/worksheets/sheet%d.xml
 type xlsxWorkbookRelation struct {
-	Id     string `xml:",attr"`
-	Target string `xml:",attr"`
-	Type   string `xml:",attr"`
+	Id         string `xml:",attr"`
+	Target     string `xml:",attr"`
+	Type       string `xml:",attr"`
+	TargetMode string `xml:",attr,omitempty"`
 }
 
 // xlsxWorkbook directly maps the workbook element from the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
 // as I need.
+//
+// As with xlsxWorksheet, elements that we don't model are captured as
+// xlsxRawXML so that they survive being read and written back out.
 type xlsxWorkbook struct {
-	XMLName            xml.Name               `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main workbook"`
-	FileVersion        xlsxFileVersion        `xml:"fileVersion"`
-	WorkbookPr         xlsxWorkbookPr         `xml:"workbookPr"`
-	WorkbookProtection xlsxWorkbookProtection `xml:"workbookProtection"`
-	BookViews          xlsxBookViews          `xml:"bookViews"`
-	Sheets             xlsxSheets             `xml:"sheets"`
-	DefinedNames       xlsxDefinedNames       `xml:"definedNames"`
-	CalcPr             xlsxCalcPr             `xml:"calcPr"`
+	XMLName             xml.Name               `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main workbook"`
+	FileVersion         xlsxFileVersion        `xml:"fileVersion"`
+	FileSharing         *xlsxRawXML            `xml:"fileSharing"`
+	WorkbookPr          xlsxWorkbookPr         `xml:"workbookPr"`
+	WorkbookProtection  xlsxWorkbookProtection `xml:"workbookProtection"`
+	BookViews           xlsxBookViews          `xml:"bookViews"`
+	Sheets              xlsxSheets             `xml:"sheets"`
+	FunctionGroups      *xlsxRawXML            `xml:"functionGroups"`
+	ExternalReferences  *xlsxRawXML            `xml:"externalReferences"`
+	DefinedNames        xlsxDefinedNames       `xml:"definedNames"`
+	CalcPr              xlsxCalcPr             `xml:"calcPr"`
+	OleSize             *xlsxRawXML            `xml:"oleSize"`
+	CustomWorkbookViews *xlsxRawXML            `xml:"customWorkbookViews"`
+	PivotCaches         *xlsxRawXML            `xml:"pivotCaches"`
+	SmartTagPr          *xlsxRawXML            `xml:"smartTagPr"`
+	SmartTagTypes       *xlsxRawXML            `xml:"smartTagTypes"`
+	WebPublishing       *xlsxRawXML            `xml:"webPublishing"`
+	FileRecoveryPr      *xlsxRawXML            `xml:"fileRecoveryPr"`
+	WebPublishObjects   *xlsxRawXML            `xml:"webPublishObjects"`
+	ExtLst              *xlsxRawXML            `xml:"extLst"`
+	Attrs               []xml.Attr             `xml:",any,attr"`
+}
+
+// rawElements returns pointers to every unmodelled element slot of
+// the workbook, in schema order.
+func (workbook *xlsxWorkbook) rawElements() []**xlsxRawXML {
+	return []**xlsxRawXML{
+		&workbook.FileSharing,
+		&workbook.FunctionGroups,
+		&workbook.ExternalReferences,
+		&workbook.OleSize,
+		&workbook.CustomWorkbookViews,
+		&workbook.PivotCaches,
+		&workbook.SmartTagPr,
+		&workbook.SmartTagTypes,
+		&workbook.WebPublishing,
+		&workbook.FileRecoveryPr,
+		&workbook.WebPublishObjects,
+		&workbook.ExtLst,
+	}
+}
+
+// flattenRawAttrs prepares the attributes of the workbook element and
+// of its unmodelled elements, as decoded, to be written back out.
+func (workbook *xlsxWorkbook) flattenRawAttrs() {
+	prefixes := rawPrefixes(workbook.Attrs)
+	workbook.Attrs = flattenRawAttrs(workbook.Attrs, nil)
+	for _, raw := range workbook.rawElements() {
+		if *raw != nil {
+			(*raw).Attrs = flattenRawAttrs((*raw).Attrs, prefixes)
+		}
+	}
+	for i := range workbook.DefinedNames.DefinedName {
+		if name := &workbook.DefinedNames.DefinedName[i]; len(name.Attrs) > 0 {
+			name.Attrs = flattenRawAttrs(name.Attrs, prefixes)
+		}
+	}
+}
+
+// copyUnmodelled copies the unmodelled content of another workbook
+// into this one.
+func (workbook *xlsxWorkbook) copyUnmodelled(other *xlsxWorkbook) {
+	workbook.Attrs = other.Attrs
+	to := workbook.rawElements()
+	for i, raw := range other.rawElements() {
+		*to[i] = *raw
+	}
 }
 
 // xlsxWorkbookProtection directly maps the workbookProtection element from the
@@ -49,7 +112,19 @@ type xlsxWorkbook struct {
 // - currently I have not checked it for completeness - it does as
 // much as I need.
 type xlsxWorkbookProtection struct {
-	// We don't need this, yet.
+	WorkbookPassword       string `xml:"workbookPassword,attr,omitempty"`
+	RevisionsPassword      string `xml:"revisionsPassword,attr,omitempty"`
+	LockStructure          bool   `xml:"lockStructure,attr,omitempty"`
+	LockWindows            bool   `xml:"lockWindows,attr,omitempty"`
+	LockRevision           bool   `xml:"lockRevision,attr,omitempty"`
+	RevisionsAlgorithmName string `xml:"revisionsAlgorithmName,attr,omitempty"`
+	RevisionsHashValue     string `xml:"revisionsHashValue,attr,omitempty"`
+	RevisionsSaltValue     string `xml:"revisionsSaltValue,attr,omitempty"`
+	RevisionsSpinCount     int    `xml:"revisionsSpinCount,attr,omitempty"`
+	WorkbookAlgorithmName  string `xml:"workbookAlgorithmName,attr,omitempty"`
+	WorkbookHashValue      string `xml:"workbookHashValue,attr,omitempty"`
+	WorkbookSaltValue      string `xml:"workbookSaltValue,attr,omitempty"`
+	WorkbookSpinCount      int    `xml:"workbookSpinCount,attr,omitempty"`
 }
 
 // xlsxFileVersion directly maps the fileVersion element from the
@@ -133,7 +208,12 @@ type xlsxDefinedNames struct {
 type xlsxDefinedName struct {
 	Data         string `xml:",chardata"`
 	Name         string `xml:"name,attr"`
-	LocalSheetID string `xml:"localSheetId,attr"`
+	Comment      string `xml:"comment,attr,omitempty"`
+	LocalSheetID string `xml:"localSheetId,attr,omitempty"`
+	Hidden       bool   `xml:"hidden,attr,omitempty"`
+	// Attrs holds the attributes that aren't modelled, such as
+	// function, vbProcedure and description.
+	Attrs []xml.Attr `xml:",any,attr"`
 }
 
 // xlsxCalcPr directly maps the calcPr element from the namespace
@@ -156,18 +236,10 @@ func getWorksheetFromSheet(sheet xlsxSheet, worksheets map[string]*zip.File, she
 	var decoder *xml.Decoder
 	var worksheet *xlsxWorksheet
 	var error error
-	var sheetName string
 	worksheet = new(xlsxWorksheet)
+	worksheet.setSchemaDefaults()
 
-	sheetName, ok := sheetXMLMap[sheet.Id]
-	if !ok {
-		if sheet.SheetId != "" {
-			sheetName = fmt.Sprintf("sheet%s", sheet.SheetId)
-		} else {
-			sheetName = fmt.Sprintf("sheet%s", sheet.Id)
-		}
-	}
-	f := worksheets[sheetName]
+	f := worksheets[worksheetNameFromSheet(sheet, sheetXMLMap)]
 	rc, error = f.Open()
 	if error != nil {
 		return nil, error
@@ -179,3 +251,18 @@ func getWorksheetFromSheet(sheet xlsxSheet, worksheets map[string]*zip.File, she
 	}
 	return worksheet, nil
 }
+
+// worksheetNameFromSheet returns the name, within xl/worksheets and
+// without its extension, of the sheetN.xml file refered to by an
+// xlsx.xlsxSheet struct.
+func worksheetNameFromSheet(sheet xlsxSheet, sheetXMLMap map[string]string) string {
+	sheetName, ok := sheetXMLMap[sheet.Id]
+	if !ok {
+		if sheet.SheetId != "" {
+			sheetName = fmt.Sprintf("sheet%s", sheet.SheetId)
+		} else {
+			sheetName = fmt.Sprintf("sheet%s", sheet.Id)
+		}
+	}
+	return sheetName
+}
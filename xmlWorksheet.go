@@ -8,18 +8,198 @@ import (
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
 // as I need.
+//
+// Elements that we don't model are captured as xlsxRawXML, in their
+// schema position, so that a worksheet that has been read can be
+// written back out without losing them.
 type xlsxWorksheet struct {
-	XMLName       xml.Name          `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main worksheet"`
-	SheetPr       xlsxSheetPr       `xml:"sheetPr"`
-	Dimension     xlsxDimension     `xml:"dimension"`
-	SheetViews    xlsxSheetViews    `xml:"sheetViews"`
-	SheetFormatPr xlsxSheetFormatPr `xml:"sheetFormatPr"`
-	Cols          xlsxCols          `xml:"cols"`
-	SheetData     xlsxSheetData     `xml:"sheetData"`
-	PrintOptions  xlsxPrintOptions  `xml:"printOptions"`
-	PageMargins   xlsxPageMargins   `xml:"pageMargins"`
-	PageSetUp     xlsxPageSetUp     `xml:"pageSetup"`
-	HeaderFooter  xlsxHeaderFooter  `xml:"headerFooter"`
+	XMLName               xml.Name             `xml:"http://schemas.openxmlformats.org/spreadsheetml/2006/main worksheet"`
+	SheetPr               xlsxSheetPr          `xml:"sheetPr"`
+	Dimension             xlsxDimension        `xml:"dimension"`
+	SheetViews            xlsxSheetViews       `xml:"sheetViews"`
+	SheetFormatPr         xlsxSheetFormatPr    `xml:"sheetFormatPr"`
+	Cols                  xlsxCols             `xml:"cols"`
+	SheetData             xlsxSheetData        `xml:"sheetData"`
+	SheetCalcPr           *xlsxRawXML          `xml:"sheetCalcPr"`
+	SheetProtection       *xlsxSheetProtection `xml:"sheetProtection"`
+	ProtectedRanges       *xlsxRawXML          `xml:"protectedRanges"`
+	Scenarios             *xlsxRawXML          `xml:"scenarios"`
+	AutoFilter            *xlsxRawXML          `xml:"autoFilter"`
+	SortState             *xlsxRawXML          `xml:"sortState"`
+	DataConsolidate       *xlsxRawXML          `xml:"dataConsolidate"`
+	CustomSheetViews      *xlsxRawXML          `xml:"customSheetViews"`
+	MergeCells            *xlsxRawXML          `xml:"mergeCells"`
+	PhoneticPr            *xlsxRawXML          `xml:"phoneticPr"`
+	ConditionalFormatting []*xlsxRawXML        `xml:"conditionalFormatting"`
+	DataValidations       *xlsxRawXML          `xml:"dataValidations"`
+	Hyperlinks            *xlsxRawXML          `xml:"hyperlinks"`
+	PrintOptions          xlsxPrintOptions     `xml:"printOptions"`
+	PageMargins           xlsxPageMargins      `xml:"pageMargins"`
+	PageSetUp             xlsxPageSetUp        `xml:"pageSetup"`
+	HeaderFooter          xlsxHeaderFooter     `xml:"headerFooter"`
+	RowBreaks             *xlsxPageBreaks      `xml:"rowBreaks"`
+	ColBreaks             *xlsxPageBreaks      `xml:"colBreaks"`
+	CustomProperties      *xlsxRawXML          `xml:"customProperties"`
+	CellWatches           *xlsxRawXML          `xml:"cellWatches"`
+	IgnoredErrors         *xlsxRawXML          `xml:"ignoredErrors"`
+	SmartTags             *xlsxRawXML          `xml:"smartTags"`
+	Drawing               *xlsxRawXML          `xml:"drawing"`
+	LegacyDrawing         *xlsxRawXML          `xml:"legacyDrawing"`
+	LegacyDrawingHF       *xlsxRawXML          `xml:"legacyDrawingHF"`
+	DrawingHF             *xlsxRawXML          `xml:"drawingHF"`
+	Picture               *xlsxRawXML          `xml:"picture"`
+	OleObjects            *xlsxRawXML          `xml:"oleObjects"`
+	Controls              *xlsxRawXML          `xml:"controls"`
+	WebPublishItems       *xlsxRawXML          `xml:"webPublishItems"`
+	TableParts            *xlsxRawXML          `xml:"tableParts"`
+	ExtLst                *xlsxRawXML          `xml:"extLst"`
+	Attrs                 []xml.Attr           `xml:",any,attr"`
+}
+
+// rawElements returns pointers to every unmodelled element slot of
+// the worksheet, in schema order.
+func (worksheet *xlsxWorksheet) rawElements() []**xlsxRawXML {
+	elements := []**xlsxRawXML{
+		&worksheet.SheetCalcPr,
+		&worksheet.ProtectedRanges,
+		&worksheet.Scenarios,
+		&worksheet.AutoFilter,
+		&worksheet.SortState,
+		&worksheet.DataConsolidate,
+		&worksheet.CustomSheetViews,
+		&worksheet.MergeCells,
+		&worksheet.PhoneticPr,
+	}
+	for i := range worksheet.ConditionalFormatting {
+		elements = append(elements, &worksheet.ConditionalFormatting[i])
+	}
+	return append(elements,
+		&worksheet.DataValidations,
+		&worksheet.Hyperlinks,
+		&worksheet.CustomProperties,
+		&worksheet.CellWatches,
+		&worksheet.IgnoredErrors,
+		&worksheet.SmartTags,
+		&worksheet.Drawing,
+		&worksheet.LegacyDrawing,
+		&worksheet.LegacyDrawingHF,
+		&worksheet.DrawingHF,
+		&worksheet.Picture,
+		&worksheet.OleObjects,
+		&worksheet.Controls,
+		&worksheet.WebPublishItems,
+		&worksheet.TableParts,
+		&worksheet.ExtLst)
+}
+
+// flattenRawAttrs prepares the attributes of the worksheet element
+// and of its unmodelled elements, as decoded, to be written back out.
+// The unmodelled elements commonly rely on namespace prefixes (such
+// as r: for relationship ids) that are declared on the worksheet
+// element, so those declarations are kept too.
+func (worksheet *xlsxWorksheet) flattenRawAttrs() {
+	prefixes := rawPrefixes(worksheet.Attrs)
+	worksheet.Attrs = flattenRawAttrs(worksheet.Attrs, nil)
+	worksheet.SheetFormatPr.Attrs = flattenRawAttrs(worksheet.SheetFormatPr.Attrs, prefixes)
+	for _, raw := range worksheet.rawElements() {
+		if *raw != nil {
+			(*raw).Attrs = flattenRawAttrs((*raw).Attrs, prefixes)
+		}
+	}
+}
+
+// copyUnmodelled copies the unmodelled content of another worksheet
+// into this one.  The sheetFormatPr element, which sets the default
+// row height and column width, is copied too, if it had been read.
+func (worksheet *xlsxWorksheet) copyUnmodelled(other *xlsxWorksheet) {
+	worksheet.Attrs = other.Attrs
+	if other.SheetFormatPr.DefaultRowHeight != 0 {
+		worksheet.SheetFormatPr = other.SheetFormatPr
+	}
+	worksheet.ConditionalFormatting = other.ConditionalFormatting
+	to := worksheet.rawElements()
+	for i, raw := range other.rawElements() {
+		*to[i] = *raw
+	}
+}
+
+// xlsxSheetProtection directly maps the sheetProtection element in the
+// namespace http://schemas.openxmlformats.org/spreadsheetml/2006/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+//
+// Each of the flags, other than Sheet, is set when the action it
+// names is prevented.
+type xlsxSheetProtection struct {
+	Password            string `xml:"password,attr,omitempty"`
+	AlgorithmName       string `xml:"algorithmName,attr,omitempty"`
+	HashValue           string `xml:"hashValue,attr,omitempty"`
+	SaltValue           string `xml:"saltValue,attr,omitempty"`
+	SpinCount           int    `xml:"spinCount,attr,omitempty"`
+	Sheet               bool   `xml:"sheet,attr"`
+	Objects             bool   `xml:"objects,attr"`
+	Scenarios           bool   `xml:"scenarios,attr"`
+	FormatCells         bool   `xml:"formatCells,attr"`
+	FormatColumns       bool   `xml:"formatColumns,attr"`
+	FormatRows          bool   `xml:"formatRows,attr"`
+	InsertColumns       bool   `xml:"insertColumns,attr"`
+	InsertRows          bool   `xml:"insertRows,attr"`
+	InsertHyperlinks    bool   `xml:"insertHyperlinks,attr"`
+	DeleteColumns       bool   `xml:"deleteColumns,attr"`
+	DeleteRows          bool   `xml:"deleteRows,attr"`
+	SelectLockedCells   bool   `xml:"selectLockedCells,attr"`
+	Sort                bool   `xml:"sort,attr"`
+	AutoFilter          bool   `xml:"autoFilter,attr"`
+	PivotTables         bool   `xml:"pivotTables,attr"`
+	SelectUnlockedCells bool   `xml:"selectUnlockedCells,attr"`
+}
+
+// UnmarshalXML decodes a sheetProtection element, giving any flag that
+// it leaves out its schema default.
+func (protection *xlsxSheetProtection) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain xlsxSheetProtection
+	p := plain{
+		FormatCells:      true,
+		FormatColumns:    true,
+		FormatRows:       true,
+		InsertColumns:    true,
+		InsertRows:       true,
+		InsertHyperlinks: true,
+		DeleteColumns:    true,
+		DeleteRows:       true,
+		Sort:             true,
+		AutoFilter:       true,
+		PivotTables:      true,
+	}
+	err := d.DecodeElement(&p, &start)
+	if err != nil {
+		return err
+	}
+	*protection = xlsxSheetProtection(p)
+	return nil
+}
+
+// xlsxPageBreaks directly maps the rowBreaks and colBreaks elements
+// in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxPageBreaks struct {
+	Count            int         `xml:"count,attr,omitempty"`
+	ManualBreakCount int         `xml:"manualBreakCount,attr,omitempty"`
+	Brk              []xlsxBreak `xml:"brk"`
+}
+
+// xlsxBreak directly maps the brk element in the namespace
+// http://schemas.openxmlformats.org/spreadsheetml/2006/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxBreak struct {
+	Id  int  `xml:"id,attr"`
+	Min int  `xml:"min,attr,omitempty"`
+	Max int  `xml:"max,attr,omitempty"`
+	Man bool `xml:"man,attr,omitempty"`
+	Pt  bool `xml:"pt,attr,omitempty"`
 }
 
 // xlsxHeaderFooter directly maps the headerFooter element in the namespace
@@ -31,6 +211,12 @@ type xlsxHeaderFooter struct {
 	DifferentOddEven bool            `xml:"differentOddEven,attr"`
 	OddHeader        []xlsxOddHeader `xml:"oddHeader"`
 	OddFooter        []xlsxOddFooter `xml:"oddFooter"`
+	// The even and first page headers and footers have the same
+	// form as the odd page ones.
+	EvenHeader  []xlsxOddHeader `xml:"evenHeader"`
+	EvenFooter  []xlsxOddFooter `xml:"evenFooter"`
+	FirstHeader []xlsxOddHeader `xml:"firstHeader"`
+	FirstFooter []xlsxOddFooter `xml:"firstFooter"`
 }
 
 // xlsxOddHeader directly maps the oddHeader element in the namespace
@@ -100,8 +286,20 @@ type xlsxPageMargins struct {
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
 // as I need.
+//
+// Attributes that we don't model are captured, so that those of a
+// worksheet that has been read are written back out.
 type xlsxSheetFormatPr struct {
-	DefaultRowHeight float64 `xml:"defaultRowHeight,attr"`
+	BaseColWidth     int        `xml:"baseColWidth,attr,omitempty"`
+	DefaultColWidth  float64    `xml:"defaultColWidth,attr,omitempty"`
+	DefaultRowHeight float64    `xml:"defaultRowHeight,attr"`
+	CustomHeight     bool       `xml:"customHeight,attr,omitempty"`
+	ZeroHeight       bool       `xml:"zeroHeight,attr,omitempty"`
+	ThickTop         bool       `xml:"thickTop,attr,omitempty"`
+	ThickBottom      bool       `xml:"thickBottom,attr,omitempty"`
+	OutlineLevelRow  int        `xml:"outlineLevelRow,attr,omitempty"`
+	OutlineLevelCol  int        `xml:"outlineLevelCol,attr,omitempty"`
+	Attrs            []xml.Attr `xml:",any,attr"`
 }
 
 // xlsxSheetViews directly maps the sheetViews element in the namespace
@@ -133,8 +331,8 @@ type xlsxSheetView struct {
 	ZoomScaleNormal         float64         `xml:"zoomScaleNormal,attr"`
 	ZoomScalePageLayoutView float64         `xml:"zoomScalePageLayoutView,attr"`
 	WorkbookViewId          int             `xml:"workbookViewId,attr"`
-	Selection               []xlsxSelection `xml:"selection"`
 	Pane                    *xlsxPane       `xml:"pane"`
+	Selection               []xlsxSelection `xml:"selection"`
 }
 
 // xlsxSelection directly maps the selection element in the namespace
@@ -148,16 +346,16 @@ type xlsxSelection struct {
 	SQRef        string `xml:"sqref,attr"`
 }
 
-// xlsxSelection directly maps the selection element in the namespace
+// xlsxPane directly maps the pane element in the namespace
 // http://schemas.openxmlformats.org/spreadsheetml/2006/main -
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxPane struct {
-	XSplit      int    `xml:"xSplit,attr"`
-	YSplit      int    `xml:"ySplit,attr"`
-	TopLeftCell string `xml:"topLeftCell,attr"`
-	ActivePane  string `xml:"activePane,attr"`
-	State       string `xml:"state,attr"` // Either "split" or "frozen"
+	XSplit      int    `xml:"xSplit,attr,omitempty"`
+	YSplit      int    `xml:"ySplit,attr,omitempty"`
+	TopLeftCell string `xml:"topLeftCell,attr,omitempty"`
+	ActivePane  string `xml:"activePane,attr,omitempty"`
+	State       string `xml:"state,attr,omitempty"` // Either "split" or "frozen"
 }
 
 // xlsxSheetPr directly maps the sheetPr element in the namespace
@@ -220,10 +418,12 @@ type xlsxSheetData struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxRow struct {
-	R      int     `xml:"r,attr"`
-	Spans  string  `xml:"spans,attr,omitempty"`
-	Hidden bool    `xml:"hidden,attr,omitempty"`
-	C      []xlsxC `xml:"c"`
+	R            int     `xml:"r,attr"`
+	Spans        string  `xml:"spans,attr,omitempty"`
+	Hidden       bool    `xml:"hidden,attr,omitempty"`
+	Ht           float64 `xml:"ht,attr,omitempty"`
+	CustomHeight bool    `xml:"customHeight,attr,omitempty"`
+	C            []xlsxC `xml:"c"`
 }
 
 // xlsxC directly maps the c element in the namespace
@@ -231,11 +431,12 @@ type xlsxRow struct {
 // currently I have not checked it for completeness - it does as much
 // as I need.
 type xlsxC struct {
-	R string `xml:"r,attr"`           // Cell ID, e.g. A1
-	S int    `xml:"s,attr"`           // Style reference.
-	T string `xml:"t,attr,omitempty"` // Type.
-	V string `xml:"v"`                // Value
-	F *xlsxF `xml:"f,omitempty"`      // Formula
+	R  string  `xml:"r,attr"`           // Cell ID, e.g. A1
+	S  int     `xml:"s,attr"`           // Style reference.
+	T  string  `xml:"t,attr,omitempty"` // Type.
+	V  string  `xml:"v,omitempty"`      // Value
+	F  *xlsxF  `xml:"f,omitempty"`      // Formula
+	Is *xlsxSI `xml:"is,omitempty"`     // Inline string
 }
 
 // xlsxC directly maps the f element in the namespace
@@ -249,6 +450,24 @@ type xlsxF struct {
 	Si      int    `xml:"si,attr,omitempty"`  // Shared formula index
 }
 
+// setSchemaDefaults populates the worksheet with the values that the
+// schema, or Excel, gives attributes and elements that are left out
+// of a worksheet, so that they are what's left after decoding one.
+func (worksheet *xlsxWorksheet) setSchemaDefaults() {
+	worksheet.PageSetUp.PaperSize = "1"
+	worksheet.PageSetUp.Scale = 100
+	worksheet.PageSetUp.FitToWidth = 1
+	worksheet.PageSetUp.FitToHeight = 1
+	worksheet.PageSetUp.PageOrder = "downThenOver"
+	worksheet.PageSetUp.Orientation = "default"
+	worksheet.PageMargins.Left = 0.7
+	worksheet.PageMargins.Right = 0.7
+	worksheet.PageMargins.Top = 0.75
+	worksheet.PageMargins.Bottom = 0.75
+	worksheet.PageMargins.Header = 0.3
+	worksheet.PageMargins.Footer = 0.3
+}
+
 // Create a new XLSX Worksheet with default values populated.
 // Strictly for internal use only!
 func newXlsxWorksheet() (worksheet *xlsxWorksheet) {
@@ -313,3 +532,48 @@ func newXlsxWorksheet() (worksheet *xlsxWorksheet) {
 	worksheet.HeaderFooter.OddFooter[0] = xlsxOddFooter{Content: `&C&"Times New Roman,Regular"&12Page &P`}
 	return
 }
+
+// xlsxX14SparklineGroups directly maps the sparklineGroups element in
+// the namespace
+// http://schemas.microsoft.com/office/spreadsheetml/2009/9/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.  It is found in the ext element, with the uri
+// sparklineExtURI, of a worksheet's extLst.
+type xlsxX14SparklineGroups struct {
+	SparklineGroup []xlsxX14SparklineGroup `xml:"sparklineGroups>sparklineGroup"`
+}
+
+// xlsxX14SparklineGroup directly maps the sparklineGroup element in
+// the namespace
+// http://schemas.microsoft.com/office/spreadsheetml/2009/9/main -
+// currently I have not checked it for completeness - it does as much
+// as I need.
+type xlsxX14SparklineGroup struct {
+	Type          string             `xml:"type,attr"`
+	LineWeight    float64            `xml:"lineWeight,attr"`
+	Markers       bool               `xml:"markers,attr"`
+	High          bool               `xml:"high,attr"`
+	Low           bool               `xml:"low,attr"`
+	First         bool               `xml:"first,attr"`
+	Last          bool               `xml:"last,attr"`
+	Negative      bool               `xml:"negative,attr"`
+	DisplayXAxis  bool               `xml:"displayXAxis,attr"`
+	ColorSeries   *xlsxColor         `xml:"colorSeries"`
+	ColorNegative *xlsxColor         `xml:"colorNegative"`
+	ColorAxis     *xlsxColor         `xml:"colorAxis"`
+	ColorMarkers  *xlsxColor         `xml:"colorMarkers"`
+	ColorFirst    *xlsxColor         `xml:"colorFirst"`
+	ColorLast     *xlsxColor         `xml:"colorLast"`
+	ColorHigh     *xlsxColor         `xml:"colorHigh"`
+	ColorLow      *xlsxColor         `xml:"colorLow"`
+	Sparklines    []xlsxX14Sparkline `xml:"sparklines>sparkline"`
+}
+
+// xlsxX14Sparkline directly maps the sparkline element in the
+// namespace http://schemas.microsoft.com/office/spreadsheetml/2009/9/main
+// - currently I have not checked it for completeness - it does as
+// much as I need.
+type xlsxX14Sparkline struct {
+	F     string `xml:"f"`
+	Sqref string `xml:"sqref"`
+}